@@ -2,7 +2,11 @@ package pcircle
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
 	"sort"
 	"strconv"
@@ -16,104 +20,173 @@ func NewBeatmap() *Beatmap {
 
 // Beatmap stores information about single beatmap.
 type Beatmap struct {
-	FileFormatVersion int    // Specifies version of beatmap file
-	FilePath          string // The location of beatmap .osu file
+	FileFormatVersion int    `json:"file_format_version"` // Specifies version of beatmap file
+	FilePath          string `json:"file_path,omitempty"` // The location of beatmap .osu file
 
 	// General
 	//
 	// Various properties about the beatmap's gameplay.
-	AudioFilename        string    // The location of the audio file relative to the current folder
-	AudioLeadIn          int       // The amount of time added before the audio file begins playing
-	PreviewTime          int       // Defines when the audio file should begin playing when selected in the song selection menu
-	Countdown            int       // The speed of the countdown which occurs before the first hit object appears
-	SampleSet            SampleSet // Specifies which set of hit sounds will be used throughout the beatmap
-	StackLeniency        float64   // How often closely placed hit objects will be stacked together
-	GameMode             int       // Defines the game mode of the beatmap (0=osu!, 1=Taiko, 2=Catch the Beat, 3=osu!mania)
-	LetterboxInBreaks    bool      // Whether the letterbox appears during breaks
-	StoryFireInFront     bool      // Whether or not display the storyboard in front of combo fire
-	SkinPreference       string    // The preferred skin to use during gameplay
-	EpilepsyWarning      bool      // Whether or not show a 'This beatmap contains scenes with rapidly flashing colours...' warning at the beginning of the beatmap
-	CountdownOffset      int       // How many beats earlier the countdown starts
-	WidescreenStoryboard bool      // Whether or not the storyboard should be widescreen
-	SpecialStyle         bool      // Whether or not use the special N+1 style for osu!mania
-	UseSkinSprites       bool      // Whether or not the storyboard can use user's skin resources
+	AudioFilename        string    `json:"audio_filename"`        // The location of the audio file relative to the current folder
+	AudioLeadIn          int       `json:"audio_lead_in"`         // The amount of time added before the audio file begins playing
+	PreviewTime          int       `json:"preview_time"`          // Defines when the audio file should begin playing when selected in the song selection menu
+	Countdown            int       `json:"countdown"`             // The speed of the countdown which occurs before the first hit object appears
+	SampleSet            SampleSet `json:"sample_set"`            // Specifies which set of hit sounds will be used throughout the beatmap
+	StackLeniency        float64   `json:"stack_leniency"`        // How often closely placed hit objects will be stacked together
+	GameMode             int       `json:"game_mode"`             // Defines the game mode of the beatmap (0=osu!, 1=Taiko, 2=Catch the Beat, 3=osu!mania)
+	LetterboxInBreaks    bool      `json:"letterbox_in_breaks"`   // Whether the letterbox appears during breaks
+	AlwaysShowPlayfield  bool      `json:"always_show_playfield"` // Whether the playfield is shown during breaks, only used by old beatmaps
+	StoryFireInFront     bool      `json:"story_fire_in_front"`   // Whether or not display the storyboard in front of combo fire
+	SkinPreference       string    `json:"skin_preference"`       // The preferred skin to use during gameplay
+	EpilepsyWarning      bool      `json:"epilepsy_warning"`      // Whether or not show a 'This beatmap contains scenes with rapidly flashing colours...' warning at the beginning of the beatmap
+	CountdownOffset      int       `json:"countdown_offset"`      // How many beats earlier the countdown starts
+	WidescreenStoryboard bool      `json:"widescreen_storyboard"` // Whether or not the storyboard should be widescreen
+	SpecialStyle         bool      `json:"special_style"`         // Whether or not use the special N+1 style for osu!mania
+	UseSkinSprites       bool      `json:"use_skin_sprites"`      // Whether or not the storyboard can use user's skin resources
+
+	SamplesMatchPlaybackRate bool `json:"samples_match_playback_rate,omitempty"` // Whether hit sounds are sped up with rate changing mods
 
 	// Editor
 	//
 	// Saved settings for mappers while editing beatmaps.
-	Bookmarks       []int   // A list of times of editor bookmarks
-	DistanceSpacing float64 // A multiplier for the "Distance Snap" feature
-	BeatDivisor     int     // The beat division for placing objects
-	GridSize        int     // The size of the grid for the "Grid Snap" feature
-	TimelineZoom    float64 // The zoom in the editor timeline
+	Bookmarks       []int   `json:"bookmarks,omitempty"` // A list of times of editor bookmarks
+	DistanceSpacing float64 `json:"distance_spacing"`    // A multiplier for the "Distance Snap" feature
+	BeatDivisor     int     `json:"beat_divisor"`        // The beat division for placing objects
+	GridSize        int     `json:"grid_size"`           // The size of the grid for the "Grid Snap" feature
+	TimelineZoom    float64 `json:"timeline_zoom"`       // The zoom in the editor timeline
 
 	// Metadata
 	//
 	// Descriptive information about the song and beatmap.
-	Title         string   // The title of the song limited to ASCII characters
-	TitleUnicode  string   // The title of the song with unicode support
-	Artist        string   // The name of the song's artist limited to ASCII characters
-	ArtistUnicode string   // The name of the song's artist with unicode support
-	Creator       string   // The username of the mapper
-	Version       string   // The name of the beatmap's difficulty
-	Source        string   // Describes the origin of the song
-	Tags          []string // A collection of words describing the song
-	BeatmapID     int      // The web ID of the single beatmap
-	BeatmapSetID  int      // The web ID of the beatmap set (Mapset)
+	Title         string   `json:"title"`          // The title of the song limited to ASCII characters
+	TitleUnicode  string   `json:"title_unicode"`  // The title of the song with unicode support
+	Artist        string   `json:"artist"`         // The name of the song's artist limited to ASCII characters
+	ArtistUnicode string   `json:"artist_unicode"` // The name of the song's artist with unicode support
+	Creator       string   `json:"creator"`        // The username of the mapper
+	Version       string   `json:"version"`        // The name of the beatmap's difficulty
+	Source        string   `json:"source"`         // Describes the origin of the song
+	Tags          []string `json:"tags,omitempty"` // A collection of words describing the song
+	BeatmapID     int      `json:"beatmap_id"`     // The web ID of the single beatmap
+	BeatmapSetID  int      `json:"beatmap_set_id"` // The web ID of the beatmap set (Mapset)
 
 	// Difficulty
 	//
 	// Values defining the difficulty of the beatmap.
-	HPDrainRate       float64 // How fast the health decreases
-	CircleSize        float64 // The size of the hit objects in the osu!standard mode. In osu!mania mode, CircleSize is the number of columns
-	OverallDifficulty float64 // The harshness of the hit window and the difficulty of spinners
-	ApproachRate      float64 // Defines when hit objects start to fade in relatively to when they should be hit
-	SliderMultiplier  float64 // Specifies the multiplier of the slider velocity
-	SliderTickRate    float64 // The number of ticks per beat
+	HPDrainRate       float64 `json:"hp_drain_rate"`      // How fast the health decreases
+	CircleSize        float64 `json:"circle_size"`        // The size of the hit objects in the osu!standard mode. In osu!mania mode, CircleSize is the number of columns
+	OverallDifficulty float64 `json:"overall_difficulty"` // The harshness of the hit window and the difficulty of spinners
+	ApproachRate      float64 `json:"approach_rate"`      // Defines when hit objects start to fade in relatively to when they should be hit
+	SliderMultiplier  float64 `json:"slider_multiplier"`  // Specifies the multiplier of the slider velocity
+	SliderTickRate    float64 `json:"slider_tick_rate"`   // The number of ticks per beat
 
 	// Events
 	//
 	// A list of storyboard events.
-	Background *Background // The location of the background image relative to the beatmap directory
-	Breaks     []*Break    // Break times through the beatmap
-	// todo: storyboards
-	// todo: video
+	Background *Background `json:"background,omitempty"` // The location of the background image relative to the beatmap directory
+	Breaks     []*Break    `json:"breaks,omitempty"`     // Break times through the beatmap
+	Video      *Video      `json:"video,omitempty"`      // Background video, if any
+	Storyboard []string    `json:"storyboard,omitempty"` // Storyboard events specific to the difficulty, commands keep their indentation
 
 	// Timing Points
 	//
 	// A list of the beatmap's timing points and hitsounds.
 	// Describes a number of properties regarding beats per minute and
 	// hit sounds. Sorted by offset in the timing points section.
-	TimingPoints []*TimingPoint
+	TimingPoints []*TimingPoint `json:"timing_points"`
 
 	// Colours
 	//
 	// RGB values of the combo colours used.
-	ComboColours []*RGB // Defines the colours of combos
+	ComboColours []*RGB `json:"combo_colours,omitempty"` // Defines the colours of combos
 
-	SliderBody          *RGB
-	SliderTrackOverride *RGB
-	SliderBorder        *RGB
+	SliderBody          *RGB `json:"slider_body,omitempty"`
+	SliderTrackOverride *RGB `json:"slider_track_override,omitempty"`
+	SliderBorder        *RGB `json:"slider_border,omitempty"`
 	// Extra colours for sliders
 
 	// Hit Objects
 	//
 	// A list of the beatmap's hit objects.
-	HitObjects []interface{}
+	HitObjects []interface{} `json:"hit_objects"`
+
+	source *beatmapSource // Original text of the beatmap, if it was parsed in lossless mode
 }
 
 func (b *Beatmap) SortTimingPoints() {
-	sort.Slice(b.TimingPoints, func(i, j int) bool {
+	sort.SliceStable(b.TimingPoints, func(i, j int) bool {
 		return b.TimingPoints[i].Offset < b.TimingPoints[j].Offset
 	})
 }
 func (b *Beatmap) SortHitObjects() {
-	sort.Slice(b.HitObjects, func(i, j int) bool {
-		return b.HitObjects[i].(BaseHitObject).Time < b.HitObjects[j].(BaseHitObject).Time
+	sort.SliceStable(b.HitObjects, func(i, j int) bool {
+		return BaseOf(b.HitObjects[i]).Time < BaseOf(b.HitObjects[j]).Time
 	})
 }
 
+// Clone returns deep copy of Beatmap.
+func (b *Beatmap) Clone() *Beatmap {
+	c := *b
+
+	c.Bookmarks = append([]int(nil), b.Bookmarks...)
+	c.Tags = append([]string(nil), b.Tags...)
+
+	if b.Background != nil {
+		bg := *b.Background
+		c.Background = &bg
+	}
+
+	if b.Video != nil {
+		v := *b.Video
+		c.Video = &v
+	}
+	c.Storyboard = append([]string(nil), b.Storyboard...)
+
+	c.Breaks = make([]*Break, len(b.Breaks))
+	for i, br := range b.Breaks {
+		nbr := *br
+		c.Breaks[i] = &nbr
+	}
+
+	c.TimingPoints = make([]*TimingPoint, len(b.TimingPoints))
+	for i, tp := range b.TimingPoints {
+		ntp := *tp
+		c.TimingPoints[i] = &ntp
+	}
+
+	c.ComboColours = make([]*RGB, len(b.ComboColours))
+	for i, colour := range b.ComboColours {
+		ncolour := *colour
+		c.ComboColours[i] = &ncolour
+	}
+	c.SliderBody = b.SliderBody.copy()
+	c.SliderTrackOverride = b.SliderTrackOverride.copy()
+	c.SliderBorder = b.SliderBorder.copy()
+
+	c.HitObjects = make([]interface{}, len(b.HitObjects))
+	for i, hitObject := range b.HitObjects {
+		c.HitObjects[i] = copyHitObject(hitObject)
+	}
+
+	return &c
+}
+
+// ToFile sorts timing points and hit objects and writes Beatmap to specified .osu file.
 func (b *Beatmap) ToFile(path string) error {
+	return b.writeFile(path, b.String)
+}
+
+// ToFileVersion is like ToFile, but writes Beatmap in canonical format of specified
+// file format version. See Beatmap.StringVersion.
+func (b *Beatmap) ToFileVersion(path string, version int) error {
+	if !SupportedFormatVersion(version) {
+		return ErrUnsupportedFormatVersion
+	}
+	return b.writeFile(path, func() string {
+		return b.canonicalString(version)
+	})
+}
+
+// writeFile sorts timing points and hit objects and writes text of Beatmap to specified file.
+func (b *Beatmap) writeFile(path string, text func() string) error {
 	f, err := os.Create(path)
 	if err != nil {
 		return err
@@ -125,12 +198,58 @@ func (b *Beatmap) ToFile(path string) error {
 	b.SortTimingPoints()
 	b.SortHitObjects()
 
-	_, err = w.WriteString("osu file format v14\n")
+	_, err = w.WriteString(text())
 	if err != nil {
 		return err
 	}
 
-	for _, line := range []string{
+	return w.Flush()
+}
+
+// MD5 returns hex encoded MD5 hash of the beatmap .osu file, which osu! uses to identify
+// beatmaps. If beatmap has no file path, the hash of Beatmap.String is returned.
+func (b *Beatmap) MD5() (string, error) {
+	data := []byte(b.String())
+	if b.FilePath != "" {
+		var err error
+		data, err = os.ReadFile(b.FilePath)
+		if err != nil {
+			return "", err
+		}
+	}
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// String returns string of Beatmap as it would be in .osu file. If Beatmap was parsed
+// with FromFileLossless, the original text is reproduced with only the edited lines
+// changed, otherwise the canonical format of the latest version is used, or of
+// LAZER_FORMAT_VERSION for beatmaps of osu!lazer.
+func (b *Beatmap) String() string {
+	if b.source != nil {
+		return b.source.render(b)
+	}
+	return b.canonicalString(b.outputVersion())
+}
+
+// StringVersion returns string of Beatmap in canonical format of specified file format
+// version. Times of versions older than LEGACY_OFFSET_FORMAT_VERSION are written
+// LEGACY_OFFSET milliseconds early and fields the version does not have are omitted.
+// ErrUnsupportedFormatVersion is returned for versions SupportedFormatVersion rejects.
+func (b *Beatmap) StringVersion(version int) (string, error) {
+	if !SupportedFormatVersion(version) {
+		return "", ErrUnsupportedFormatVersion
+	}
+	return b.canonicalString(version), nil
+}
+
+// canonicalString returns string of Beatmap in canonical .osu format of specified version.
+func (b *Beatmap) canonicalString(version int) string {
+	b = b.forVersion(version)
+
+	lines := []string{
+		"osu file format v" + strconv.Itoa(version),
+		"",
 		"[General]",
 		generateLineFor("AudioFilename", b.AudioFilename),
 		generateLineFor("AudioLeadIn", b.AudioLeadIn),
@@ -140,38 +259,229 @@ func (b *Beatmap) ToFile(path string) error {
 		generateLineFor("StackLeniency", b.StackLeniency),
 		generateLineFor("Mode", b.GameMode),
 		generateLineFor("LetterboxInBreaks", b.LetterboxInBreaks),
-		generateLineFor("StoryFireInFront", b.StoryFireInFront),
-		generateLineFor("SkinPreference", b.SkinPreference),
-		generateLineFor("EpilepsyWarning", b.EpilepsyWarning),
-		generateLineFor("CountdownOffset", b.CountdownOffset),
-		generateLineFor("WidescreenStoryboard", b.WidescreenStoryboard),
-		generateLineFor("SpecialStyle", b.SpecialStyle),
-		generateLineFor("UseSkinSprites", b.UseSkinSprites),
-	} {
-		_, err = w.WriteString(line + "\n")
-		if err != nil {
-			return err
+	}
+	if b.AlwaysShowPlayfield {
+		lines = append(lines, generateLineFor("AlwaysShowPlayfield", b.AlwaysShowPlayfield))
+	}
+	settings := []struct {
+		key   string
+		value interface{}
+		set   bool // Whether the value differs from the default
+	}{
+		{"StoryFireInFront", b.StoryFireInFront, !b.StoryFireInFront},
+		{"SkinPreference", b.SkinPreference, b.SkinPreference != ""},
+		{"EpilepsyWarning", b.EpilepsyWarning, b.EpilepsyWarning},
+		{"CountdownOffset", b.CountdownOffset, b.CountdownOffset != 0},
+		{"WidescreenStoryboard", b.WidescreenStoryboard, b.WidescreenStoryboard},
+		{"SpecialStyle", b.SpecialStyle, b.SpecialStyle},
+		{"UseSkinSprites", b.UseSkinSprites, b.UseSkinSprites},
+	}
+	for _, setting := range settings {
+		// versions without [Editor] section predate these settings, they are kept only if set
+		if setting.set || !olderThan(version, EDITOR_SECTION_FORMAT_VERSION) {
+			lines = append(lines, generateLineFor(setting.key, setting.value))
 		}
 	}
+	if b.SamplesMatchPlaybackRate {
+		lines = append(lines, generateLineFor("SamplesMatchPlaybackRate", b.SamplesMatchPlaybackRate))
+	}
 
-	return w.Flush()
+	var bookmarks []string
+	for _, bookmark := range b.Bookmarks {
+		bookmarks = append(bookmarks, strconv.Itoa(bookmark))
+	}
+	if olderThan(version, EDITOR_SECTION_FORMAT_VERSION) {
+		// editor settings were stored in General section by old versions
+		if len(bookmarks) > 0 {
+			lines = append(lines, generateLineFor("EditorBookmarks", strings.Join(bookmarks, ",")))
+		}
+		lines = append(lines, generateLineFor("EditorDistanceSpacing", b.DistanceSpacing))
+	} else {
+		lines = append(lines, "", "[Editor]")
+		if len(bookmarks) > 0 {
+			lines = append(lines, generateLineFor("Bookmarks", strings.Join(bookmarks, ",")))
+		}
+		lines = append(lines,
+			generateLineFor("DistanceSpacing", b.DistanceSpacing),
+			generateLineFor("BeatDivisor", b.BeatDivisor),
+			generateLineFor("GridSize", b.GridSize),
+			generateLineFor("TimelineZoom", b.TimelineZoom),
+		)
+	}
+
+	lines = append(lines,
+		"",
+		"[Metadata]",
+		generateCompactLineFor("Title", b.Title),
+	)
+	if !olderThan(version, UNICODE_METADATA_FORMAT_VERSION) {
+		lines = append(lines, generateCompactLineFor("TitleUnicode", b.TitleUnicode))
+	}
+	lines = append(lines, generateCompactLineFor("Artist", b.Artist))
+	if !olderThan(version, UNICODE_METADATA_FORMAT_VERSION) {
+		lines = append(lines, generateCompactLineFor("ArtistUnicode", b.ArtistUnicode))
+	}
+	lines = append(lines,
+		generateCompactLineFor("Creator", b.Creator),
+		generateCompactLineFor("Version", b.Version),
+		generateCompactLineFor("Source", b.Source),
+		generateCompactLineFor("Tags", strings.Join(b.Tags, " ")),
+	)
+	if !olderThan(version, BEATMAP_ID_FORMAT_VERSION) {
+		lines = append(lines,
+			generateCompactLineFor("BeatmapID", b.BeatmapID),
+			generateCompactLineFor("BeatmapSetID", b.BeatmapSetID),
+		)
+	}
+
+	lines = append(lines,
+		"",
+		"[Difficulty]",
+		generateCompactLineFor("HPDrainRate", b.HPDrainRate),
+		generateCompactLineFor("CircleSize", b.CircleSize),
+		generateCompactLineFor("OverallDifficulty", b.OverallDifficulty),
+	)
+	if !olderThan(version, APPROACH_RATE_FORMAT_VERSION) {
+		lines = append(lines, generateCompactLineFor("ApproachRate", b.ApproachRate))
+	}
+	lines = append(lines,
+		generateCompactLineFor("SliderMultiplier", b.SliderMultiplier),
+		generateCompactLineFor("SliderTickRate", b.SliderTickRate),
+		"",
+		"[Events]",
+		"//Background and Video events",
+	)
+
+	if b.Background != nil {
+		lines = append(lines, b.Background.String())
+	}
+	if b.Video != nil {
+		lines = append(lines, b.Video.String())
+	}
+
+	lines = append(lines, "//Break Periods")
+	for _, br := range b.Breaks {
+		lines = append(lines, br.String())
+	}
+
+	if olderThan(version, STORYBOARD_LAYERS_FORMAT_VERSION) {
+		lines = append(lines, b.Storyboard...)
+	} else {
+		lines = append(lines, storyboardEventLines(b.Storyboard)...)
+	}
+	lines = append(lines,
+		"",
+		"[TimingPoints]",
+	)
+
+	for _, tp := range b.TimingPoints {
+		lines = append(lines, timingPointLine(tp, version))
+	}
+
+	lines = append(lines, "", "")
+
+	if len(b.ComboColours) > 0 || b.SliderBody != nil || b.SliderTrackOverride != nil || b.SliderBorder != nil {
+		lines = append(lines, "[Colours]")
+		for i, colour := range b.ComboColours {
+			lines = append(lines, "Combo"+strconv.Itoa(i+1)+" : "+colour.String())
+		}
+		if b.SliderBody != nil {
+			lines = append(lines, "SliderBody : "+b.SliderBody.String())
+		}
+		if b.SliderTrackOverride != nil {
+			lines = append(lines, "SliderTrackOverride : "+b.SliderTrackOverride.String())
+		}
+		if b.SliderBorder != nil {
+			lines = append(lines, "SliderBorder : "+b.SliderBorder.String())
+		}
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, "[HitObjects]")
+	for _, hitObject := range b.HitObjects {
+		if ho, ok := hitObject.(fmt.Stringer); ok {
+			lines = append(lines, ho.String())
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
 }
 
 // FromFile parses specified file and fills Beatmap with data.
 func (b *Beatmap) FromFile(path string) error {
-	f, err := os.Open(path)
+	_, err := b.parseFile(path, false, false)
+	return err
+}
+
+// ParseError describes a line of .osu file which could not be parsed.
+type ParseError struct {
+	Line int    // Number of the line, starting from 1
+	Text string // Contents of the line
+	Err  error
+}
+
+// Error returns ParseError in readable format.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// FromFileLenient parses specified file like FromFile, but skips lines which could not be
+// parsed and returns their errors instead of failing.
+func (b *Beatmap) FromFileLenient(path string) ([]*ParseError, error) {
+	return b.parseFile(path, true, false)
+}
+
+// FromFileLossless parses specified file like FromFile and records its original text,
+// including comments, unknown keys and formatting of values. String and ToFile then
+// reproduce the file byte for byte if nothing was changed and rewrite only the lines
+// of edited values otherwise. DiscardSource switches back to the canonical format.
+func (b *Beatmap) FromFileLossless(path string) error {
+	_, err := b.parseFile(path, false, true)
+	return err
+}
+
+// DiscardSource drops the original text recorded by FromFileLossless, so that Beatmap
+// is written in the canonical format.
+func (b *Beatmap) DiscardSource() {
+	b.source = nil
+}
+
+// parseFile parses specified file and fills Beatmap with data. In lenient mode lines
+// which could not be parsed are skipped, otherwise the first ParseError is returned.
+// In lossless mode the original text is recorded.
+func (b *Beatmap) parseFile(path string, lenient, lossless bool) (errs []*ParseError, err error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer f.Close()
 
 	b.FilePath = path
+	b.source = nil
+
+	var source *beatmapSource
+	if lossless {
+		source = newBeatmapSource(data)
+	}
 
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	var section string
+	lineNumber := 0
+	seen := make(map[string]bool) // keys of key-value lines by section
 
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		lineNumber++
+		if lineNumber == 1 {
+			// byte order mark written by some editors
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		var sl *sourceLine
+		if source != nil {
+			sl = &sourceLine{raw: raw}
+			source.lines = append(source.lines, sl)
+		}
 
 		if len(line) <= 2 || strings.HasPrefix(line, "//") || strings.HasPrefix(line, ";") {
 			continue
@@ -180,7 +490,7 @@ func (b *Beatmap) FromFile(path string) error {
 		if b.FileFormatVersion == 0 && strings.HasPrefix(line, "osu file format v") {
 			b.FileFormatVersion, err = strconv.Atoi(line[17:])
 			if err != nil {
-				return err
+				return nil, &ParseError{lineNumber, raw, err}
 			}
 			continue
 		}
@@ -190,298 +500,372 @@ func (b *Beatmap) FromFile(path string) error {
 			continue
 		}
 
-		// parsing line
-		switch section {
-		case "General":
-			head, data := tokenize(line)
-			switch head {
-			case "AudioFilename":
-				b.AudioFilename = data
-			case "AudioLeadIn":
-				b.AudioLeadIn, err = strconv.Atoi(data)
-				if err != nil {
-					return err
-				}
-			case "PreviewTime":
-				b.PreviewTime, err = strconv.Atoi(data)
-				if err != nil {
-					return err
-				}
-			case "Countdown":
-				b.Countdown, err = strconv.Atoi(data)
-				if err != nil {
-					return err
-				}
-			case "SampleSet":
-				err = b.SampleSet.FromString(data)
-				if err != nil {
-					return err
-				}
-			case "StackLeniency":
-				b.StackLeniency, err = strconv.ParseFloat(data, 64)
-				if err != nil {
-					return err
-				}
-			case "Mode":
-				b.GameMode, err = strconv.Atoi(data)
-				if err != nil {
-					return err
-				}
-			case "LetterboxInBreaks":
-				b.LetterboxInBreaks, err = string2int2bool(data)
-				if err != nil {
-					return err
-				}
-			case "StoryFireInFront":
-				b.StoryFireInFront, err = string2int2bool(data)
-				if err != nil {
-					return err
-				}
-			case "SkinPreference":
-				b.SkinPreference = data
-			case "EpilepsyWarning":
-				b.EpilepsyWarning, err = string2int2bool(data)
-				if err != nil {
-					return err
-				}
-			case "CountdownOffset":
-				b.CountdownOffset, err = strconv.Atoi(data)
-				if err != nil {
-					return err
-				}
-			case "WidescreenStoryboard":
-				b.WidescreenStoryboard, err = string2int2bool(data)
-				if err != nil {
-					return err
-				}
-			case "SpecialStyle":
-				b.SpecialStyle, err = string2int2bool(data)
-				if err != nil {
-					return err
-				}
-			case "UseSkinSprites":
-				b.UseSkinSprites, err = string2int2bool(data)
-				if err != nil {
-					return err
-				}
-			}
-
-		case "Editor":
-			head, data := tokenize(line)
-			switch head {
-			case "Bookmarks":
-				bookmarks := strings.Split(data, ",")
-				b.Bookmarks = make([]int, len(bookmarks))
-				for i := range bookmarks {
-					b.Bookmarks[i], err = strconv.Atoi(bookmarks[i])
-					if err != nil {
-						return err
-					}
-				}
-			case "DistanceSpacing":
-				b.DistanceSpacing, err = strconv.ParseFloat(data, 64)
-				if err != nil {
-					return err
-				}
-			case "BeatDivisor":
-				b.BeatDivisor, err = strconv.Atoi(data)
-				if err != nil {
-					return err
-				}
-			case "GridSize":
-				b.GridSize, err = strconv.Atoi(data)
-				if err != nil {
-					return err
-				}
-			case "TimelineZoom":
-				b.TimelineZoom, err = strconv.ParseFloat(data, 64)
-				if err != nil {
-					return err
-				}
-			}
-
-		case "Metadata":
-			head, data := tokenize(line)
-			switch head {
-			case "Title":
-				b.Title = data
-			case "TitleUnicode":
-				b.TitleUnicode = data
-			case "Artist":
-				b.Artist = data
-			case "ArtistUnicode":
-				b.ArtistUnicode = data
-			case "Creator":
-				b.Creator = data
-			case "Version":
-				b.Version = data
-			case "Source":
-				b.Source = data
-			case "Tags":
-				b.Tags = strings.Split(data, ",")
-			case "BeatmapID":
-				b.BeatmapID, err = strconv.Atoi(data)
-				if err != nil {
-					return err
-				}
-			case "BeatmapSetID":
-				b.BeatmapSetID, err = strconv.Atoi(data)
-				if err != nil {
-					return err
-				}
-			}
-
-		case "Difficulty":
-			head, data := tokenize(line)
-			switch head {
-			case "HPDrainRate":
-				b.HPDrainRate, err = strconv.ParseFloat(data, 64)
-				if err != nil {
-					return err
-				}
-			case "CircleSize":
-				b.CircleSize, err = strconv.ParseFloat(data, 64)
-				if err != nil {
-					return err
-				}
-			case "OverallDifficulty":
-				b.OverallDifficulty, err = strconv.ParseFloat(data, 64)
-				if err != nil {
-					return err
-				}
-			case "ApproachRate":
-				b.ApproachRate, err = strconv.ParseFloat(data, 64)
-				if err != nil {
-					return err
-				}
-			case "SliderMultiplier":
-				b.SliderMultiplier, err = strconv.ParseFloat(data, 64)
-				if err != nil {
-					return err
-				}
-			case "SliderTickRate":
-				b.SliderTickRate, err = strconv.ParseFloat(data, 64)
-				if err != nil {
-					return err
-				}
-			}
-
-		case "Events":
-			// todo: storyboards
-			// todo: video
-
-			if strings.HasPrefix(line, "2,") {
-				// Breaks
-				br := &Break{}
-				err = br.FromString(line)
-				if err != nil {
-					return err
-				}
-				b.Breaks = append(b.Breaks, br)
-				continue
-
-			}
-			if strings.HasPrefix(line, "0,0,") {
-				// Background
-				bg := &Background{}
-				err = bg.FromString(line)
-				if err != nil {
-					return err
-				}
-				b.Background = bg
-				continue
-			}
-
-		case "TimingPoints":
-			tp := new(TimingPoint)
-			err = tp.FromString(line)
-			if err != nil {
-				return err
-			}
-			b.TimingPoints = append(b.TimingPoints, tp)
-
-		case "Colours":
-			head, data := tokenize(line)
-			if strings.HasPrefix(head, "Combo") {
-				colour := &RGB{}
-				err = colour.FromString(data)
-				if err != nil {
-					return err
-				}
-				b.ComboColours = append(b.ComboColours, colour)
-				continue
-			}
-
-			switch head {
-			case "SliderBody":
-				colour := &RGB{}
-				err = colour.FromString(data)
-				if err != nil {
-					return err
-				}
-				b.SliderBody = colour
-			case "SliderTrackOverride":
-				colour := &RGB{}
-				err = colour.FromString(data)
-				if err != nil {
-					return err
-				}
-				b.SliderTrackOverride = colour
-			case "SliderBorder":
-				colour := &RGB{}
-				err = colour.FromString(data)
-				if err != nil {
-					return err
-				}
-				b.SliderBorder = colour
-			}
-
-		case "HitObjects":
-			objectType, err := strconv.Atoi(strings.Split(line, ",")[3])
-			if err != nil {
-				return err
-			}
-
-			if (CIRCLE & objectType) == 1 {
-				hitObject := &Circle{}
-				err = hitObject.FromString(line)
-				if err != nil {
-					return err
-				}
-				b.HitObjects = append(b.HitObjects, hitObject)
-
-			} else if (SLIDER & objectType) > 0 {
-				hitObject := &Slider{}
-				err = hitObject.FromString(line)
-				if err != nil {
-					return err
-				}
-				b.HitObjects = append(b.HitObjects, hitObject)
-
-			} else if (SPINNER & objectType) > 0 {
-				hitObject := &Spinner{}
-				err = hitObject.FromString(line)
-				if err != nil {
-					return err
-				}
-				b.HitObjects = append(b.HitObjects, hitObject)
-
-			} else if (MANIA_HOLD_NOTE & objectType) > 0 {
-				hitObject := &ManiaHoldNote{}
-				err = hitObject.FromString(line)
-				if err != nil {
-					return err
-				}
-				b.HitObjects = append(b.HitObjects, hitObject)
+		hitObjects := len(b.HitObjects)
+		if err := b.parseLine(section, line, raw); err != nil {
+			perr := &ParseError{lineNumber, raw, err}
+			if !lenient {
+				return nil, perr
 			}
-		default:
-			return errors.New("invalid section in beatmap file: '" + section + "'")
+			errs = append(errs, perr)
+			continue
 		}
-	}
 
+		key, element := sourceKey(section, line)
+		if !element {
+			seen[section+"."+key] = true
+		}
+		if sl != nil {
+			sl.section = section
+			sl.key, sl.element = key, element
+			if section == "HitObjects" && len(b.HitObjects) == hitObjects {
+				// hit objects of unknown types are skipped
+				sl.key = ""
+			}
+		}
+	}
 	if err := scanner.Err(); err != nil {
-		return err
+		return nil, err
+	}
+
+	b.applyDefaults(seen)
+	if hasLegacyOffset(b.FileFormatVersion) {
+		b.shiftRange(LEGACY_OFFSET, MIN_TIME, MAX_TIME)
 	}
 
+	if source != nil {
+		source.assignValues(b)
+		b.source = source
+	}
+	return errs, nil
+}
+
+// parseLine parses single line of specified section. Malformed lines, which would make
+// parsers of single values panic, are reported as errors.
+func (b *Beatmap) parseLine(section, line, raw string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed line: %v", r)
+		}
+	}()
+
+	switch section {
+	case "General":
+		head, data := tokenize(line)
+		switch head {
+		case "AudioFilename":
+			b.AudioFilename = data
+		case "AudioLeadIn":
+			b.AudioLeadIn, err = strconv.Atoi(data)
+			if err != nil {
+				return err
+			}
+		case "PreviewTime":
+			b.PreviewTime, err = strconv.Atoi(data)
+			if err != nil {
+				return err
+			}
+		case "Countdown":
+			b.Countdown, err = strconv.Atoi(data)
+			if err != nil {
+				return err
+			}
+		case "SampleSet":
+			err = b.SampleSet.FromString(data)
+			if err != nil {
+				return err
+			}
+		case "StackLeniency":
+			b.StackLeniency, err = strconv.ParseFloat(data, 64)
+			if err != nil {
+				return err
+			}
+		case "Mode":
+			b.GameMode, err = strconv.Atoi(data)
+			if err != nil {
+				return err
+			}
+		case "LetterboxInBreaks":
+			b.LetterboxInBreaks, err = string2int2bool(data)
+			if err != nil {
+				return err
+			}
+		case "AlwaysShowPlayfield":
+			b.AlwaysShowPlayfield, err = string2int2bool(data)
+			if err != nil {
+				return err
+			}
+		case "StoryFireInFront":
+			b.StoryFireInFront, err = string2int2bool(data)
+			if err != nil {
+				return err
+			}
+		case "SkinPreference":
+			b.SkinPreference = data
+		case "EpilepsyWarning":
+			b.EpilepsyWarning, err = string2int2bool(data)
+			if err != nil {
+				return err
+			}
+		case "CountdownOffset":
+			b.CountdownOffset, err = strconv.Atoi(data)
+			if err != nil {
+				return err
+			}
+		case "WidescreenStoryboard":
+			b.WidescreenStoryboard, err = string2int2bool(data)
+			if err != nil {
+				return err
+			}
+		case "SpecialStyle":
+			b.SpecialStyle, err = string2int2bool(data)
+			if err != nil {
+				return err
+			}
+		case "UseSkinSprites":
+			b.UseSkinSprites, err = string2int2bool(data)
+			if err != nil {
+				return err
+			}
+		case "SamplesMatchPlaybackRate":
+			b.SamplesMatchPlaybackRate, err = string2int2bool(data)
+			if err != nil {
+				return err
+			}
+		case "EditorBookmarks":
+			// editor settings were stored in General section by old versions
+			b.Bookmarks, err = parseBookmarks(data)
+			if err != nil {
+				return err
+			}
+		case "EditorDistanceSpacing":
+			b.DistanceSpacing, err = strconv.ParseFloat(data, 64)
+			if err != nil {
+				return err
+			}
+		}
+
+	case "Editor":
+		head, data := tokenize(line)
+		switch head {
+		case "Bookmarks":
+			b.Bookmarks, err = parseBookmarks(data)
+			if err != nil {
+				return err
+			}
+		case "DistanceSpacing":
+			b.DistanceSpacing, err = strconv.ParseFloat(data, 64)
+			if err != nil {
+				return err
+			}
+		case "BeatDivisor":
+			b.BeatDivisor, err = strconv.Atoi(data)
+			if err != nil {
+				return err
+			}
+		case "GridSize":
+			b.GridSize, err = strconv.Atoi(data)
+			if err != nil {
+				return err
+			}
+		case "TimelineZoom":
+			b.TimelineZoom, err = strconv.ParseFloat(data, 64)
+			if err != nil {
+				return err
+			}
+		}
+
+	case "Metadata":
+		head, data := tokenize(line)
+		switch head {
+		case "Title":
+			b.Title = data
+		case "TitleUnicode":
+			b.TitleUnicode = data
+		case "Artist":
+			b.Artist = data
+		case "ArtistUnicode":
+			b.ArtistUnicode = data
+		case "Creator":
+			b.Creator = data
+		case "Version":
+			b.Version = data
+		case "Source":
+			b.Source = data
+		case "Tags":
+			b.Tags = strings.Fields(data)
+		case "BeatmapID":
+			b.BeatmapID, err = strconv.Atoi(data)
+			if err != nil {
+				return err
+			}
+		case "BeatmapSetID":
+			b.BeatmapSetID, err = strconv.Atoi(data)
+			if err != nil {
+				return err
+			}
+		}
+
+	case "Difficulty":
+		head, data := tokenize(line)
+		switch head {
+		case "HPDrainRate":
+			b.HPDrainRate, err = strconv.ParseFloat(data, 64)
+			if err != nil {
+				return err
+			}
+		case "CircleSize":
+			b.CircleSize, err = strconv.ParseFloat(data, 64)
+			if err != nil {
+				return err
+			}
+		case "OverallDifficulty":
+			b.OverallDifficulty, err = strconv.ParseFloat(data, 64)
+			if err != nil {
+				return err
+			}
+		case "ApproachRate":
+			b.ApproachRate, err = strconv.ParseFloat(data, 64)
+			if err != nil {
+				return err
+			}
+		case "SliderMultiplier":
+			b.SliderMultiplier, err = strconv.ParseFloat(data, 64)
+			if err != nil {
+				return err
+			}
+		case "SliderTickRate":
+			b.SliderTickRate, err = strconv.ParseFloat(data, 64)
+			if err != nil {
+				return err
+			}
+		}
+
+	case "Events":
+		switch eventKind(line) {
+		case VIDEO_ELEMENT:
+			v := &Video{}
+			err = v.FromString(line)
+			if err != nil {
+				return err
+			}
+			b.Video = v
+		case BREAK_ELEMENT:
+			br := &Break{}
+			err = br.FromString(line)
+			if err != nil {
+				return err
+			}
+			b.Breaks = append(b.Breaks, br)
+		case BACKGROUND_ELEMENT:
+			bg := &Background{}
+			err = bg.FromString(line)
+			if err != nil {
+				return err
+			}
+			b.Background = bg
+		default:
+			// storyboard commands are indented, so untrimmed line is kept
+			b.Storyboard = append(b.Storyboard, strings.TrimRight(raw, " \t"))
+		}
+
+	case "TimingPoints":
+		tp := new(TimingPoint)
+		err = tp.FromString(line)
+		if err != nil {
+			return err
+		}
+		b.TimingPoints = append(b.TimingPoints, tp)
+
+	case "Colours":
+		head, data := tokenize(line)
+		if strings.HasPrefix(head, "Combo") {
+			colour := &RGB{}
+			err = colour.FromString(data)
+			if err != nil {
+				return err
+			}
+			b.ComboColours = append(b.ComboColours, colour)
+			return nil
+		}
+
+		switch head {
+		case "SliderBody":
+			colour := &RGB{}
+			err = colour.FromString(data)
+			if err != nil {
+				return err
+			}
+			b.SliderBody = colour
+		case "SliderTrackOverride":
+			colour := &RGB{}
+			err = colour.FromString(data)
+			if err != nil {
+				return err
+			}
+			b.SliderTrackOverride = colour
+		case "SliderBorder":
+			colour := &RGB{}
+			err = colour.FromString(data)
+			if err != nil {
+				return err
+			}
+			b.SliderBorder = colour
+		}
+
+	case "HitObjects":
+		objectType, err := strconv.Atoi(strings.Split(line, ",")[3])
+		if err != nil {
+			return err
+		}
+
+		if (CIRCLE & objectType) == 1 {
+			hitObject := &Circle{}
+			err = hitObject.FromString(line)
+			if err != nil {
+				return err
+			}
+			b.HitObjects = append(b.HitObjects, hitObject)
+
+		} else if (SLIDER & objectType) > 0 {
+			hitObject := &Slider{}
+			err = hitObject.FromString(line)
+			if err != nil {
+				return err
+			}
+			b.HitObjects = append(b.HitObjects, hitObject)
+
+		} else if (SPINNER & objectType) > 0 {
+			hitObject := &Spinner{}
+			err = hitObject.FromString(line)
+			if err != nil {
+				return err
+			}
+			b.HitObjects = append(b.HitObjects, hitObject)
+
+		} else if (MANIA_HOLD_NOTE & objectType) > 0 {
+			hitObject := &ManiaHoldNote{}
+			err = hitObject.FromString(line)
+			if err != nil {
+				return err
+			}
+			b.HitObjects = append(b.HitObjects, hitObject)
+		}
+	default:
+		return errors.New("invalid section in beatmap file: '" + section + "'")
+	}
 	return nil
 }
+
+// parseBookmarks parses comma separated list of bookmark times.
+func parseBookmarks(data string) ([]int, error) {
+	bookmarks := strings.Split(data, ",")
+	times := make([]int, len(bookmarks))
+	for i := range bookmarks {
+		time, err := strconv.Atoi(strings.TrimSpace(bookmarks[i]))
+		if err != nil {
+			return nil, err
+		}
+		times[i] = time
+	}
+	return times, nil
+}
@@ -0,0 +1,58 @@
+package pcircle
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBeatmap_ToFile(t *testing.T) {
+	b := newTestBeatmap()
+	b.Title = "Title"
+	b.Tags = []string{"first", "second"}
+	b.Background = &Background{FileName: "bg.jpg"}
+	b.Video = &Video{StartTime: -200, FileName: "video.avi"}
+	b.Storyboard = []string{`Sprite,Foreground,Centre,"star.png",320,240`, " F,0,1000,2000,0,1", `Sample,500,0,"clap.wav",100`}
+	b.ComboColours = []*RGB{{255, 128, 0}, {0, 128, 255}}
+	b.SliderBorder = &RGB{255, 255, 255}
+
+	path := filepath.Join(t.TempDir(), "beatmap.osu")
+	if err := b.ToFile(path); err != nil {
+		t.Fatalf("Beatmap.ToFile() error = %v", err)
+	}
+
+	nb := NewBeatmap()
+	if err := nb.FromFile(path); err != nil {
+		t.Fatalf("Beatmap.FromFile() error = %v", err)
+	}
+	if got, want := nb.String(), b.String(); got != want {
+		t.Errorf("Beatmap.String() after round trip = %v, want %v", got, want)
+	}
+}
+
+func TestBeatmap_FromFileLenient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "beatmap.osu")
+	b := newTestBeatmap()
+	data := strings.Replace(b.String(), "CircleSize:4", "CircleSize:four", 1)
+	data = strings.Replace(data, "[HitObjects]\n", "[HitObjects]\n1,2\n", 1)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewBeatmap().FromFile(path); err == nil {
+		t.Fatalf("Beatmap.FromFile() error = nil, want error")
+	}
+
+	nb := NewBeatmap()
+	errs, err := nb.FromFileLenient(path)
+	if err != nil {
+		t.Fatalf("Beatmap.FromFileLenient() error = %v", err)
+	}
+	if len(errs) != 2 || errs[0].Text != "CircleSize:four" || errs[1].Text != "1,2" {
+		t.Fatalf("Beatmap.FromFileLenient() errors = %v, want CircleSize and hit object lines", errs)
+	}
+	if got, want := len(nb.HitObjects), len(b.HitObjects); got != want {
+		t.Errorf("Beatmap.FromFileLenient() hit objects = %v, want %v", got, want)
+	}
+}
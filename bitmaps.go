@@ -2,6 +2,7 @@ package pcircle
 
 import (
 	"errors"
+	"strconv"
 )
 
 // Countdown specifies the speed of the countdown which occurs
@@ -34,10 +35,11 @@ const (
 	DRUM_SAMPLESET
 )
 
-// FromString allows you to set sample sets with strings.
+// FromString allows you to set sample sets with strings. Old beatmaps use "None" for
+// Auto and osu!lazer beatmaps may use numbers of extended sample banks.
 func (ss *SampleSet) FromString(sample string) error {
 	switch sample {
-	case "Auto":
+	case "Auto", "None":
 		*ss = AUTO_SAMPLESET
 	case "Normal":
 		*ss = NORMAL_SAMPLESET
@@ -46,19 +48,28 @@ func (ss *SampleSet) FromString(sample string) error {
 	case "Drum":
 		*ss = DRUM_SAMPLESET
 	default:
-		return errors.New("invalid sample set identifier: " + sample)
+		n, err := strconv.Atoi(sample)
+		if err != nil {
+			return errors.New("invalid sample set identifier: " + sample)
+		}
+		*ss = SampleSet(n)
 	}
 	return nil
 }
 
-// String returns string of SampleSet in readable format.
+// String returns string of SampleSet in readable format, or its number if it has no name.
 func (ss SampleSet) String() string {
-	return map[SampleSet]string{
-		AUTO_SAMPLESET:   "Auto",
-		NORMAL_SAMPLESET: "Normal",
-		SOFT_SAMPLESET:   "Soft",
-		DRUM_SAMPLESET:   "Drum",
-	}[ss]
+	switch ss {
+	case AUTO_SAMPLESET:
+		return "Auto"
+	case NORMAL_SAMPLESET:
+		return "Normal"
+	case SOFT_SAMPLESET:
+		return "Soft"
+	case DRUM_SAMPLESET:
+		return "Drum"
+	}
+	return strconv.Itoa(int(ss))
 }
 
 // HitSound specifies a hit sounds to play when the hit object is successfully hit.
@@ -72,3 +83,17 @@ const (
 	FINISH_HITSOUND
 	CLAP_HITSOUND
 )
+
+// MarshalText encodes SampleSet by its name, e.g. "Soft", or its number.
+func (ss SampleSet) MarshalText() ([]byte, error) {
+	return []byte(ss.String()), nil
+}
+
+// UnmarshalText decodes SampleSet from its name or number.
+func (ss *SampleSet) UnmarshalText(text []byte) error {
+	if n, err := strconv.Atoi(string(text)); err == nil {
+		*ss = SampleSet(n)
+		return nil
+	}
+	return ss.FromString(string(text))
+}
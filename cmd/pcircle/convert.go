@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pcircle "github.com/Polkisss/osu-parser"
+)
+
+// Output formats of convert command.
+const (
+	OSU_FORMAT  = "osu"
+	JSON_FORMAT = "json"
+)
+
+// runConvert runs convert command.
+func runConvert(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("convert", "<beatmap or mapset>",
+		"Converts beatmaps and mapsets between .osu and JSON. Input is a .osu file, a JSON\n"+
+			"file, a mapset directory or a .osz archive. Mapsets are converted to .osu files\n"+
+			"in the -o directory.", stderr)
+	output := fs.String("o", "", "output file or directory, standard output if empty")
+	format := fs.String("to", "", "output format: osu or json, guessed from -o and the input if empty")
+	computed := fs.Bool("computed", false, "include computed fields of hit objects in JSON")
+	indent := fs.Bool("indent", true, "indent JSON")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	input := fs.Arg(0)
+	kind, err := inputKind(input)
+	if err != nil {
+		return err
+	}
+
+	if *format == "" {
+		*format = guessFormat(*output, kind)
+	}
+	if *format != OSU_FORMAT && *format != JSON_FORMAT {
+		fmt.Fprintln(stderr, "pcircle convert: invalid format "+*format)
+		return errUsage
+	}
+
+	m, cleanup, err := loadMapset(input)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	single := kind == OSU_INPUT
+	if kind == JSON_INPUT {
+		data, err := os.ReadFile(input)
+		if err != nil {
+			return err
+		}
+		isMapset, err := isMapsetJSON(data)
+		if err != nil {
+			return err
+		}
+		single = !isMapset
+	}
+
+	switch {
+	case *format == JSON_FORMAT:
+		opts := &pcircle.JSONOptions{Computed: *computed}
+		if *indent {
+			opts.Indent = "  "
+		}
+		return writeOutput(*output, stdout, func(w io.Writer) error {
+			if single {
+				return m.Beatmaps[0].ToJSON(w, opts)
+			}
+			return m.ToJSON(w, opts)
+		})
+	case single:
+		return writeOutput(*output, stdout, func(w io.Writer) error {
+			_, err := io.WriteString(w, m.Beatmaps[0].String())
+			return err
+		})
+	}
+	if *output == "" {
+		return errors.New("mapset can be converted to .osu files only with -o directory")
+	}
+	return writeMapset(m, *output)
+}
+
+// guessFormat returns output format by extension of output path: JSON for .json files,
+// .osu for .osu files and directories. Without output JSON is converted to .osu and
+// anything else to JSON.
+func guessFormat(output string, kind int) string {
+	switch ext := strings.ToLower(filepath.Ext(output)); {
+	case ext == ".json":
+		return JSON_FORMAT
+	case ext == ".osu", output != "" && ext == "":
+		return OSU_FORMAT
+	case kind == JSON_INPUT:
+		return OSU_FORMAT
+	}
+	return JSON_FORMAT
+}
+
+// writeOutput writes output to specified file or to stdout if path is empty.
+func writeOutput(path string, stdout io.Writer, write func(w io.Writer) error) error {
+	if path == "" {
+		return write(stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeMapset writes .osu files of beatmaps and the storyboard of mapset into directory.
+func writeMapset(m *pcircle.Mapset, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, b := range m.Beatmaps {
+		if err := b.ToFile(filepath.Join(dir, beatmapFileName(b))); err != nil {
+			return err
+		}
+	}
+	if m.Storyboard == nil {
+		return nil
+	}
+	name := filepath.Base(m.Storyboard.FilePath)
+	if m.Storyboard.FilePath == "" && len(m.Beatmaps) > 0 {
+		b := m.Beatmaps[0]
+		name = safeFileName(fmt.Sprintf("%s - %s (%s)", b.Artist, b.Title, b.Creator)) + ".osb"
+	}
+	return m.Storyboard.ToFile(filepath.Join(dir, name))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	pcircle "github.com/Polkisss/osu-parser"
+)
+
+// loadBeatmap loads single beatmap from .osu or JSON file.
+func loadBeatmap(path string) (*pcircle.Beatmap, error) {
+	kind, err := inputKind(path)
+	if err != nil {
+		return nil, err
+	}
+	if kind != OSU_INPUT && kind != JSON_INPUT {
+		return nil, errors.New("not a beatmap: " + path)
+	}
+	m, cleanup, err := loadMapset(path)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	if len(m.Beatmaps) != 1 {
+		return nil, errors.New("not a beatmap: " + path)
+	}
+	return m.Beatmaps[0], nil
+}
+
+// runDiff runs diff command.
+func runDiff(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("diff", "<old> <new>",
+		"Prints changes of metadata, timing points, hit objects, colours and breaks between\n"+
+			"two versions of a beatmap in .osu or JSON format. Exits with code 1 if they differ.", stderr)
+	asJSON := fs.Bool("json", false, "print difference as JSON")
+	if err := parseFlags(fs, args, 2, 2); err != nil {
+		return err
+	}
+
+	old, err := loadBeatmap(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	new, err := loadBeatmap(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	d := pcircle.Diff(old, new)
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	} else if _, err := io.WriteString(stdout, d.String()); err != nil {
+		return err
+	}
+
+	if !d.Empty() {
+		return errFailed
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	pcircle "github.com/Polkisss/osu-parser"
+)
+
+// runFmt runs fmt command.
+func runFmt(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("fmt", "<file.osu>...",
+		"Re-serializes .osu files canonically and prints them to standard output.", stderr)
+	write := fs.Bool("w", false, "write result to the source file instead of standard output")
+	list := fs.Bool("l", false, "list files whose formatting differs instead of printing them")
+	version := fs.Int("version", 0, "file format `version` to write, by default the latest one (v14, or v128 for osu!lazer beatmaps)")
+	if err := parseFlags(fs, args, 1, -1); err != nil {
+		return err
+	}
+	if *version != 0 && !pcircle.SupportedFormatVersion(*version) {
+		fmt.Fprintln(stderr, "pcircle fmt: invalid -version "+strconv.Itoa(*version))
+		return errUsage
+	}
+
+	for _, path := range fs.Args() {
+		b := pcircle.NewBeatmap()
+		if err := b.FromFile(path); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		b.SortTimingPoints()
+		b.SortHitObjects()
+		formatted := b.String()
+		if *version != 0 {
+			var err error
+			if formatted, err = b.StringVersion(*version); err != nil {
+				return err
+			}
+		}
+
+		if *list || *write {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if string(data) == formatted {
+				continue
+			}
+		}
+		if *list {
+			fmt.Fprintln(stdout, path)
+		}
+		if *write {
+			if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+				return err
+			}
+		}
+		if !*list && !*write {
+			if _, err := io.WriteString(stdout, formatted); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+
+	pcircle "github.com/Polkisss/osu-parser"
+	"github.com/Polkisss/osu-parser/scoring"
+)
+
+// Names of game modes in info output.
+var gameModeNames = map[int]string{
+	pcircle.OSU_GAMEMODE:   "osu!",
+	pcircle.TAIKO_GAMEMODE: "osu!taiko",
+	pcircle.CTB_GAMEMODE:   "osu!catch",
+	pcircle.MANIA_GAMEMODE: "osu!mania",
+}
+
+// beatmapInfo is information about a beatmap printed by info command.
+type beatmapInfo struct {
+	Name              string  `json:"name"`
+	File              string  `json:"file,omitempty"`
+	GameMode          string  `json:"game_mode"`
+	BeatmapID         int     `json:"beatmap_id"`
+	BeatmapSetID      int     `json:"beatmap_set_id"`
+	Audio             string  `json:"audio"`
+	HPDrainRate       float64 `json:"hp_drain_rate"`
+	CircleSize        float64 `json:"circle_size"`
+	OverallDifficulty float64 `json:"overall_difficulty"`
+	ApproachRate      float64 `json:"approach_rate"`
+	SliderMultiplier  float64 `json:"slider_multiplier"`
+	SliderTickRate    float64 `json:"slider_tick_rate"`
+	MinBPM            float64 `json:"min_bpm"`
+	MaxBPM            float64 `json:"max_bpm"`
+	Circles           int     `json:"circles"`
+	Sliders           int     `json:"sliders"`
+	Spinners          int     `json:"spinners"`
+	HoldNotes         int     `json:"hold_notes"`
+	MaxCombo          int     `json:"max_combo"`
+
+	*pcircle.LengthMetrics
+}
+
+// newBeatmapInfo collects information about beatmap.
+func newBeatmapInfo(b *pcircle.Beatmap) *beatmapInfo {
+	info := &beatmapInfo{
+		Name:              beatmapName(b),
+		File:              b.FilePath,
+		GameMode:          gameModeNames[b.GameMode],
+		BeatmapID:         b.BeatmapID,
+		BeatmapSetID:      b.BeatmapSetID,
+		Audio:             b.AudioFilename,
+		HPDrainRate:       b.HPDrainRate,
+		CircleSize:        b.CircleSize,
+		OverallDifficulty: b.OverallDifficulty,
+		ApproachRate:      b.ApproachRate,
+		SliderMultiplier:  b.SliderMultiplier,
+		SliderTickRate:    b.SliderTickRate,
+		MaxCombo:          scoring.MaxCombo(b),
+		LengthMetrics:     b.LengthMetrics(1),
+	}
+
+	info.MinBPM, info.MaxBPM = math.Inf(1), 0
+	for _, tp := range b.TimingPoints {
+		if !tp.Inherited || tp.MillisecondsPerBeat <= 0 {
+			continue
+		}
+		bpm := math.Round(60000/tp.MillisecondsPerBeat*100) / 100
+		info.MinBPM, info.MaxBPM = math.Min(info.MinBPM, bpm), math.Max(info.MaxBPM, bpm)
+	}
+	if info.MaxBPM == 0 {
+		info.MinBPM = 0
+	}
+
+	for _, hitObject := range b.HitObjects {
+		switch hitObject.(type) {
+		case *pcircle.Circle:
+			info.Circles++
+		case *pcircle.Slider:
+			info.Sliders++
+		case *pcircle.Spinner:
+			info.Spinners++
+		case *pcircle.ManiaHoldNote:
+			info.HoldNotes++
+		}
+	}
+	return info
+}
+
+// write prints info in readable format.
+func (info *beatmapInfo) write(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, info.Name)
+	if info.File != "" {
+		fmt.Fprintf(tw, "File:\t%s\n", info.File)
+	}
+	fmt.Fprintf(tw, "Mode:\t%s\n", info.GameMode)
+	fmt.Fprintf(tw, "IDs:\tbeatmap %d, set %d\n", info.BeatmapID, info.BeatmapSetID)
+	fmt.Fprintf(tw, "Audio:\t%s\n", info.Audio)
+	fmt.Fprintf(tw, "Difficulty:\tHP %g  CS %g  OD %g  AR %g\n", info.HPDrainRate, info.CircleSize, info.OverallDifficulty, info.ApproachRate)
+	fmt.Fprintf(tw, "Sliders:\tmultiplier %g, tick rate %g\n", info.SliderMultiplier, info.SliderTickRate)
+	if info.MinBPM == info.MaxBPM {
+		fmt.Fprintf(tw, "BPM:\t%g\n", info.MaxBPM)
+	} else {
+		fmt.Fprintf(tw, "BPM:\t%g-%g\n", info.MinBPM, info.MaxBPM)
+	}
+	objects := []string{fmt.Sprintf("%d circles", info.Circles), fmt.Sprintf("%d sliders", info.Sliders), fmt.Sprintf("%d spinners", info.Spinners)}
+	if info.HoldNotes > 0 {
+		objects = append(objects, fmt.Sprintf("%d hold notes", info.HoldNotes))
+	}
+	fmt.Fprintf(tw, "Objects:\t%s\n", strings.Join(objects, ", "))
+	fmt.Fprintf(tw, "Max combo:\t%d\n", info.MaxCombo)
+	fmt.Fprintf(tw, "Length:\t%s total, %s drain\n", formatDuration(info.TotalLength), formatDuration(info.DrainTime))
+	fmt.Fprintf(tw, "Density:\t%.2f objects/s\n", info.ObjectDensity)
+	fmt.Fprintf(tw, "Kiai:\t%s (%.0f%%)\n", formatDuration(info.KiaiTime), info.KiaiCoverage*100)
+	tw.Flush()
+}
+
+// formatDuration formats milliseconds as minutes and seconds, e.g. "3:29".
+func formatDuration(ms int) string {
+	return fmt.Sprintf("%d:%02d", ms/60000, ms/1000%60)
+}
+
+// runInfo runs info command.
+func runInfo(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("info", "<beatmap or mapset>", "Prints metadata, difficulty and length stats of every beatmap.", stderr)
+	asJSON := fs.Bool("json", false, "print information as JSON")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	m, cleanup, err := loadMapset(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	infos := make([]*beatmapInfo, len(m.Beatmaps))
+	for i, b := range m.Beatmaps {
+		infos[i] = newBeatmapInfo(b)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	}
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		info.write(stdout)
+	}
+	return nil
+}
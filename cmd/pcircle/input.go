@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pcircle "github.com/Polkisss/osu-parser"
+)
+
+// newFlagSet returns flag set of a command, which prints usage to stderr.
+func newFlagSet(name, args, description string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: pcircle %s [flags] %s\n\n%s\n", name, args, description)
+		var hasFlags bool
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(stderr, "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// parseFlags parses args with fs and checks number of positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return errUsage
+	}
+	if n := fs.NArg(); n < minArgs || (maxArgs >= 0 && n > maxArgs) {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// Kinds of inputs.
+const (
+	OSU_INPUT = iota
+	JSON_INPUT
+	DIRECTORY_INPUT
+	OSZ_INPUT
+)
+
+// inputKind returns kind of input at specified path.
+func inputKind(path string) (int, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if fi.IsDir() {
+		return DIRECTORY_INPUT, nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".osu":
+		return OSU_INPUT, nil
+	case ".json":
+		return JSON_INPUT, nil
+	case ".osz":
+		return OSZ_INPUT, nil
+	}
+	return 0, errors.New("unsupported input: " + path)
+}
+
+// loadMapset loads mapset from any supported input. Single beatmaps are wrapped into
+// a mapset without directory. Archives are extracted into a temporary directory,
+// which is removed by the returned cleanup function.
+func loadMapset(path string) (m *pcircle.Mapset, cleanup func(), err error) {
+	cleanup = func() {}
+	kind, err := inputKind(path)
+	if err != nil {
+		return nil, cleanup, err
+	}
+
+	m = pcircle.NewMapset()
+	switch kind {
+	case OSU_INPUT:
+		b := pcircle.NewBeatmap()
+		if err := b.FromFile(path); err != nil {
+			return nil, cleanup, err
+		}
+		m.Beatmaps = []*pcircle.Beatmap{b}
+		m.BeatmapSetID = b.BeatmapSetID
+	case JSON_INPUT:
+		m, err = loadJSON(path)
+		if err != nil {
+			return nil, cleanup, err
+		}
+	case DIRECTORY_INPUT:
+		err = m.FromDirectory(path)
+	case OSZ_INPUT:
+		var dir string
+		dir, cleanup, err = extractTemp(path)
+		if err == nil {
+			err = m.FromDirectory(dir)
+		}
+	}
+	if err != nil {
+		cleanup()
+		return nil, func() {}, err
+	}
+	return m, cleanup, nil
+}
+
+// loadJSON loads beatmap or mapset encoded to JSON. Beatmaps are wrapped into a mapset.
+func loadJSON(path string) (*pcircle.Mapset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	isMapset, err := isMapsetJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	m := pcircle.NewMapset()
+	if isMapset {
+		err = m.FromJSON(bytes.NewReader(data))
+		return m, err
+	}
+	b := pcircle.NewBeatmap()
+	if err := b.FromJSON(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	m.Beatmaps = []*pcircle.Beatmap{b}
+	m.BeatmapSetID = b.BeatmapSetID
+	return m, nil
+}
+
+// isMapsetJSON reports whether JSON object is an encoded mapset rather than a beatmap.
+func isMapsetJSON(data []byte) (bool, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return false, err
+	}
+	_, ok := keys["beatmaps"]
+	return ok, nil
+}
+
+// extractTemp extracts .osz archive into a temporary directory.
+func extractTemp(osz string) (dir string, cleanup func(), err error) {
+	dir, err = os.MkdirTemp("", "pcircle-")
+	if err != nil {
+		return "", func() {}, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	if err := pcircle.ExtractOSZ(osz, dir); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	return dir, cleanup, nil
+}
+
+// beatmapName returns readable name of beatmap, e.g. "Artist - Title (Creator) [Version]".
+func beatmapName(b *pcircle.Beatmap) string {
+	return fmt.Sprintf("%s - %s (%s) [%s]", b.Artist, b.Title, b.Creator, b.Version)
+}
+
+// beatmapFileName returns name of .osu file of beatmap, as osu! names it unless the
+// beatmap was loaded from a file.
+func beatmapFileName(b *pcircle.Beatmap) string {
+	if b.FilePath != "" {
+		return filepath.Base(b.FilePath)
+	}
+	return safeFileName(beatmapName(b)) + ".osu"
+}
+
+// safeFileName returns name without characters which are not allowed in file names.
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`\/:*?"<>|`, r) {
+			return -1
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,107 @@
+// Command pcircle inspects, validates and converts osu! beatmaps and mapsets.
+//
+// Usage:
+//
+//	pcircle <command> [flags] [arguments]
+//
+// Commands:
+//
+//	info      print metadata, difficulty and length stats
+//	validate  parse beatmaps strictly or leniently and run lint checks
+//	convert   convert between .osu, JSON and mapset directories
+//	diff      print structural difference between two versions of a beatmap
+//	merge     three-way merge of beatmap versions
+//	fmt       re-serialize .osu files canonically
+//	osz       pack mapset directory into .osz or unpack .osz
+//
+// Beatmaps and mapsets are accepted as .osu files, JSON files, mapset directories
+// and .osz archives. Exit code is 0 on success, 1 if the command failed, validation
+// found problems, beatmaps differ or a merge has conflicts, and 2 on invalid usage.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes.
+const (
+	EXIT_OK      = 0
+	EXIT_FAILURE = 1
+	EXIT_USAGE   = 2
+)
+
+// Errors returned by commands which have already printed the reason: usage on invalid
+// arguments and on -h, a report when validation fails or beatmaps differ.
+var (
+	errUsage  = errors.New("invalid usage")
+	errHelp   = errors.New("help requested")
+	errFailed = errors.New("command failed")
+)
+
+// command is a single pcircle subcommand.
+type command struct {
+	name        string
+	description string
+	run         func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []*command{
+	{"info", "print metadata, difficulty and length stats", runInfo},
+	{"validate", "parse beatmaps strictly or leniently and run lint checks", runValidate},
+	{"convert", "convert between .osu, JSON and mapset directories", runConvert},
+	{"diff", "print structural difference between two versions of a beatmap", runDiff},
+	{"merge", "three-way merge of beatmap versions", runMerge},
+	{"fmt", "re-serialize .osu files canonically", runFmt},
+	{"osz", "pack mapset directory into .osz or unpack .osz", runOSZ},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command specified by args and returns exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return EXIT_USAGE
+		}
+		return EXIT_OK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:], stdout, stderr)
+		switch {
+		case err == nil, errors.Is(err, errHelp):
+			return EXIT_OK
+		case errors.Is(err, errUsage):
+			return EXIT_USAGE
+		case errors.Is(err, errFailed):
+			return EXIT_FAILURE
+		}
+		fmt.Fprintln(stderr, "pcircle "+cmd.name+":", err)
+		return EXIT_FAILURE
+	}
+
+	fmt.Fprintln(stderr, "pcircle: unknown command "+args[0])
+	usage(stderr)
+	return EXIT_USAGE
+}
+
+// usage prints list of commands.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: pcircle <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "pcircle <command> -h" for help on a command.`)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pcircle "github.com/Polkisss/osu-parser"
+)
+
+// writeTestMapset writes mapset directory with a single beatmap and returns path of its .osu file.
+func writeTestMapset(t *testing.T) string {
+	b := pcircle.NewBeatmap()
+	b.AudioFilename = "audio.mp3"
+	b.Title, b.Artist, b.Creator, b.Version = "Title", "Artist", "Mapper", "Normal"
+	b.HPDrainRate, b.CircleSize, b.OverallDifficulty, b.ApproachRate = 5, 4, 8, 9
+	b.SliderMultiplier, b.SliderTickRate = 1.4, 1
+	b.TimingPoints = []*pcircle.TimingPoint{{Offset: 1000, MillisecondsPerBeat: 500, Meter: 4, Volume: 100, Inherited: true}}
+	b.HitObjects = []interface{}{
+		&pcircle.Circle{BaseHitObject: pcircle.BaseHitObject{X: 100, Y: 100, Time: 1000, Type: pcircle.CIRCLE | pcircle.NEW_COMBO, Extras: &pcircle.Extras{}}},
+		&pcircle.Circle{BaseHitObject: pcircle.BaseHitObject{X: 200, Y: 100, Time: 1500, Type: pcircle.CIRCLE, Extras: &pcircle.Extras{}}},
+		&pcircle.Spinner{BaseHitObject: pcircle.BaseHitObject{X: 256, Y: 192, Time: 2000, Type: pcircle.SPINNER, Extras: &pcircle.Extras{}}, EndTime: 3000},
+	}
+
+	dir := filepath.Join(t.TempDir(), "mapset")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "normal.osu")
+	if err := b.ToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// runTest runs pcircle with args and returns exit code and standard output.
+func runTest(args ...string) (int, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String()
+}
+
+func TestRun(t *testing.T) {
+	path := writeTestMapset(t)
+	dir := filepath.Dir(path)
+
+	broken := filepath.Join(t.TempDir(), "broken.osu")
+	data, _ := os.ReadFile(path)
+	data = bytes.Replace(data, []byte("HPDrainRate:5"), []byte("HPDrainRate:five"), 1)
+	if err := os.WriteFile(broken, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string
+	}{
+		{"no command", nil, EXIT_USAGE, ""},
+		{"unknown command", []string{"play"}, EXIT_USAGE, ""},
+		{"info", []string{"info", path}, EXIT_OK, "Artist - Title (Mapper) [Normal]"},
+		{"info json", []string{"info", "-json", dir}, EXIT_OK, `"max_combo": 3`},
+		{"info missing", []string{"info", filepath.Join(dir, "missing.osu")}, EXIT_FAILURE, ""},
+		{"validate", []string{"validate", "-no-lint", path}, EXIT_OK, "ok"},
+		{"validate strict", []string{"validate", "-no-lint", broken}, EXIT_FAILURE, "broken.osu:"},
+		{"validate lenient", []string{"validate", "-no-lint", "-lenient", broken}, EXIT_FAILURE, "five"},
+		{"validate severity", []string{"validate", "-fail-on", "fatal", path}, EXIT_USAGE, ""},
+		{"convert", []string{"convert", path}, EXIT_OK, `"type": "spinner"`},
+		{"convert format", []string{"convert", "-to", "yaml", path}, EXIT_USAGE, ""},
+		{"diff broken", []string{"diff", path, broken}, EXIT_FAILURE, ""},
+		{"diff equal", []string{"diff", path, path}, EXIT_OK, ""},
+		{"merge", []string{"merge", path, path, path}, EXIT_OK, "[HitObjects]"},
+		{"merge broken", []string{"merge", path, broken, path}, EXIT_FAILURE, ""},
+		{"fmt", []string{"fmt", path}, EXIT_OK, "[HitObjects]"},
+		{"fmt list", []string{"fmt", "-l", path}, EXIT_OK, ""},
+		{"fmt old version", []string{"fmt", "-version", "4", path}, EXIT_OK, "osu file format v4"},
+		{"fmt unsupported version", []string{"fmt", "-version", "-1", path}, EXIT_USAGE, ""},
+		{"osz", []string{"osz", "zip", dir}, EXIT_USAGE, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, out := runTest(tt.args...)
+			if code != tt.code {
+				t.Errorf("run(%v) = %v, want %v", tt.args, code, tt.code)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("run(%v) output = %v, want to contain %v", tt.args, out, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_convert(t *testing.T) {
+	path := writeTestMapset(t)
+	tmp := t.TempDir()
+	jsonPath, osuPath := filepath.Join(tmp, "beatmap.json"), filepath.Join(tmp, "beatmap.osu")
+
+	if code, _ := runTest("convert", "-o", jsonPath, path); code != EXIT_OK {
+		t.Fatalf("convert to JSON = %v, want %v", code, EXIT_OK)
+	}
+	if code, _ := runTest("convert", "-o", osuPath, jsonPath); code != EXIT_OK {
+		t.Fatalf("convert to .osu = %v, want %v", code, EXIT_OK)
+	}
+	want, _ := os.ReadFile(path)
+	got, _ := os.ReadFile(osuPath)
+	if string(got) != string(want) {
+		t.Errorf("convert round trip = %v, want %v", string(got), string(want))
+	}
+
+	// mapsets are converted to directories
+	mapsetJSON, out := filepath.Join(tmp, "mapset.json"), filepath.Join(tmp, "out")
+	if code, _ := runTest("convert", "-o", mapsetJSON, filepath.Dir(path)); code != EXIT_OK {
+		t.Fatalf("convert mapset to JSON = %v, want %v", code, EXIT_OK)
+	}
+	if code, _ := runTest("convert", "-o", out, mapsetJSON); code != EXIT_OK {
+		t.Fatalf("convert mapset to .osu = %v, want %v", code, EXIT_OK)
+	}
+	if got, _ := os.ReadFile(filepath.Join(out, "normal.osu")); string(got) != string(want) {
+		t.Errorf("convert mapset round trip = %v, want %v", string(got), string(want))
+	}
+}
+
+func TestRun_diff(t *testing.T) {
+	path := writeTestMapset(t)
+	changed := filepath.Join(t.TempDir(), "changed.osu")
+	data, _ := os.ReadFile(path)
+	data = bytes.Replace(data, []byte("Title:Title"), []byte("Title:New Title"), 1)
+	data = bytes.Replace(data, []byte("200,100,1500"), []byte("200,100,1750"), 1)
+	if err := os.WriteFile(changed, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out := runTest("diff", path, changed)
+	if code != EXIT_FAILURE {
+		t.Errorf("diff = %v, want %v", code, EXIT_FAILURE)
+	}
+	for _, want := range []string{`Metadata.Title: "Title" -> "New Title"`, "moved    circle 00:01:750 from 00:01:500"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("diff output = %v, want to contain %v", out, want)
+		}
+	}
+}
+
+func TestRun_merge(t *testing.T) {
+	path := writeTestMapset(t)
+	tmp := t.TempDir()
+	data, _ := os.ReadFile(path)
+
+	// Git passes temporary files without extension to merge drivers
+	base, ours, theirs := filepath.Join(tmp, "base"), filepath.Join(tmp, "ours"), filepath.Join(tmp, "theirs")
+	files := map[string][]byte{
+		base:   data,
+		ours:   bytes.Replace(data, []byte("Title:Title"), []byte("Title:New Title"), 1),
+		theirs: bytes.Replace(data, []byte("200,100,1500"), []byte("200,100,1750"), 1),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(name, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if code, _ := runTest("merge", "-o", ours, base, ours, theirs); code != EXIT_OK {
+		t.Fatalf("merge = %v, want %v", code, EXIT_OK)
+	}
+	merged, _ := os.ReadFile(ours)
+	for _, want := range []string{"Title:New Title", "200,100,1750"} {
+		if !strings.Contains(string(merged), want) {
+			t.Errorf("merge output = %v, want to contain %v", string(merged), want)
+		}
+	}
+
+	// conflicting changes of the same hit object
+	conflicting := bytes.Replace(data, []byte("200,100,1500"), []byte("220,100,1500"), 1)
+	if err := os.WriteFile(ours, conflicting, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, out := runTest("merge", base, ours, theirs); code != EXIT_FAILURE || !strings.Contains(out, "220,100,1500") {
+		t.Errorf("merge with conflicts = %v, %v, want %v", code, out, EXIT_FAILURE)
+	}
+}
+
+func TestWriteMapset(t *testing.T) {
+	m := pcircle.NewMapset()
+	b := pcircle.NewBeatmap()
+	b.Title, b.Artist, b.Creator, b.Version = "Why?", "AC/DC", "a:b", "Normal"
+	m.Beatmaps = append(m.Beatmaps, b)
+	m.Storyboard = new(pcircle.Storyboard)
+
+	dir := t.TempDir()
+	if err := writeMapset(m, dir); err != nil {
+		t.Fatalf("writeMapset() error = %v", err)
+	}
+	for _, name := range []string{"ACDC - Why (ab) [Normal].osu", "ACDC - Why (ab).osb"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("writeMapset() did not write %v: %v", name, err)
+		}
+	}
+}
+
+func TestRun_validateStoryboard(t *testing.T) {
+	path := writeTestMapset(t)
+	dir := filepath.Dir(path)
+	if err := os.Mkdir(filepath.Join(dir, "bad.osb"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// beatmaps are still parsed and linted
+	code, out := runTest("validate", "-fail-on", "info", dir)
+	if code != EXIT_FAILURE || !strings.Contains(out, "bad.osb: ") || !strings.Contains(out, "[Normal]") {
+		t.Errorf("validate with unreadable storyboard = %v, %v", code, out)
+	}
+}
+
+func TestRun_osz(t *testing.T) {
+	path := writeTestMapset(t)
+	tmp := t.TempDir()
+	osz, out := filepath.Join(tmp, "mapset.osz"), filepath.Join(tmp, "unpacked")
+
+	if code, _ := runTest("osz", "pack", "-o", osz, filepath.Dir(path)); code != EXIT_OK {
+		t.Fatalf("osz pack = %v, want %v", code, EXIT_OK)
+	}
+	if code, _ := runTest("osz", "unpack", "-o", out, osz); code != EXIT_OK {
+		t.Fatalf("osz unpack = %v, want %v", code, EXIT_OK)
+	}
+	want, _ := os.ReadFile(path)
+	if got, err := os.ReadFile(filepath.Join(out, "normal.osu")); err != nil || string(got) != string(want) {
+		t.Errorf("osz unpack = %v, %v, want %v", string(got), err, string(want))
+	}
+
+	if code, out := runTest("info", osz); code != EXIT_OK || !strings.Contains(out, "[Normal]") {
+		t.Errorf("info of .osz = %v, %v", code, out)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	pcircle "github.com/Polkisss/osu-parser"
+)
+
+// loadMergeInput loads beatmap to merge. Files which are not JSON are parsed as .osu
+// in lossless mode, as Git passes temporary files without extension to merge drivers
+// and unchanged lines should be kept as they are.
+func loadMergeInput(path string) (*pcircle.Beatmap, error) {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return loadBeatmap(path)
+	}
+	b := pcircle.NewBeatmap()
+	if err := b.FromFileLossless(path); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// runMerge runs merge command.
+func runMerge(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("merge", "<base> <ours> <theirs>",
+		"Merges changes made in two versions of a beatmap since their common ancestor and\n"+
+			"prints the merged beatmap in .osu format. Hit objects and timing points are merged\n"+
+			"per element and kept sorted. Elements changed differently on both sides are\n"+
+			"printed to standard error, our version is kept and the command exits with code 1.\n\n"+
+			"To use it as a Git merge driver for .osu files, add to .git/config:\n\n"+
+			"  [merge \"pcircle\"]\n"+
+			"  \tname = osu! beatmap merge\n"+
+			"  \tdriver = pcircle merge -o %A %O %A %B\n\n"+
+			"and \"*.osu merge=pcircle\" to .gitattributes.", stderr)
+	output := fs.String("o", "", "write merged beatmap to `file` instead of standard output")
+	if err := parseFlags(fs, args, 3, 3); err != nil {
+		return err
+	}
+
+	var beatmaps [3]*pcircle.Beatmap
+	for i := range beatmaps {
+		b, err := loadMergeInput(fs.Arg(i))
+		if err != nil {
+			return err
+		}
+		beatmaps[i] = b
+	}
+
+	merged, conflicts, err := pcircle.Merge(beatmaps[0], beatmaps[1], beatmaps[2])
+	if err != nil {
+		return err
+	}
+	err = writeOutput(*output, stdout, func(w io.Writer) error {
+		_, err := io.WriteString(w, merged.String())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range conflicts {
+		fmt.Fprintln(stderr, c)
+	}
+	if len(conflicts) > 0 {
+		return errFailed
+	}
+	return nil
+}
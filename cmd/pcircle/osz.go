@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pcircle "github.com/Polkisss/osu-parser"
+)
+
+// runOSZ runs osz command.
+func runOSZ(args []string, stdout, stderr io.Writer) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "pack":
+			return runOSZPack(args[1:], stdout, stderr)
+		case "unpack":
+			return runOSZUnpack(args[1:], stdout, stderr)
+		case "-h", "--help":
+			oszUsage(stderr)
+			return errHelp
+		}
+	}
+	oszUsage(stderr)
+	return errUsage
+}
+
+// oszUsage prints usage of osz command.
+func oszUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: pcircle osz pack [flags] <directory>")
+	fmt.Fprintln(w, "       pcircle osz unpack [flags] <file.osz>")
+}
+
+// runOSZPack runs osz pack command.
+func runOSZPack(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("osz pack", "<directory>", "Packs mapset directory into .osz archive.", stderr)
+	output := fs.String("o", "", "output .osz file, the directory name with .osz extension if empty")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	m := pcircle.NewMapset()
+	if err := m.FromDirectory(fs.Arg(0)); err != nil {
+		return err
+	}
+	buf, err := m.ToOSZ()
+	if err != nil {
+		return err
+	}
+
+	path := *output
+	if path == "" {
+		path = filepath.Clean(fs.Arg(0)) + ".osz"
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, path)
+	return nil
+}
+
+// runOSZUnpack runs osz unpack command.
+func runOSZUnpack(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("osz unpack", "<file.osz>", "Extracts .osz archive into a directory.", stderr)
+	output := fs.String("o", "", "output directory, the archive name without extension if empty")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	dir := *output
+	if dir == "" {
+		dir = strings.TrimSuffix(fs.Arg(0), filepath.Ext(fs.Arg(0)))
+	}
+	if err := pcircle.ExtractOSZ(fs.Arg(0), dir); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, dir)
+	return nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	pcircle "github.com/Polkisss/osu-parser"
+)
+
+// Severities accepted by -fail-on flag.
+var severities = map[string]pcircle.Severity{
+	"info":    pcircle.INFO_SEVERITY,
+	"warning": pcircle.WARNING_SEVERITY,
+	"problem": pcircle.PROBLEM_SEVERITY,
+}
+
+// parseFailure is a beatmap file which could not be parsed, fully or partially.
+type parseFailure struct {
+	File  string `json:"file"`
+	Line  int    `json:"line,omitempty"`
+	Text  string `json:"text,omitempty"`
+	Error string `json:"error"`
+}
+
+// issueReport is a lint issue in validation report.
+type issueReport struct {
+	Check    string           `json:"check"`
+	Severity pcircle.Severity `json:"severity"`
+	Beatmap  string           `json:"beatmap,omitempty"`
+	Time     int              `json:"time"`
+	Message  string           `json:"message"`
+}
+
+// validationReport is output of validate command.
+type validationReport struct {
+	Valid       bool            `json:"valid"`
+	ParseErrors []*parseFailure `json:"parse_errors"`
+	Issues      []*issueReport  `json:"issues"`
+}
+
+// parseMapset parses beatmaps of a mapset directory or a single .osu file strictly or
+// leniently and returns mapset of beatmaps which were parsed. Storyboard which could not
+// be parsed is reported as a failure and left out.
+func parseMapset(path string, kind int, lenient bool) (*pcircle.Mapset, []*parseFailure, error) {
+	m := pcircle.NewMapset()
+	files := []string{path}
+	var sbfiles []string
+	if kind == DIRECTORY_INPUT {
+		m.DirectoryPath = path
+		var err error
+		files, err = filepath.Glob(filepath.Join(path, "*.osu"))
+		if err != nil {
+			return nil, nil, err
+		}
+		sbfiles, err = filepath.Glob(filepath.Join(path, "*.osb"))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var failures []*parseFailure
+	if len(sbfiles) > 0 {
+		sb := new(pcircle.Storyboard)
+		if err := sb.FromFile(sbfiles[0]); err != nil {
+			failures = append(failures, newParseFailure(sbfiles[0], err))
+		} else {
+			m.Storyboard = sb
+		}
+	}
+	for _, file := range files {
+		b := pcircle.NewBeatmap()
+		if !lenient {
+			if err := b.FromFile(file); err != nil {
+				failures = append(failures, newParseFailure(file, err))
+				continue
+			}
+		} else {
+			errs, err := b.FromFileLenient(file)
+			if err != nil {
+				failures = append(failures, newParseFailure(file, err))
+				continue
+			}
+			for _, err := range errs {
+				failures = append(failures, newParseFailure(file, err))
+			}
+		}
+		m.Beatmaps = append(m.Beatmaps, b)
+	}
+	if len(m.Beatmaps) > 0 {
+		m.BeatmapSetID = m.Beatmaps[0].BeatmapSetID
+	}
+	return m, failures, nil
+}
+
+// newParseFailure returns parseFailure of a file.
+func newParseFailure(file string, err error) *parseFailure {
+	f := &parseFailure{File: file, Error: err.Error()}
+	var perr *pcircle.ParseError
+	if errors.As(err, &perr) {
+		f.Line, f.Text, f.Error = perr.Line, perr.Text, perr.Err.Error()
+	}
+	return f
+}
+
+// runValidate runs validate command.
+func runValidate(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("validate", "<beatmap or mapset>",
+		"Parses beatmaps and runs lint checks over them. Exits with code 1 if a beatmap\n"+
+			"could not be parsed or an issue of -fail-on severity or higher is found.", stderr)
+	lenient := fs.Bool("lenient", false, "skip lines which could not be parsed instead of rejecting the beatmap")
+	noLint := fs.Bool("no-lint", false, "only parse beatmaps")
+	failOn := fs.String("fail-on", "problem", "lowest severity of issues which fail validation: info, warning or problem")
+	asJSON := fs.Bool("json", false, "print report as JSON")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	threshold, ok := severities[*failOn]
+	if !ok {
+		fmt.Fprintln(stderr, "pcircle validate: invalid -fail-on severity "+*failOn)
+		return errUsage
+	}
+
+	path := fs.Arg(0)
+	kind, err := inputKind(path)
+	if err != nil {
+		return err
+	}
+	cleanup := func() {}
+	switch kind {
+	case JSON_INPUT:
+		return errors.New("JSON input can not be validated, convert it to .osu first")
+	case OSZ_INPUT:
+		path, cleanup, err = extractTemp(path)
+		if err != nil {
+			return err
+		}
+		kind = DIRECTORY_INPUT
+	}
+	defer cleanup()
+
+	m, failures, err := parseMapset(path, kind, *lenient)
+	if err != nil {
+		return err
+	}
+
+	report := &validationReport{ParseErrors: failures, Issues: []*issueReport{}}
+	if report.ParseErrors == nil {
+		report.ParseErrors = []*parseFailure{}
+	}
+	if !*noLint {
+		var issues []*pcircle.Issue
+		if kind == DIRECTORY_INPUT {
+			issues = pcircle.NewLinter().LintMapset(m)
+		} else if len(m.Beatmaps) > 0 {
+			issues = pcircle.NewLinter().LintBeatmap(m.Beatmaps[0])
+		}
+		for _, issue := range issues {
+			r := &issueReport{Check: issue.Check, Severity: issue.Severity, Time: issue.Time, Message: issue.Message}
+			if issue.Beatmap != nil {
+				r.Beatmap = issue.Beatmap.Version
+			}
+			report.Issues = append(report.Issues, r)
+		}
+	}
+
+	report.Valid = len(report.ParseErrors) == 0
+	for _, issue := range report.Issues {
+		if issue.Severity >= threshold {
+			report.Valid = false
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		report.write(stdout)
+	}
+
+	if !report.Valid {
+		return errFailed
+	}
+	return nil
+}
+
+// write prints report in readable format.
+func (r *validationReport) write(w io.Writer) {
+	for _, f := range r.ParseErrors {
+		if f.Line > 0 {
+			fmt.Fprintf(w, "%s:%d: %s\n", f.File, f.Line, f.Error)
+		} else {
+			fmt.Fprintf(w, "%s: %s\n", f.File, f.Error)
+		}
+	}
+	for _, issue := range r.Issues {
+		where := "mapset"
+		if issue.Beatmap != "" {
+			where = "[" + issue.Beatmap + "]"
+		}
+		if issue.Time != pcircle.NO_TIME {
+			where += " " + pcircle.EditorTimestamp(issue.Time)
+		}
+		fmt.Fprintf(w, "%s %s %s: %s\n", where, issue.Severity, issue.Check, issue.Message)
+	}
+	if r.Valid {
+		fmt.Fprintln(w, "ok")
+	} else {
+		fmt.Fprintln(w, "FAIL")
+	}
+}
@@ -0,0 +1,160 @@
+package pcircle
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// Client version written to databases which have no version set.
+const DEFAULT_DB_VERSION = 20250108
+
+// NewCollectionDB returns a new empty CollectionDB.
+func NewCollectionDB() *CollectionDB {
+	return new(CollectionDB)
+}
+
+// CollectionDB stores contents of collection.db, the beatmap collections of osu! client.
+type CollectionDB struct {
+	Version     int // Version of the client which wrote the file, like 20250108
+	Collections []*Collection
+}
+
+// Collection is a named list of beatmaps identified by MD5 hashes of their .osu files.
+type Collection struct {
+	Name string
+	MD5s []string
+}
+
+// FromFile parses specified collection.db file and fills CollectionDB with data.
+func (db *CollectionDB) FromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return db.FromReader(f)
+}
+
+// FromReader parses collection.db from r and fills CollectionDB with data.
+func (db *CollectionDB) FromReader(r io.Reader) error {
+	dr := newDBReader(r)
+
+	db.Version = dr.int()
+	count := dr.int()
+	if dr.err != nil {
+		return dr.err
+	}
+	if count < 0 {
+		return errors.New("invalid number of collections")
+	}
+
+	db.Collections = make([]*Collection, 0, count)
+	for i := 0; i < count; i++ {
+		c := &Collection{Name: dr.string()}
+		n := dr.int()
+		if dr.err == nil && n < 0 {
+			return errors.New("invalid number of beatmaps in collection " + c.Name)
+		}
+		for j := 0; j < n && dr.err == nil; j++ {
+			c.MD5s = append(c.MD5s, dr.string())
+		}
+		if dr.err != nil {
+			return dr.err
+		}
+		db.Collections = append(db.Collections, c)
+	}
+	return nil
+}
+
+// ToFile writes CollectionDB to specified collection.db file.
+func (db *CollectionDB) ToFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := db.Write(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Write writes CollectionDB in collection.db format to w.
+// DEFAULT_DB_VERSION is written if version is not set.
+func (db *CollectionDB) Write(w io.Writer) error {
+	dw := newDBWriter(w)
+
+	version := db.Version
+	if version == 0 {
+		version = DEFAULT_DB_VERSION
+	}
+	dw.int(version)
+	dw.int(len(db.Collections))
+	for _, c := range db.Collections {
+		dw.string(c.Name)
+		dw.int(len(c.MD5s))
+		for _, hash := range c.MD5s {
+			dw.string(hash)
+		}
+	}
+	return dw.flush()
+}
+
+// Collection returns collection with specified name, or nil if there is none.
+func (db *CollectionDB) Collection(name string) *Collection {
+	for _, c := range db.Collections {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// NewCollection creates collection of beatmaps in specified mapsets for which match
+// returns true. All beatmaps are added if match is nil.
+//
+// For example, collection of all mapsets by a creator:
+//
+//	NewCollection("X", mapsets, func(b *Beatmap) bool { return b.Creator == "X" })
+func NewCollection(name string, mapsets []*Mapset, match func(b *Beatmap) bool) (*Collection, error) {
+	c := &Collection{Name: name}
+	for _, m := range mapsets {
+		for _, b := range m.Beatmaps {
+			if match != nil && !match(b) {
+				continue
+			}
+			hash, err := b.MD5()
+			if err != nil {
+				return nil, err
+			}
+			c.MD5s = append(c.MD5s, hash)
+		}
+	}
+	return c, nil
+}
+
+// Resolve finds beatmaps of the collection in specified mapsets.
+// Returns found beatmaps in the collection order and hashes which were not found.
+func (c *Collection) Resolve(mapsets []*Mapset) (beatmaps []*Beatmap, missing []string, err error) {
+	byHash := make(map[string]*Beatmap)
+	for _, m := range mapsets {
+		for _, b := range m.Beatmaps {
+			hash, err := b.MD5()
+			if err != nil {
+				return nil, nil, err
+			}
+			byHash[hash] = b
+		}
+	}
+
+	for _, hash := range c.MD5s {
+		if b, ok := byHash[hash]; ok {
+			beatmaps = append(beatmaps, b)
+		} else {
+			missing = append(missing, hash)
+		}
+	}
+	return beatmaps, missing, nil
+}
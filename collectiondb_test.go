@@ -0,0 +1,56 @@
+package pcircle
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCollectionDB_Write(t *testing.T) {
+	db := &CollectionDB{Version: 20240820, Collections: []*Collection{
+		{Name: "Favourites", MD5s: []string{"0123456789abcdef0123456789abcdef", "fedcba9876543210fedcba9876543210"}},
+		{Name: "Empty"},
+	}}
+
+	var buf bytes.Buffer
+	if err := db.Write(&buf); err != nil {
+		t.Fatalf("CollectionDB.Write() error = %v", err)
+	}
+
+	got := NewCollectionDB()
+	if err := got.FromReader(&buf); err != nil {
+		t.Fatalf("CollectionDB.FromReader() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, db) {
+		t.Errorf("CollectionDB.FromReader() = %+v, want %+v", got, db)
+	}
+}
+
+func TestCollection_Resolve(t *testing.T) {
+	dir := t.TempDir()
+	m := &Mapset{DirectoryPath: dir}
+	for _, creator := range []string{"X", "Y"} {
+		b := newTestBeatmap()
+		b.Creator = creator
+		b.FilePath = filepath.Join(dir, creator+".osu")
+		if err := b.ToFile(b.FilePath); err != nil {
+			t.Fatal(err)
+		}
+		m.Beatmaps = append(m.Beatmaps, b)
+	}
+
+	c, err := NewCollection("X", []*Mapset{m}, func(b *Beatmap) bool { return b.Creator == "X" })
+	if err != nil {
+		t.Fatalf("NewCollection() error = %v", err)
+	}
+	c.MD5s = append(c.MD5s, "00000000000000000000000000000000")
+
+	beatmaps, missing, err := c.Resolve([]*Mapset{m})
+	if err != nil {
+		t.Fatalf("Collection.Resolve() error = %v", err)
+	}
+	if len(beatmaps) != 1 || beatmaps[0].Creator != "X" || len(missing) != 1 {
+		t.Errorf("Collection.Resolve() = %v, %v", beatmaps, missing)
+	}
+}
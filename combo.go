@@ -0,0 +1,89 @@
+package pcircle
+
+// DefaultComboColours are combo colours of the default osu! skin.
+var DefaultComboColours = []*RGB{
+	{255, 192, 0},
+	{0, 202, 0},
+	{18, 124, 255},
+	{242, 24, 57},
+}
+
+// ComboInfo holds combo information of a single hit object.
+type ComboInfo struct {
+	ComboIndex  int  `json:"combo_index"`      // Number of the combo in the beatmap, starting from 1
+	ColourIndex int  `json:"colour_index"`     // Zero-based index of the combo colour, taking colour skips into account
+	Number      int  `json:"number"`           // Number of the hit object within its combo, starting from 1. Zero for spinners
+	Colour      *RGB `json:"colour,omitempty"` // Resolved combo colour, nil if there are no colours
+}
+
+// ComboSkip returns number of combo colours skipped by the hit object (COMBO_SKIP_1..3 bits).
+func (o BaseHitObject) ComboSkip() int {
+	return (o.Type >> 4) & 7
+}
+
+// IsNewCombo reports whether hit object has NEW_COMBO bit set.
+func (o BaseHitObject) IsNewCombo() bool {
+	return o.Type&NEW_COMBO > 0
+}
+
+// Combos computes combo information for every hit object, in order of Beatmap.HitObjects.
+//
+// The first hit object always starts a new combo. Spinners are not numbered and
+// do not start combos themselves: their new combo flag and colour skip are carried
+// over to the next hit object. In beatmaps of file format v8 and older the object
+// after a spinner always starts a new combo.
+//
+// defaultColours are used when beatmap has no combo colours, e.g. DefaultComboColours
+// or colours of a skin. If both are empty, Colour is nil.
+func (b *Beatmap) Combos(defaultColours []*RGB) []*ComboInfo {
+	colours := b.ComboColours
+	if len(colours) == 0 {
+		colours = defaultColours
+	}
+
+	combos := make([]*ComboInfo, len(b.HitObjects))
+
+	comboIndex, colourIndex, number := 0, -1, 0
+	forceNewCombo, extraSkip := false, 0
+	first := true
+
+	for i, hitObject := range b.HitObjects {
+		base := BaseOf(hitObject)
+
+		if _, ok := hitObject.(*Spinner); ok {
+			forceNewCombo = forceNewCombo || base.IsNewCombo() || b.FileFormatVersion <= 8
+			extraSkip += base.ComboSkip()
+			combos[i] = &ComboInfo{
+				ComboIndex:  comboIndex,
+				ColourIndex: colourIndex,
+				Colour:      comboColour(colours, colourIndex),
+			}
+			continue
+		}
+
+		if first || base.IsNewCombo() || forceNewCombo {
+			comboIndex++
+			colourIndex += 1 + base.ComboSkip() + extraSkip
+			number = 0
+		}
+		number++
+		first, forceNewCombo, extraSkip = false, false, 0
+
+		combos[i] = &ComboInfo{
+			ComboIndex:  comboIndex,
+			ColourIndex: colourIndex,
+			Number:      number,
+			Colour:      comboColour(colours, colourIndex),
+		}
+	}
+
+	return combos
+}
+
+// comboColour returns colour with specified index, wrapping around colours list.
+func comboColour(colours []*RGB, index int) *RGB {
+	if len(colours) == 0 || index < 0 {
+		return nil
+	}
+	return colours[index%len(colours)]
+}
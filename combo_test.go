@@ -0,0 +1,97 @@
+package pcircle
+
+import (
+	"testing"
+)
+
+func TestBeatmap_Combos(t *testing.T) {
+	colours := []*RGB{{255, 0, 0}, {0, 255, 0}, {0, 0, 255}}
+
+	tests := []struct {
+		name           string
+		version        int
+		colours        []*RGB
+		defaultColours []*RGB
+		types          []int
+		wantNumbers    []int
+		wantColours    []int
+		wantNilColour  bool
+	}{
+		{
+			name:        "new combos and colour skip",
+			version:     14,
+			colours:     colours,
+			types:       []int{CIRCLE, CIRCLE, CIRCLE | NEW_COMBO, SLIDER | NEW_COMBO | COMBO_SKIP_1, CIRCLE},
+			wantNumbers: []int{1, 2, 1, 1, 2},
+			wantColours: []int{0, 0, 1, 0, 0},
+		},
+		{
+			name:        "spinner carries new combo",
+			version:     14,
+			colours:     colours,
+			types:       []int{CIRCLE | NEW_COMBO, SPINNER | NEW_COMBO | COMBO_SKIP_1, CIRCLE, CIRCLE},
+			wantNumbers: []int{1, 0, 1, 2},
+			wantColours: []int{0, 0, 2, 2},
+		},
+		{
+			name:        "old beatmap forces new combo after spinner",
+			version:     7,
+			colours:     colours,
+			types:       []int{CIRCLE | NEW_COMBO, SPINNER, CIRCLE},
+			wantNumbers: []int{1, 0, 1},
+			wantColours: []int{0, 0, 1},
+		},
+		{
+			name:           "skin default colours",
+			version:        14,
+			defaultColours: DefaultComboColours,
+			types:          []int{CIRCLE | NEW_COMBO, CIRCLE | NEW_COMBO | COMBO_SKIP_2 | COMBO_SKIP_1},
+			wantNumbers:    []int{1, 1},
+			wantColours:    []int{0, 0},
+		},
+		{
+			name:          "no colours",
+			version:       14,
+			types:         []int{CIRCLE | NEW_COMBO},
+			wantNumbers:   []int{1},
+			wantColours:   []int{0},
+			wantNilColour: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Beatmap{FileFormatVersion: tt.version, ComboColours: tt.colours}
+			for i, typ := range tt.types {
+				base := BaseHitObject{Time: i * 1000, Type: typ}
+				switch {
+				case typ&SPINNER > 0:
+					b.HitObjects = append(b.HitObjects, &Spinner{BaseHitObject: base})
+				case typ&SLIDER > 0:
+					b.HitObjects = append(b.HitObjects, &Slider{BaseHitObject: base})
+				default:
+					b.HitObjects = append(b.HitObjects, &Circle{base})
+				}
+			}
+
+			palette := tt.colours
+			if len(palette) == 0 {
+				palette = tt.defaultColours
+			}
+
+			for i, combo := range b.Combos(tt.defaultColours) {
+				if combo.Number != tt.wantNumbers[i] {
+					t.Errorf("Beatmap.Combos() number of object %v = %v, want %v", i, combo.Number, tt.wantNumbers[i])
+				}
+				if tt.wantNilColour {
+					if combo.Colour != nil {
+						t.Errorf("Beatmap.Combos() colour of object %v = %v, want nil", i, combo.Colour)
+					}
+					continue
+				}
+				if combo.Colour != palette[tt.wantColours[i]] {
+					t.Errorf("Beatmap.Combos() colour of object %v = %v, want %v", i, combo.Colour, palette[tt.wantColours[i]])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package pcircle
+
+import (
+	"math"
+)
+
+// All possible slider curve types.
+const (
+	LINEAR_CURVE         = "L"
+	PERFECT_CIRCLE_CURVE = "P"
+	BEZIER_CURVE         = "B"
+	CATMULL_CURVE        = "C"
+)
+
+// Approximation settings of slider curves.
+const (
+	curveSegmentLength = 2.0 // Maximal distance between approximated points of curved segments
+	catmullDetail      = 50  // Number of approximated points per catmull segment
+)
+
+// Point is a position on the playfield in osu!pixels.
+type Point struct {
+	X, Y float64
+}
+
+// Add returns sum of two points.
+func (p Point) Add(q Point) Point {
+	return Point{p.X + q.X, p.Y + q.Y}
+}
+
+// Sub returns difference of two points.
+func (p Point) Sub(q Point) Point {
+	return Point{p.X - q.X, p.Y - q.Y}
+}
+
+// Scale returns point multiplied by scalar.
+func (p Point) Scale(k float64) Point {
+	return Point{p.X * k, p.Y * k}
+}
+
+// Length returns distance from the origin to the point.
+func (p Point) Length() float64 {
+	return math.Hypot(p.X, p.Y)
+}
+
+// Distance returns distance between two points.
+func (p Point) Distance(q Point) float64 {
+	return p.Sub(q).Length()
+}
+
+// Position returns position of hit object as Point.
+func (o BaseHitObject) Position() Point {
+	return Point{float64(o.X), float64(o.Y)}
+}
+
+// controlPoints returns slider control points, including its head.
+func (s *Slider) controlPoints() []Point {
+	points := []Point{s.Position()}
+	if s.SliderPath == nil {
+		return points
+	}
+	for _, p := range s.SliderPath.CurvePoints {
+		points = append(points, Point{float64(p.X), float64(p.Y)})
+	}
+	return points
+}
+
+// Path returns slider path approximated as a polyline, starting at slider head
+// and having exactly the length of PixelLength.
+func (s *Slider) Path() []Point {
+	controlPoints := s.controlPoints()
+
+	var path []Point
+	sliderType := ""
+	if s.SliderPath != nil {
+		sliderType = s.SliderPath.SliderType
+	}
+
+	switch sliderType {
+	case LINEAR_CURVE:
+		path = controlPoints
+	case PERFECT_CIRCLE_CURVE:
+		path = approximateCircularArc(controlPoints)
+		if path == nil {
+			path = approximateBezierSegments(controlPoints)
+		}
+	case CATMULL_CURVE:
+		path = approximateCatmull(controlPoints)
+	default:
+		path = approximateBezierSegments(controlPoints)
+	}
+
+	return trimPath(path, s.PixelLength)
+}
+
+// PositionAt returns position on the slider path at specified progress,
+// where 0 is the slider head and 1 is the slider tail.
+func (s *Slider) PositionAt(progress float64) Point {
+	return positionOnPath(s.Path(), s.PixelLength*math.Min(math.Max(progress, 0), 1))
+}
+
+// EndPosition returns position where the slider ends, taking repeats into account.
+func (s *Slider) EndPosition() Point {
+	if s.Repeat%2 == 0 {
+		return s.Position()
+	}
+	return s.PositionAt(1)
+}
+
+// positionOnPath returns position on polyline at specified distance from its start.
+func positionOnPath(path []Point, distance float64) Point {
+	if len(path) == 0 {
+		return Point{}
+	}
+	for i := 1; i < len(path); i++ {
+		segment := path[i].Distance(path[i-1])
+		if distance <= segment {
+			if segment == 0 {
+				return path[i]
+			}
+			return path[i-1].Add(path[i].Sub(path[i-1]).Scale(distance / segment))
+		}
+		distance -= segment
+	}
+	return path[len(path)-1]
+}
+
+// trimPath cuts or extends the last segment of the polyline so it has specified length.
+func trimPath(path []Point, length float64) []Point {
+	if len(path) < 2 || length <= 0 {
+		return path
+	}
+
+	var total float64
+	for i := 1; i < len(path); i++ {
+		segment := path[i].Distance(path[i-1])
+		if total+segment >= length {
+			trimmed := append([]Point(nil), path[:i]...)
+			if segment == 0 {
+				return append(trimmed, path[i])
+			}
+			return append(trimmed, path[i-1].Add(path[i].Sub(path[i-1]).Scale((length-total)/segment)))
+		}
+		total += segment
+	}
+
+	// path is shorter than expected, extend it in the direction of the last segment
+	last, prev := path[len(path)-1], path[len(path)-2]
+	segment := last.Distance(prev)
+	if segment == 0 {
+		return path
+	}
+	extended := append([]Point(nil), path...)
+	extended[len(extended)-1] = prev.Add(last.Sub(prev).Scale((segment + length - total) / segment))
+	return extended
+}
+
+// approximateBezierSegments approximates bezier curve. Repeated control points
+// (red anchors) split the curve into separate bezier segments.
+func approximateBezierSegments(points []Point) []Point {
+	var path []Point
+	start := 0
+	for i := 1; i <= len(points); i++ {
+		if i == len(points) || points[i] == points[i-1] {
+			segment := approximateBezier(points[start:i])
+			if len(path) > 0 && len(segment) > 0 {
+				segment = segment[1:]
+			}
+			path = append(path, segment...)
+			start = i
+		}
+	}
+	return path
+}
+
+// approximateBezier approximates single bezier curve with specified control points.
+func approximateBezier(points []Point) []Point {
+	if len(points) < 3 {
+		return append([]Point(nil), points...)
+	}
+
+	var controlLength float64
+	for i := 1; i < len(points); i++ {
+		controlLength += points[i].Distance(points[i-1])
+	}
+	steps := int(math.Ceil(controlLength / curveSegmentLength))
+	if steps < 1 {
+		steps = 1
+	}
+
+	path := make([]Point, 0, steps+1)
+	buf := make([]Point, len(points))
+	for step := 0; step <= steps; step++ {
+		t := float64(step) / float64(steps)
+		copy(buf, points)
+		for n := len(buf) - 1; n > 0; n-- {
+			for i := 0; i < n; i++ {
+				buf[i] = buf[i].Add(buf[i+1].Sub(buf[i]).Scale(t))
+			}
+		}
+		path = append(path, buf[0])
+	}
+	return path
+}
+
+// approximateCircularArc approximates circular arc which passes through three points.
+// Returns nil if there are not exactly three points or they lie on the same line.
+func approximateCircularArc(points []Point) []Point {
+	if len(points) != 3 {
+		return nil
+	}
+	a, b, c := points[0], points[1], points[2]
+
+	d := 2 * (a.X*(b.Y-c.Y) + b.X*(c.Y-a.Y) + c.X*(a.Y-b.Y))
+	if math.Abs(d) < 1e-3 {
+		return nil
+	}
+
+	aSq, bSq, cSq := a.X*a.X+a.Y*a.Y, b.X*b.X+b.Y*b.Y, c.X*c.X+c.Y*c.Y
+	centre := Point{
+		(aSq*(b.Y-c.Y) + bSq*(c.Y-a.Y) + cSq*(a.Y-b.Y)) / d,
+		(aSq*(c.X-b.X) + bSq*(a.X-c.X) + cSq*(b.X-a.X)) / d,
+	}
+	radius := a.Distance(centre)
+
+	thetaStart := math.Atan2(a.Y-centre.Y, a.X-centre.X)
+	thetaEnd := math.Atan2(c.Y-centre.Y, c.X-centre.X)
+	for thetaEnd < thetaStart {
+		thetaEnd += 2 * math.Pi
+	}
+
+	direction := 1.0
+	thetaRange := thetaEnd - thetaStart
+
+	// decide direction by whether the middle point lies on the counter-clockwise arc
+	orthoAtoC := Point{c.Y - a.Y, -(c.X - a.X)}
+	if orthoAtoC.X*(b.X-a.X)+orthoAtoC.Y*(b.Y-a.Y) < 0 {
+		direction = -1
+		thetaRange = 2*math.Pi - thetaRange
+	}
+
+	steps := int(math.Ceil(thetaRange * radius / curveSegmentLength))
+	if steps < 2 {
+		steps = 2
+	}
+
+	path := make([]Point, steps+1)
+	for i := 0; i <= steps; i++ {
+		theta := thetaStart + direction*float64(i)/float64(steps)*thetaRange
+		path[i] = Point{centre.X + radius*math.Cos(theta), centre.Y + radius*math.Sin(theta)}
+	}
+	return path
+}
+
+// approximateCatmull approximates uniform catmull-rom spline through control points.
+func approximateCatmull(points []Point) []Point {
+	if len(points) < 2 {
+		return append([]Point(nil), points...)
+	}
+
+	var path []Point
+	for i := 0; i < len(points)-1; i++ {
+		v1 := points[i]
+		if i > 0 {
+			v1 = points[i-1]
+		}
+		v2 := points[i]
+		v3 := points[i+1]
+		v4 := v3.Add(v3.Sub(v2))
+		if i+2 < len(points) {
+			v4 = points[i+2]
+		}
+
+		for c := 0; c < catmullDetail; c++ {
+			path = append(path, catmullPoint(v1, v2, v3, v4, float64(c)/catmullDetail))
+		}
+	}
+	return append(path, points[len(points)-1])
+}
+
+// catmullPoint returns point of catmull-rom segment between v2 and v3 at specified t.
+func catmullPoint(v1, v2, v3, v4 Point, t float64) Point {
+	t2 := t * t
+	t3 := t * t2
+	coord := func(p1, p2, p3, p4 float64) float64 {
+		return 0.5 * (2*p2 + (-p1+p3)*t + (2*p1-5*p2+4*p3-p4)*t2 + (-p1+3*p2-3*p3+p4)*t3)
+	}
+	return Point{coord(v1.X, v2.X, v3.X, v4.X), coord(v1.Y, v2.Y, v3.Y, v4.Y)}
+}
@@ -0,0 +1,73 @@
+package pcircle
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSlider_PositionAt(t *testing.T) {
+	tests := []struct {
+		name        string
+		slider      *Slider
+		progress    float64
+		want        Point
+		wantEndSame bool
+	}{
+		{
+			name: "linear slider trimmed",
+			slider: &Slider{
+				BaseHitObject: BaseHitObject{X: 100, Y: 100},
+				SliderPath:    &SliderPath{SliderType: LINEAR_CURVE, CurvePoints: []*SliderCurvePoint{{300, 100}}},
+				Repeat:        1,
+				PixelLength:   100,
+			},
+			progress: 1,
+			want:     Point{200, 100},
+		},
+		{
+			name: "linear slider extended",
+			slider: &Slider{
+				BaseHitObject: BaseHitObject{X: 100, Y: 100},
+				SliderPath:    &SliderPath{SliderType: LINEAR_CURVE, CurvePoints: []*SliderCurvePoint{{100, 150}}},
+				Repeat:        1,
+				PixelLength:   80,
+			},
+			progress: 1,
+			want:     Point{100, 180},
+		},
+		{
+			name: "perfect circle half",
+			slider: &Slider{
+				BaseHitObject: BaseHitObject{X: 0, Y: 100},
+				SliderPath:    &SliderPath{SliderType: PERFECT_CIRCLE_CURVE, CurvePoints: []*SliderCurvePoint{{100, 0}, {200, 100}}},
+				Repeat:        1,
+				PixelLength:   100 * math.Pi,
+			},
+			progress: 0.5,
+			want:     Point{100, 0},
+		},
+		{
+			name: "bezier with red anchor",
+			slider: &Slider{
+				BaseHitObject: BaseHitObject{X: 0, Y: 0},
+				SliderPath:    &SliderPath{SliderType: BEZIER_CURVE, CurvePoints: []*SliderCurvePoint{{100, 0}, {100, 0}, {100, 100}}},
+				Repeat:        2,
+				PixelLength:   200,
+			},
+			progress:    1,
+			want:        Point{100, 100},
+			wantEndSame: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.slider.PositionAt(tt.progress)
+			if got.Distance(tt.want) > 0.5 {
+				t.Errorf("Slider.PositionAt() = %v, want %v", got, tt.want)
+			}
+			if tt.wantEndSame && tt.slider.EndPosition() != tt.slider.Position() {
+				t.Errorf("Slider.EndPosition() = %v, want slider head", tt.slider.EndPosition())
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package pcircle
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// Number of .NET DateTime ticks (100 nanoseconds since 0001-01-01) at the Unix epoch.
+const unixEpochTicks = 621355968000000000
+
+// dbReader reads values of types used in osu! client databases.
+// The first error stops reading, following reads return zero values.
+type dbReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+// newDBReader returns dbReader reading from r.
+func newDBReader(r io.Reader) *dbReader {
+	return &dbReader{r: bufio.NewReader(r)}
+}
+
+// read reads little-endian value of fixed size into v.
+func (r *dbReader) read(v interface{}) {
+	if r.err != nil {
+		return
+	}
+	r.err = binary.Read(r.r, binary.LittleEndian, v)
+	if r.err == io.EOF {
+		r.err = io.ErrUnexpectedEOF
+	}
+}
+
+func (r *dbReader) byte() byte {
+	var v byte
+	r.read(&v)
+	return v
+}
+
+func (r *dbReader) bool() bool {
+	return r.byte() != 0
+}
+
+func (r *dbReader) short() int {
+	var v int16
+	r.read(&v)
+	return int(v)
+}
+
+func (r *dbReader) int() int {
+	var v int32
+	r.read(&v)
+	return int(v)
+}
+
+func (r *dbReader) long() int64 {
+	var v int64
+	r.read(&v)
+	return v
+}
+
+func (r *dbReader) single() float32 {
+	var v float32
+	r.read(&v)
+	return v
+}
+
+func (r *dbReader) double() float64 {
+	var v float64
+	r.read(&v)
+	return v
+}
+
+// dateTime reads .NET DateTime ticks. Zero ticks are returned as zero time.
+func (r *dbReader) dateTime() time.Time {
+	return ticksToTime(r.long())
+}
+
+// string reads optional string: 0x00 if it is absent, or 0x0b followed by
+// ULEB128 length and UTF-8 bytes.
+func (r *dbReader) string() string {
+	switch r.byte() {
+	case 0x00:
+		return ""
+	case 0x0b:
+	default:
+		if r.err == nil {
+			r.err = errors.New("invalid string marker")
+		}
+		return ""
+	}
+
+	var length, shift uint
+	for {
+		b := r.byte()
+		if r.err != nil {
+			return ""
+		}
+		length |= uint(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+		if shift > 28 {
+			r.err = errors.New("invalid string length")
+			return ""
+		}
+	}
+
+	if length > 1<<24 {
+		r.err = errors.New("invalid string length")
+		return ""
+	}
+	buf := make([]byte, length)
+	if r.err == nil {
+		_, r.err = io.ReadFull(r.r, buf)
+	}
+	return string(buf)
+}
+
+// ticksToTime converts .NET DateTime ticks to time in UTC.
+func ticksToTime(ticks int64) time.Time {
+	if ticks == 0 {
+		return time.Time{}
+	}
+	ticks -= unixEpochTicks
+	return time.Unix(ticks/1e7, ticks%1e7*100).UTC()
+}
+
+// timeToTicks converts time to .NET DateTime ticks.
+func timeToTicks(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()*1e7 + int64(t.Nanosecond())/100 + unixEpochTicks
+}
+
+// dbWriter writes values of types used in osu! client databases.
+// The first error stops writing.
+type dbWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// newDBWriter returns dbWriter writing to w. It must be flushed after writing.
+func newDBWriter(w io.Writer) *dbWriter {
+	return &dbWriter{w: bufio.NewWriter(w)}
+}
+
+// write writes little-endian value of fixed size.
+func (w *dbWriter) write(v interface{}) {
+	if w.err == nil {
+		w.err = binary.Write(w.w, binary.LittleEndian, v)
+	}
+}
+
+func (w *dbWriter) byte(v byte) { w.write(v) }
+
+func (w *dbWriter) bool(v bool) {
+	if v {
+		w.byte(1)
+	} else {
+		w.byte(0)
+	}
+}
+
+func (w *dbWriter) short(v int)          { w.write(int16(v)) }
+func (w *dbWriter) int(v int)            { w.write(int32(v)) }
+func (w *dbWriter) long(v int64)         { w.write(v) }
+func (w *dbWriter) single(v float32)     { w.write(v) }
+func (w *dbWriter) double(v float64)     { w.write(v) }
+func (w *dbWriter) dateTime(t time.Time) { w.long(timeToTicks(t)) }
+
+// string writes string in the same format dbReader.string reads it.
+// Empty strings are written as absent.
+func (w *dbWriter) string(v string) {
+	if v == "" {
+		w.byte(0x00)
+		return
+	}
+	w.byte(0x0b)
+	length := uint(len(v))
+	for {
+		b := byte(length & 0x7f)
+		length >>= 7
+		if length != 0 {
+			b |= 0x80
+		}
+		w.byte(b)
+		if length == 0 {
+			break
+		}
+	}
+	if w.err == nil {
+		_, w.err = w.w.WriteString(v)
+	}
+}
+
+// flush writes buffered data and returns the first error.
+func (w *dbWriter) flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// single2double converts float32 to float64 keeping short decimal values like 9.3 exact.
+func single2double(v float32) float64 {
+	d := float64(v)
+	rounded := math.Round(d*1e4) / 1e4
+	if float32(rounded) == v {
+		return rounded
+	}
+	return d
+}
@@ -0,0 +1,568 @@
+package pcircle
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind specifies how an element of a beatmap changed.
+type ChangeKind int
+
+// All possible kinds of changes.
+const (
+	ADDED_CHANGE    ChangeKind = iota // Element exists only in the new beatmap
+	REMOVED_CHANGE                    // Element exists only in the old beatmap
+	MODIFIED_CHANGE                   // Element was changed in place
+	MOVED_CHANGE                      // Element was only moved in time or position
+	HITSOUND_CHANGE                   // Only hit sounds of a hit object were changed
+)
+
+// String returns string of ChangeKind in readable format.
+func (k ChangeKind) String() string {
+	return map[ChangeKind]string{
+		ADDED_CHANGE:    "added",
+		REMOVED_CHANGE:  "removed",
+		MODIFIED_CHANGE: "modified",
+		MOVED_CHANGE:    "moved",
+		HITSOUND_CHANGE: "hitsound",
+	}[k]
+}
+
+// MarshalText encodes ChangeKind as its readable name.
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Sections of beatmap fields compared by Diff.
+var diffSections = []string{"General", "Editor", "Metadata", "Difficulty", "Events", "Colours"}
+
+// Hit object fields, as in JSON encoding, which only affect hit sounds.
+var hitSoundFields = map[string]bool{
+	"hit_sound":       true,
+	"extras":          true,
+	"edge_hit_sounds": true,
+	"edge_additions":  true,
+}
+
+// FieldChange is a change of a single field of a beatmap, e.g. Metadata.Title.
+// Values are formatted as in .osu file, empty if the field is missing.
+type FieldChange struct {
+	Section string `json:"section"`
+	Field   string `json:"field"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+}
+
+// TimingPointChange is an added, removed, modified or moved timing point.
+type TimingPointChange struct {
+	Kind   ChangeKind   `json:"kind"`
+	Old    *TimingPoint `json:"old,omitempty"`
+	New    *TimingPoint `json:"new,omitempty"`
+	Fields []string     `json:"fields,omitempty"` // Names of changed fields, as in JSON encoding
+}
+
+// Time returns offset of the new timing point, or of the old one if it was removed.
+func (c *TimingPointChange) Time() int {
+	if c.New != nil {
+		return c.New.Offset
+	}
+	return c.Old.Offset
+}
+
+// HitObjectChange is an added, removed, modified or moved hit object, or a hit object
+// with changed hit sounds. Hit objects are matched by time and position.
+type HitObjectChange struct {
+	Kind   ChangeKind
+	Old    interface{} // Hit object of the old beatmap, nil if it was added
+	New    interface{} // Hit object of the new beatmap, nil if it was removed
+	Fields []string    // Names of changed fields, as in JSON encoding
+}
+
+// Time returns time of the new hit object, or of the old one if it was removed.
+func (c *HitObjectChange) Time() int {
+	if c.New != nil {
+		return BaseOf(c.New).Time
+	}
+	return BaseOf(c.Old).Time
+}
+
+// MarshalJSON encodes HitObjectChange with hit objects encoded like in Beatmap.ToJSON.
+func (c *HitObjectChange) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Kind   ChangeKind     `json:"kind"`
+		Time   int            `json:"time"`
+		Old    *hitObjectJSON `json:"old,omitempty"`
+		New    *hitObjectJSON `json:"new,omitempty"`
+		Fields []string       `json:"fields,omitempty"`
+	}{Kind: c.Kind, Time: c.Time(), Fields: c.Fields}
+
+	var err error
+	if c.Old != nil {
+		if v.Old, err = newHitObjectJSON(c.Old); err != nil {
+			return nil, err
+		}
+	}
+	if c.New != nil {
+		if v.New, err = newHitObjectJSON(c.New); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(v)
+}
+
+// BreakChange is an added, removed or modified break. Breaks starting at the same time
+// are modified.
+type BreakChange struct {
+	Kind ChangeKind `json:"kind"`
+	Old  *Break     `json:"old,omitempty"`
+	New  *Break     `json:"new,omitempty"`
+}
+
+// BeatmapDiff is a structural difference between two versions of a beatmap.
+// Changes of timing points, hit objects and breaks are sorted by time.
+type BeatmapDiff struct {
+	Fields       []*FieldChange       `json:"fields"`
+	TimingPoints []*TimingPointChange `json:"timing_points"`
+	HitObjects   []*HitObjectChange   `json:"hit_objects"`
+	Breaks       []*BreakChange       `json:"breaks"`
+}
+
+// Diff returns structural difference between old and new versions of a beatmap.
+func Diff(old, new *Beatmap) *BeatmapDiff {
+	return &BeatmapDiff{
+		Fields:       diffFields(old, new),
+		TimingPoints: diffTimingPoints(old.TimingPoints, new.TimingPoints),
+		HitObjects:   diffHitObjects(old.HitObjects, new.HitObjects),
+		Breaks:       diffBreaks(old.Breaks, new.Breaks),
+	}
+}
+
+// Empty reports whether there are no differences.
+func (d *BeatmapDiff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.TimingPoints) == 0 && len(d.HitObjects) == 0 && len(d.Breaks) == 0
+}
+
+// String returns the difference in readable format, a change per line.
+func (d *BeatmapDiff) String() string {
+	var lines []string
+	for _, c := range d.Fields {
+		lines = append(lines, fmt.Sprintf("%s.%s: %q -> %q", c.Section, c.Field, c.Old, c.New))
+	}
+	for _, c := range d.TimingPoints {
+		line := fmt.Sprintf("%-8s timing point %s", c.Kind, EditorTimestamp(c.Time()))
+		switch c.Kind {
+		case ADDED_CHANGE:
+			line += ": " + c.New.String()
+		case REMOVED_CHANGE:
+			line += ": " + c.Old.String()
+		case MOVED_CHANGE:
+			line += " from " + EditorTimestamp(c.Old.Offset)
+		default:
+			line += " " + strings.Join(c.Fields, ", ") + ": " + c.Old.String() + " -> " + c.New.String()
+		}
+		lines = append(lines, line)
+	}
+	for _, c := range d.HitObjects {
+		line := fmt.Sprintf("%-8s %s %s", c.Kind, hitObjectName(c), EditorTimestamp(c.Time()))
+		switch c.Kind {
+		case ADDED_CHANGE:
+			line += ": " + fmt.Sprint(c.New)
+		case REMOVED_CHANGE:
+			line += ": " + fmt.Sprint(c.Old)
+		case MOVED_CHANGE:
+			o, n := BaseOf(c.Old), BaseOf(c.New)
+			if o.Time != n.Time {
+				line += " from " + EditorTimestamp(o.Time)
+			} else {
+				line += fmt.Sprintf(" from %d,%d to %d,%d", o.X, o.Y, n.X, n.Y)
+			}
+		default:
+			line += " " + strings.Join(c.Fields, ", ") + ": " + fmt.Sprint(c.Old) + " -> " + fmt.Sprint(c.New)
+		}
+		lines = append(lines, line)
+	}
+	for _, c := range d.Breaks {
+		switch c.Kind {
+		case ADDED_CHANGE:
+			lines = append(lines, fmt.Sprintf("%-8s break %s-%s", c.Kind, EditorTimestamp(c.New.StartTime), EditorTimestamp(c.New.EndTime)))
+		case REMOVED_CHANGE:
+			lines = append(lines, fmt.Sprintf("%-8s break %s-%s", c.Kind, EditorTimestamp(c.Old.StartTime), EditorTimestamp(c.Old.EndTime)))
+		default:
+			lines = append(lines, fmt.Sprintf("%-8s break %s ends at %s instead of %s", c.Kind,
+				EditorTimestamp(c.New.StartTime), EditorTimestamp(c.New.EndTime), EditorTimestamp(c.Old.EndTime)))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// hitObjectName returns type of the changed hit object, e.g. "slider".
+func hitObjectName(c *HitObjectChange) string {
+	hitObject := c.New
+	if hitObject == nil {
+		hitObject = c.Old
+	}
+	switch hitObject.(type) {
+	case *Slider:
+		return SLIDER_JSON_TYPE
+	case *Spinner:
+		return SPINNER_JSON_TYPE
+	case *ManiaHoldNote:
+		return "hold note"
+	}
+	return CIRCLE_JSON_TYPE
+}
+
+// beatmapFields returns fields of beatmap by sections, formatted as in .osu file.
+// Storyboard is represented by its number of lines.
+func beatmapFields(b *Beatmap) map[string][][2]string {
+	fields := make(map[string][][2]string)
+	for section, lines := range canonicalFields(b.canonicalString(LATEST_FORMAT_VERSION)) {
+		for _, line := range lines {
+			_, data := tokenize(line[1])
+			fields[section] = append(fields[section], [2]string{line[0], data})
+		}
+	}
+
+	if b.Background != nil {
+		fields["Events"] = append(fields["Events"], [2]string{"Background", b.Background.String()})
+	}
+	if b.Video != nil {
+		fields["Events"] = append(fields["Events"], [2]string{"Video", b.Video.String()})
+	}
+	if len(b.Storyboard) > 0 {
+		fields["Events"] = append(fields["Events"], [2]string{"Storyboard", strconv.Itoa(len(b.Storyboard)) + " lines"})
+	}
+	return fields
+}
+
+// diffFields compares fields of beatmaps section by section.
+func diffFields(old, new *Beatmap) []*FieldChange {
+	oldFields, newFields := beatmapFields(old), beatmapFields(new)
+	var changes []*FieldChange
+	for _, section := range diffSections {
+		newValues := make(map[string]string)
+		for _, f := range newFields[section] {
+			newValues[f[0]] = f[1]
+		}
+		seen := make(map[string]bool)
+		for _, f := range oldFields[section] {
+			seen[f[0]] = true
+			if v, ok := newValues[f[0]]; !ok || v != f[1] {
+				changes = append(changes, &FieldChange{section, f[0], f[1], v})
+			}
+		}
+		for _, f := range newFields[section] {
+			if !seen[f[0]] {
+				changes = append(changes, &FieldChange{section, f[0], "", f[1]})
+			}
+		}
+	}
+
+	// storyboards with the same number of lines may still differ
+	if len(old.Storyboard) == len(new.Storyboard) && !reflect.DeepEqual(old.Storyboard, new.Storyboard) {
+		lines := strconv.Itoa(len(old.Storyboard)) + " lines"
+		changes = append(changes, &FieldChange{"Events", "Storyboard", lines, lines + " (changed)"})
+	}
+	return changes
+}
+
+// jsonFields returns fields of value as they are encoded to JSON.
+func jsonFields(v interface{}) map[string]interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+// changedFields returns sorted names of fields which differ.
+func changedFields(a, b map[string]interface{}) []string {
+	var names []string
+	for name, v := range a {
+		if !reflect.DeepEqual(v, b[name]) {
+			names = append(names, name)
+		}
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// onlyFields reports whether all names are in allowed.
+func onlyFields(names []string, allowed ...string) bool {
+	for _, name := range names {
+		found := false
+		for _, a := range allowed {
+			found = found || name == a
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// diffElement is an element of a beatmap prepared for matching.
+type diffElement struct {
+	value   interface{}
+	time    int
+	key     string      // Type of hit objects, kind of timing points
+	line    string      // Canonical line of the element in .osu file
+	encoded interface{} // Value whose JSON encoding gives fields of the element
+	fields  map[string]interface{}
+	match   *diffElement // Matching element of the other beatmap, nil if there is none
+}
+
+// jsonFields returns fields of element as they are encoded to JSON. They are encoded
+// only when first needed, as most elements are matched by their lines.
+func (e *diffElement) jsonFields() map[string]interface{} {
+	if e.fields == nil {
+		e.fields = jsonFields(e.encoded)
+	}
+	return e.fields
+}
+
+// equal reports whether elements have equal fields.
+func (e *diffElement) equal(other *diffElement) bool {
+	return e.line == other.line || reflect.DeepEqual(e.jsonFields(), other.jsonFields())
+}
+
+// exactKey returns key of element which is equal only for equal elements.
+func (e *diffElement) exactKey() string {
+	return e.key + "|" + e.line
+}
+
+// fieldsKey returns key of element which is equal for elements whose fields, except
+// specified ones, are equal.
+func (e *diffElement) fieldsKey(except ...string) string {
+	fields := make(map[string]interface{}, len(e.jsonFields()))
+	for name, v := range e.jsonFields() {
+		fields[name] = v
+	}
+	for _, name := range except {
+		delete(fields, name)
+	}
+	data, _ := json.Marshal(fields)
+	return e.key + "|" + string(data)
+}
+
+// matchElements matches unmatched old and new elements with the same group for which
+// match, if not nil, returns true. Old elements are matched in order with the suitable
+// new element closest in time, searched from the old time outwards among new elements
+// of the group sorted by time.
+func matchElements(old, new []*diffElement, group func(*diffElement) string, match func(o, n *diffElement) bool) {
+	groups := make(map[string][]*diffElement)
+	for _, n := range new {
+		if n.match == nil {
+			g := group(n)
+			groups[g] = append(groups[g], n)
+		}
+	}
+	for _, g := range groups {
+		sort.SliceStable(g, func(i, j int) bool {
+			return g[i].time < g[j].time
+		})
+	}
+
+	for _, o := range old {
+		if o.match != nil {
+			continue
+		}
+		g := groups[group(o)]
+		r := sort.Search(len(g), func(i int) bool {
+			return g[i].time >= o.time
+		})
+		l := r - 1
+		for l >= 0 || r < len(g) {
+			var n *diffElement
+			if r == len(g) || l >= 0 && o.time-g[l].time <= g[r].time-o.time {
+				n, l = g[l], l-1
+			} else {
+				n, r = g[r], r+1
+			}
+			if n.match == nil && (match == nil || match(o, n)) {
+				o.match, n.match = n, o
+				break
+			}
+		}
+	}
+}
+
+// timingPointElements returns timing points prepared for matching.
+func timingPointElements(tps []*TimingPoint) []*diffElement {
+	es := make([]*diffElement, len(tps))
+	for i, tp := range tps {
+		es[i] = &diffElement{value: tp, time: tp.Offset, key: strconv.FormatBool(tp.Inherited), line: tp.String(), encoded: tp}
+	}
+	return es
+}
+
+// matchTimingPoints matches timing points of the same kind (red or green lines): unchanged
+// ones, then ones moved in time and then ones at the same offset.
+func matchTimingPoints(old, new []*diffElement) {
+	// unchanged first, so that duplicates are matched correctly
+	matchElements(old, new, (*diffElement).exactKey, nil)
+	matchElements(old, new, func(e *diffElement) string {
+		return e.fieldsKey("offset")
+	}, nil)
+	matchElements(old, new, func(e *diffElement) string {
+		return e.key + "|" + strconv.Itoa(e.time)
+	}, nil)
+}
+
+// hitObjectElements returns hit objects prepared for matching.
+func hitObjectElements(hitObjects []interface{}) []*diffElement {
+	es := make([]*diffElement, 0, len(hitObjects))
+	for _, hitObject := range hitObjects {
+		o, err := newHitObjectJSON(hitObject)
+		if err != nil {
+			continue
+		}
+		es = append(es, &diffElement{value: hitObject, time: o.Time, key: o.Type, line: fmt.Sprint(hitObject), encoded: o})
+	}
+	return es
+}
+
+// matchHitObjects matches hit objects of the same type: unchanged ones, then ones at the
+// same time and position, then ones moved in position and then ones moved in time.
+func matchHitObjects(old, new []*diffElement) {
+	matchElements(old, new, (*diffElement).exactKey, nil)
+	matchElements(old, new, func(e *diffElement) string {
+		return fmt.Sprintf("%s|%d|%v|%v", e.key, e.time, BaseOf(e.value).X, BaseOf(e.value).Y)
+	}, nil)
+	matchElements(old, new, func(e *diffElement) string {
+		return e.fieldsKey("x", "y")
+	}, nil)
+	matchElements(old, new, func(e *diffElement) string {
+		return e.fieldsKey("time", "end_time")
+	}, nil)
+}
+
+// diffTimingPoints compares timing points matching them by offset and kind.
+func diffTimingPoints(old, new []*TimingPoint) []*TimingPointChange {
+	oldElements, newElements := timingPointElements(old), timingPointElements(new)
+	matchTimingPoints(oldElements, newElements)
+
+	var changes []*TimingPointChange
+	for _, o := range oldElements {
+		if o.match == nil {
+			changes = append(changes, &TimingPointChange{Kind: REMOVED_CHANGE, Old: o.value.(*TimingPoint)})
+			continue
+		}
+		if o.line == o.match.line {
+			continue
+		}
+		fields := changedFields(o.jsonFields(), o.match.jsonFields())
+		if len(fields) == 0 {
+			continue
+		}
+		kind := MODIFIED_CHANGE
+		if onlyFields(fields, "offset") {
+			kind = MOVED_CHANGE
+		}
+		changes = append(changes, &TimingPointChange{kind, o.value.(*TimingPoint), o.match.value.(*TimingPoint), fields})
+	}
+	for _, n := range newElements {
+		if n.match == nil {
+			changes = append(changes, &TimingPointChange{Kind: ADDED_CHANGE, New: n.value.(*TimingPoint)})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Time() < changes[j].Time()
+	})
+	return changes
+}
+
+// diffHitObjects compares hit objects matching them by time and position.
+func diffHitObjects(old, new []interface{}) []*HitObjectChange {
+	oldElements, newElements := hitObjectElements(old), hitObjectElements(new)
+	matchHitObjects(oldElements, newElements)
+
+	var changes []*HitObjectChange
+	for _, o := range oldElements {
+		if o.match == nil {
+			changes = append(changes, &HitObjectChange{Kind: REMOVED_CHANGE, Old: o.value})
+			continue
+		}
+		if o.line == o.match.line {
+			continue
+		}
+		fields := changedFields(o.jsonFields(), o.match.jsonFields())
+		if len(fields) == 0 {
+			continue
+		}
+		kind := MODIFIED_CHANGE
+		switch {
+		case onlyFields(fields, "x", "y"), onlyFields(fields, "time", "end_time") && o.time != o.match.time:
+			kind = MOVED_CHANGE
+		default:
+			hitSoundOnly := true
+			for _, name := range fields {
+				hitSoundOnly = hitSoundOnly && hitSoundFields[name]
+			}
+			if hitSoundOnly {
+				kind = HITSOUND_CHANGE
+			}
+		}
+		changes = append(changes, &HitObjectChange{kind, o.value, o.match.value, fields})
+	}
+	for _, n := range newElements {
+		if n.match == nil {
+			changes = append(changes, &HitObjectChange{Kind: ADDED_CHANGE, New: n.value})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Time() < changes[j].Time()
+	})
+	return changes
+}
+
+// diffBreaks compares breaks matching them by start time.
+func diffBreaks(old, new []*Break) []*BreakChange {
+	newByStart := make(map[int]*Break)
+	for _, br := range new {
+		newByStart[br.StartTime] = br
+	}
+	oldStarts := make(map[int]bool)
+
+	var changes []*BreakChange
+	for _, o := range old {
+		oldStarts[o.StartTime] = true
+		n, ok := newByStart[o.StartTime]
+		switch {
+		case !ok:
+			changes = append(changes, &BreakChange{Kind: REMOVED_CHANGE, Old: o})
+		case n.EndTime != o.EndTime:
+			changes = append(changes, &BreakChange{Kind: MODIFIED_CHANGE, Old: o, New: n})
+		}
+	}
+	for _, n := range new {
+		if !oldStarts[n.StartTime] {
+			changes = append(changes, &BreakChange{Kind: ADDED_CHANGE, New: n})
+		}
+	}
+
+	time := func(c *BreakChange) int {
+		if c.New != nil {
+			return c.New.StartTime
+		}
+		return c.Old.StartTime
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return time(changes[i]) < time(changes[j])
+	})
+	return changes
+}
@@ -0,0 +1,164 @@
+package pcircle
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := newTestBeatmap()
+	old.Title = "Title"
+	b := old.Clone()
+
+	b.Title = "New Title"
+	b.TimingPoints[1].Offset = 3500
+	b.TimingPoints = append(b.TimingPoints, &TimingPoint{Offset: 5000, MillisecondsPerBeat: -100, Meter: 4, Volume: 80})
+	BaseOf(b.HitObjects[0]).X = 120
+	b.HitObjects[1].(*Slider).EdgeHitSounds[0] = CLAP_HITSOUND
+	b.HitObjects[2].(*Spinner).EndTime = 11000
+	b.HitObjects = append(b.HitObjects, &Circle{BaseHitObject{X: 300, Y: 300, Time: 13000, Type: CIRCLE, Extras: &Extras{}}})
+	b.Breaks[0].EndTime = 8000
+	b.ComboColours = []*RGB{{255, 0, 0}}
+
+	d := Diff(old, b)
+
+	fields := map[string]*FieldChange{}
+	for _, c := range d.Fields {
+		fields[c.Section+"."+c.Field] = c
+	}
+	if c := fields["Metadata.Title"]; c == nil || c.Old != "Title" || c.New != "New Title" {
+		t.Errorf("Diff() title change = %+v", c)
+	}
+	if c := fields["Colours.Combo1"]; c == nil || c.Old != "" || c.New != "255,0,0" {
+		t.Errorf("Diff() colour change = %+v", c)
+	}
+	if len(d.Fields) != 2 {
+		t.Errorf("Diff() field changes = %v, want 2", len(d.Fields))
+	}
+
+	tpKinds := []ChangeKind{MOVED_CHANGE, ADDED_CHANGE}
+	if len(d.TimingPoints) != len(tpKinds) {
+		t.Fatalf("Diff() timing point changes = %v, want %v", len(d.TimingPoints), len(tpKinds))
+	}
+	for i, kind := range tpKinds {
+		if got := d.TimingPoints[i].Kind; got != kind {
+			t.Errorf("Diff() timing point change %d = %v, want %v", i, got, kind)
+		}
+	}
+
+	hoKinds := []struct {
+		kind   ChangeKind
+		fields string
+	}{
+		{MOVED_CHANGE, "x"},
+		{HITSOUND_CHANGE, "edge_hit_sounds"},
+		{MODIFIED_CHANGE, "end_time"},
+		{ADDED_CHANGE, ""},
+	}
+	if len(d.HitObjects) != len(hoKinds) {
+		t.Fatalf("Diff() hit object changes = %v, want %v", len(d.HitObjects), len(hoKinds))
+	}
+	for i, want := range hoKinds {
+		c := d.HitObjects[i]
+		if c.Kind != want.kind || strings.Join(c.Fields, ",") != want.fields {
+			t.Errorf("Diff() hit object change %d = %v %v, want %v %v", i, c.Kind, c.Fields, want.kind, want.fields)
+		}
+	}
+
+	if len(d.Breaks) != 1 || d.Breaks[0].Kind != MODIFIED_CHANGE {
+		t.Errorf("Diff() break changes = %v", d.Breaks)
+	}
+
+	str := d.String()
+	for _, want := range []string{`Metadata.Title: "Title" -> "New Title"`, "moved    timing point 00:03:500 from 00:03:000", "hitsound slider 00:03:000", "added    circle 00:13:000"} {
+		if !strings.Contains(str, want) {
+			t.Errorf("BeatmapDiff.String() = %v, want to contain %v", str, want)
+		}
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("json.Marshal(BeatmapDiff) error = %v", err)
+	}
+	if !strings.Contains(string(data), `"kind":"hitsound"`) || !strings.Contains(string(data), `"type":"slider"`) {
+		t.Errorf("json.Marshal(BeatmapDiff) = %s", data)
+	}
+}
+
+func TestDiff_moved(t *testing.T) {
+	old := newTestBeatmap()
+	b := old.Clone()
+	b.Shift(250)
+
+	d := Diff(old, b)
+	if len(d.HitObjects) != len(b.HitObjects) {
+		t.Fatalf("Diff() hit object changes = %v, want %v", len(d.HitObjects), len(b.HitObjects))
+	}
+	for _, c := range d.HitObjects {
+		if c.Kind != MOVED_CHANGE {
+			t.Errorf("Diff() hit object change = %v %v, want %v", c.Kind, c.Fields, MOVED_CHANGE)
+		}
+	}
+
+	if d := Diff(old, old.Clone()); !d.Empty() {
+		t.Errorf("Diff() of equal beatmaps = %v, want empty", d)
+	}
+}
+
+// newLargeTestBeatmap returns test beatmap with n circles and sliders 100ms apart.
+func newLargeTestBeatmap(n int) *Beatmap {
+	b := newTestBeatmap()
+	circle, slider := b.HitObjects[0], b.HitObjects[1]
+	b.HitObjects = nil
+	b.TimingPoints = b.TimingPoints[:1]
+	for i := 0; i < n; i++ {
+		hitObject := copyHitObject(circle)
+		if i%4 == 3 {
+			hitObject = copyHitObject(slider)
+		}
+		base := BaseOf(hitObject)
+		base.Time = 1000 + i*100
+		base.X, base.Y = i*37%512, i*53%384
+		b.HitObjects = append(b.HitObjects, hitObject)
+		if i%16 == 0 {
+			b.TimingPoints = append(b.TimingPoints, &TimingPoint{Offset: base.Time, MillisecondsPerBeat: -100, Meter: 4, Volume: 50 + i%40})
+		}
+	}
+	return b
+}
+
+func TestDiff_large(t *testing.T) {
+	old := newLargeTestBeatmap(4000)
+	b := old.Clone()
+	b.Shift(10)
+
+	d := Diff(old, b)
+	if len(d.HitObjects) != 4000 || len(d.TimingPoints) != len(old.TimingPoints) {
+		t.Fatalf("Diff() changes = %v hit objects, %v timing points", len(d.HitObjects), len(d.TimingPoints))
+	}
+	for _, c := range d.HitObjects {
+		if c.Kind != MOVED_CHANGE || c.Time() != BaseOf(c.Old).Time+10 {
+			t.Fatalf("Diff() hit object change = %v %v at %v", c.Kind, c.Fields, c.Time())
+		}
+	}
+}
+
+func BenchmarkDiff(b *testing.B) {
+	old := newLargeTestBeatmap(4000)
+	for _, bench := range []struct {
+		name  string
+		shift int
+	}{
+		{"equal", 0},
+		{"shifted", 10},
+	} {
+		new := old.Clone()
+		new.Shift(bench.shift)
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Diff(old, new)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package pcircle
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -18,6 +19,12 @@ const (
 	MANIA_HOLD_NOTE
 )
 
+// Size of the osu! playfield in osu!pixels.
+const (
+	PLAYFIELD_WIDTH  = 512
+	PLAYFIELD_HEIGHT = 384
+)
+
 // BaseHitObject provides common information that is used in all hit objects.
 type BaseHitObject struct {
 	X, Y     int
@@ -25,6 +32,9 @@ type BaseHitObject struct {
 	Type     int
 	HitSound HitSound
 	Extras   *Extras
+
+	// Computed by Beatmap.ApplyStacking, it is not stored in .osu file.
+	StackHeight int
 }
 
 // Circle is a single hit in all osu! game modes.
@@ -36,14 +46,17 @@ type Circle struct {
 
 // String returns string of Circle as it would be in .osu file.
 func (c Circle) String() string {
-	return strings.Join([]string{
+	attrs := []string{
 		strconv.Itoa(c.X),
 		strconv.Itoa(c.Y),
 		strconv.Itoa(c.Time),
 		strconv.Itoa(c.Type),
 		strconv.Itoa(int(c.HitSound)),
-		c.Extras.String(),
-	}, ",")
+	}
+	if c.Extras != nil {
+		attrs = append(attrs, c.Extras.String())
+	}
+	return strings.Join(attrs, ",")
 }
 
 // FromString fills Circle fields with data parsed from string.
@@ -76,6 +89,11 @@ func (c *Circle) FromString(str string) (err error) {
 	}
 	c.HitSound = HitSound(hs)
 
+	// old versions have no extras
+	if len(attrs) <= 5 {
+		return nil
+	}
+
 	c.Extras = new(Extras)
 	return c.Extras.FromString(attrs[5])
 }
@@ -129,7 +147,7 @@ func (s Slider) String() string {
 	for i := range additions {
 		additions[i] = strconv.Itoa(int(s.EdgeAdditions[i].SampleSet)) + ":" + strconv.Itoa(int(s.EdgeAdditions[i].AdditionSet))
 	}
-	return strings.Join([]string{
+	attrs := []string{
 		strconv.Itoa(s.X),
 		strconv.Itoa(s.Y),
 		strconv.Itoa(s.Time),
@@ -138,10 +156,18 @@ func (s Slider) String() string {
 		s.SliderPath.String(),
 		strconv.Itoa(s.Repeat),
 		fmt.Sprintf("%g", s.PixelLength),
+	}
+	if len(s.EdgeHitSounds) == 0 && s.Extras == nil {
+		return strings.Join(attrs, ",")
+	}
+	attrs = append(attrs,
 		strings.Join(hitSounds, "|"),
 		strings.Join(additions, "|"),
-		s.Extras.String(),
-	}, ",")
+	)
+	if s.Extras != nil {
+		attrs = append(attrs, s.Extras.String())
+	}
+	return strings.Join(attrs, ",")
 }
 
 // FromString fills Slider fields with data parsed from string.
@@ -204,6 +230,10 @@ func (s *Slider) FromString(str string) (err error) {
 		s.EdgeHitSounds[i] = HitSound(hs)
 	}
 
+	if len(attrs) <= 9 {
+		return nil
+	}
+
 	edgeAdditions := strings.Split(attrs[9], "|")
 	s.EdgeAdditions = make([]*SliderEdgeAddition, len(edgeAdditions))
 	for i := 0; i < len(edgeAdditions); i++ {
@@ -214,6 +244,10 @@ func (s *Slider) FromString(str string) (err error) {
 		}
 	}
 
+	if len(attrs) <= 10 {
+		return nil
+	}
+
 	s.Extras = new(Extras)
 	return s.Extras.FromString(attrs[10])
 }
@@ -221,8 +255,8 @@ func (s *Slider) FromString(str string) (err error) {
 // SliderEdgeAddition is a sample sets to apply to the circles of the slider.
 // SampleSet and AdditionSet are the same as for hit circles' extras fields.
 type SliderEdgeAddition struct {
-	SampleSet   SampleSet
-	AdditionSet SampleSet
+	SampleSet   SampleSet `json:"sample_set"`
+	AdditionSet SampleSet `json:"addition_set"`
 }
 
 // String returns string of SliderEdgeAddition as it would be in .osu file.
@@ -240,7 +274,7 @@ func (sea *SliderEdgeAddition) FromString(str string) (err error) {
 	}
 	sea.SampleSet = SampleSet(ss)
 
-	ss, err = strconv.Atoi(str[:sep])
+	ss, err = strconv.Atoi(str[sep+1:])
 	if err != nil {
 		return err
 	}
@@ -282,7 +316,8 @@ func (sp *SliderPath) FromString(str string) (err error) {
 
 // SliderCurvePoint describe a single point of the slider.
 type SliderCurvePoint struct {
-	X, Y int
+	X int `json:"x"`
+	Y int `json:"y"`
 }
 
 // String returns string of SliderCurvePoint as it would be in .osu file.
@@ -437,3 +472,132 @@ func (hn *ManiaHoldNote) FromString(str string) (err error) {
 	hn.Extras = new(Extras)
 	return hn.Extras.FromString(attrs[5][sep+1:])
 }
+
+// BaseOf returns BaseHitObject of any hit object stored in Beatmap.HitObjects
+// (*Circle, *Slider, *Spinner or *ManiaHoldNote). Returns nil for unknown types.
+func BaseOf(hitObject interface{}) *BaseHitObject {
+	switch o := hitObject.(type) {
+	case *Circle:
+		return &o.BaseHitObject
+	case *Slider:
+		return &o.BaseHitObject
+	case *Spinner:
+		return &o.BaseHitObject
+	case *ManiaHoldNote:
+		return &o.BaseHitObject
+	}
+	return nil
+}
+
+// copyHitObject returns deep copy of hit object.
+func copyHitObject(hitObject interface{}) interface{} {
+	switch o := hitObject.(type) {
+	case *Circle:
+		c := *o
+		c.Extras = o.Extras.copy()
+		return &c
+	case *Slider:
+		s := *o
+		s.Extras = o.Extras.copy()
+		if o.SliderPath != nil {
+			s.SliderPath = &SliderPath{SliderType: o.SliderPath.SliderType}
+			s.SliderPath.CurvePoints = make([]*SliderCurvePoint, len(o.SliderPath.CurvePoints))
+			for i, p := range o.SliderPath.CurvePoints {
+				point := *p
+				s.SliderPath.CurvePoints[i] = &point
+			}
+		}
+		s.EdgeHitSounds = append([]HitSound(nil), o.EdgeHitSounds...)
+		s.EdgeAdditions = make([]*SliderEdgeAddition, len(o.EdgeAdditions))
+		for i, a := range o.EdgeAdditions {
+			addition := *a
+			s.EdgeAdditions[i] = &addition
+		}
+		return &s
+	case *Spinner:
+		s := *o
+		s.Extras = o.Extras.copy()
+		return &s
+	case *ManiaHoldNote:
+		hn := *o
+		hn.Extras = o.Extras.copy()
+		return &hn
+	}
+	return hitObject
+}
+
+// SliderDuration returns duration of the whole slider (including all repeats) in milliseconds.
+func (b *Beatmap) SliderDuration(s *Slider) float64 {
+	velocity := b.SliderMultiplier * 100 * b.SliderVelocityAt(s.Time)
+	if velocity == 0 {
+		return 0
+	}
+	return s.PixelLength / velocity * b.BeatLengthAt(s.Time) * float64(s.Repeat)
+}
+
+// SliderTickTimes returns times of slider ticks of every span of the slider.
+// Ticks closer than SLIDER_TICK_END_LENIENCY to the end of a span are skipped.
+func (b *Beatmap) SliderTickTimes(s *Slider) []int {
+	if b.SliderTickRate <= 0 {
+		return nil
+	}
+	tickInterval := b.BeatLengthAt(s.Time) / b.SliderTickRate
+	if tickInterval <= 0 {
+		return nil
+	}
+
+	repeats := s.Repeat
+	if repeats < 1 {
+		repeats = 1
+	}
+	spanDuration := b.SliderDuration(s) / float64(repeats)
+
+	var times []int
+	for span := 0; span < repeats; span++ {
+		spanStart := float64(s.Time) + spanDuration*float64(span)
+		for t := tickInterval; t < spanDuration-SLIDER_TICK_END_LENIENCY; t += tickInterval {
+			times = append(times, int(math.Round(spanStart+t)))
+		}
+	}
+	return times
+}
+
+// EndTime returns time when specified hit object ends.
+// For circles it is the same as the hit time.
+func (b *Beatmap) EndTime(hitObject interface{}) int {
+	switch o := hitObject.(type) {
+	case *Slider:
+		return o.Time + int(b.SliderDuration(o))
+	case *Spinner:
+		return o.EndTime
+	case *ManiaHoldNote:
+		return o.EndTime
+	}
+	if base := BaseOf(hitObject); base != nil {
+		return base.Time
+	}
+	return 0
+}
+
+// ManiaColumn returns zero-based osu!mania column of hit object with specified X position.
+func ManiaColumn(x, keys int) int {
+	if keys <= 0 {
+		return 0
+	}
+	column := x * keys / PLAYFIELD_WIDTH
+	if column < 0 {
+		return 0
+	}
+	if column >= keys {
+		return keys - 1
+	}
+	return column
+}
+
+// ManiaColumnX returns X position of hit objects placed in specified osu!mania column.
+func ManiaColumnX(column, keys int) int {
+	if keys <= 0 {
+		return 0
+	}
+	return (column*PLAYFIELD_WIDTH + PLAYFIELD_WIDTH/2) / keys
+}
@@ -0,0 +1,314 @@
+package pcircle
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// HitSoundCopyOptions specifies how hit sounds are copied between beatmaps.
+type HitSoundCopyOptions struct {
+	// Maximal difference in milliseconds between source and target hit times
+	// for hit sounds to be copied.
+	Tolerance int
+
+	// Whether to copy sample set, index and volume of timing points.
+	// Green lines are added to targets where needed.
+	CopyTimingPoints bool
+
+	// Whether to remove hit sounds from target hit objects which have no
+	// matching source hit sound.
+	ResetUnmatched bool
+}
+
+// HitSoundCopyReport describes result of copying hit sounds to a single beatmap.
+type HitSoundCopyReport struct {
+	Target            *Beatmap
+	Copied            int                  // Number of target hit object nodes which received hit sounds
+	AddedTimingPoints int                  // Number of green lines added to the target
+	Unmatched         []*UnmatchedHitSound // Source hit sounds which no target node received
+}
+
+// UnmatchedHitSound is a source hit sound which was not copied to the target.
+type UnmatchedHitSound struct {
+	Time      int
+	HitSound  HitSound
+	HitObject interface{} // Source hit object
+}
+
+// hitSound holds all hit sound parameters of a single hit.
+type hitSound struct {
+	hitSound    HitSound
+	sampleSet   SampleSet
+	additionSet SampleSet
+	customIndex int
+	volume      int
+	filename    string
+	body        HitSound // Slider body hit sound, only for slider heads
+}
+
+// isDefault reports whether hit sound plays nothing but the default hit normal.
+func (hs hitSound) isDefault() bool {
+	return hs == hitSound{}
+}
+
+// hitSoundNode is a single moment when hit object plays hit sounds:
+// a circle, slider edge, spinner end or hold note head.
+type hitSoundNode struct {
+	time      int
+	hitObject interface{}
+	edge      int // Index of slider edge, -1 for other hit objects
+}
+
+// hitSoundNodes returns all hit sound nodes of the beatmap.
+func (b *Beatmap) hitSoundNodes() []*hitSoundNode {
+	var nodes []*hitSoundNode
+	for _, hitObject := range b.HitObjects {
+		switch o := hitObject.(type) {
+		case *Slider:
+			repeats := o.Repeat
+			if repeats < 1 {
+				repeats = 1
+			}
+			spanDuration := b.SliderDuration(o) / float64(repeats)
+			for i := 0; i <= repeats; i++ {
+				nodes = append(nodes, &hitSoundNode{time: o.Time + int(math.Round(spanDuration*float64(i))), hitObject: o, edge: i})
+			}
+		case *Spinner:
+			nodes = append(nodes, &hitSoundNode{time: o.EndTime, hitObject: o, edge: -1})
+		default:
+			nodes = append(nodes, &hitSoundNode{time: BaseOf(o).Time, hitObject: o, edge: -1})
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].time < nodes[j].time
+	})
+	return nodes
+}
+
+// get returns hit sound of the node.
+func (n *hitSoundNode) get() hitSound {
+	var hs hitSound
+	var extras *Extras
+
+	if s, ok := n.hitObject.(*Slider); ok {
+		hs.hitSound = s.HitSound
+		if n.edge < len(s.EdgeHitSounds) {
+			hs.hitSound = s.EdgeHitSounds[n.edge]
+		}
+		if n.edge == 0 {
+			hs.body = s.HitSound
+		}
+		extras = s.Extras
+	} else {
+		base := BaseOf(n.hitObject)
+		hs.hitSound = base.HitSound
+		extras = base.Extras
+	}
+
+	if extras != nil {
+		hs.sampleSet = extras.SampleSet
+		hs.additionSet = extras.AdditionalSet
+		hs.customIndex = extras.CustomIndex
+		hs.volume = extras.SampleVolume
+		hs.filename = extras.Filename
+	}
+
+	if s, ok := n.hitObject.(*Slider); ok && n.edge < len(s.EdgeAdditions) {
+		if a := s.EdgeAdditions[n.edge]; a.SampleSet != AUTO_SAMPLESET || a.AdditionSet != AUTO_SAMPLESET {
+			hs.sampleSet = a.SampleSet
+			hs.additionSet = a.AdditionSet
+		}
+	}
+	return hs
+}
+
+// set applies hit sound to the node. Slider edges share custom index, volume and
+// filename, so these are taken from the slider head.
+func (n *hitSoundNode) set(hs hitSound) {
+	s, ok := n.hitObject.(*Slider)
+	if !ok {
+		base := BaseOf(n.hitObject)
+		base.HitSound = hs.hitSound
+		extras := Extras{
+			SampleSet:     hs.sampleSet,
+			AdditionalSet: hs.additionSet,
+			CustomIndex:   hs.customIndex,
+			SampleVolume:  hs.volume,
+			Filename:      hs.filename,
+		}
+		// keep short syntax of hit objects without extras
+		if base.Extras != nil || extras != (Extras{}) {
+			base.Extras = &extras
+		}
+		return
+	}
+
+	edges := s.Repeat + 1
+	if s.Repeat < 1 {
+		edges = 2
+	}
+	for len(s.EdgeHitSounds) < edges {
+		s.EdgeHitSounds = append(s.EdgeHitSounds, NO_HITSOUND)
+	}
+	for len(s.EdgeAdditions) < edges {
+		s.EdgeAdditions = append(s.EdgeAdditions, new(SliderEdgeAddition))
+	}
+	if s.Extras == nil {
+		s.Extras = new(Extras)
+	}
+
+	s.EdgeHitSounds[n.edge] = hs.hitSound
+	s.EdgeAdditions[n.edge].SampleSet = hs.sampleSet
+	s.EdgeAdditions[n.edge].AdditionSet = hs.additionSet
+	if n.edge == 0 {
+		s.HitSound = hs.body
+		s.Extras.CustomIndex = hs.customIndex
+		s.Extras.SampleVolume = hs.volume
+		s.Extras.Filename = hs.filename
+	}
+}
+
+// CopyHitSounds copies hit sounds from source beatmap to targets, matching
+// hit object nodes (circles, slider edges, spinner ends and hold note heads)
+// by time. If no targets are specified, all other beatmaps of the mapset are used.
+func (m *Mapset) CopyHitSounds(source *Beatmap, targets []*Beatmap, opts HitSoundCopyOptions) ([]*HitSoundCopyReport, error) {
+	if source == nil {
+		return nil, errors.New("no source beatmap")
+	}
+	if opts.Tolerance < 0 {
+		return nil, errors.New("negative tolerance")
+	}
+
+	if len(targets) == 0 {
+		for _, b := range m.Beatmaps {
+			if b != source {
+				targets = append(targets, b)
+			}
+		}
+	}
+
+	reports := make([]*HitSoundCopyReport, 0, len(targets))
+	for _, target := range targets {
+		if target == source {
+			continue
+		}
+		reports = append(reports, source.CopyHitSoundsTo(target, opts))
+	}
+	return reports, nil
+}
+
+// CopyHitSoundsTo copies hit sounds of the beatmap to target beatmap.
+// See Mapset.CopyHitSounds.
+func (b *Beatmap) CopyHitSoundsTo(target *Beatmap, opts HitSoundCopyOptions) *HitSoundCopyReport {
+	report := &HitSoundCopyReport{Target: target}
+
+	sourceNodes := b.hitSoundNodes()
+	targetNodes := target.hitSoundNodes()
+	matched := make([]bool, len(sourceNodes))
+
+	// both node lists are sorted by time, so we walk them together
+	// looking for the closest source node within tolerance
+	start := 0
+	for _, tn := range targetNodes {
+		for start < len(sourceNodes) && sourceNodes[start].time < tn.time-opts.Tolerance {
+			start++
+		}
+
+		best := -1
+		for i := start; i < len(sourceNodes) && sourceNodes[i].time <= tn.time+opts.Tolerance; i++ {
+			if best == -1 || abs(sourceNodes[i].time-tn.time) < abs(sourceNodes[best].time-tn.time) {
+				best = i
+			}
+		}
+
+		if best == -1 {
+			if opts.ResetUnmatched {
+				tn.set(hitSound{})
+			}
+			continue
+		}
+
+		hs := sourceNodes[best].get()
+		if s, ok := tn.hitObject.(*Slider); ok && tn.edge == 0 {
+			// slider body sound is copied only from another slider head
+			if _, sourceSlider := sourceNodes[best].hitObject.(*Slider); !sourceSlider || sourceNodes[best].edge != 0 {
+				hs.body = s.HitSound
+			}
+		}
+		tn.set(hs)
+		matched[best] = true
+		report.Copied++
+	}
+
+	for i, sn := range sourceNodes {
+		if matched[i] {
+			continue
+		}
+		if hs := sn.get(); !hs.isDefault() {
+			report.Unmatched = append(report.Unmatched, &UnmatchedHitSound{
+				Time:      sn.time,
+				HitSound:  hs.hitSound,
+				HitObject: sn.hitObject,
+			})
+		}
+	}
+
+	if opts.CopyTimingPoints {
+		report.AddedTimingPoints = b.copySampleTimingPoints(target)
+	}
+
+	return report
+}
+
+// copySampleTimingPoints makes sample sets, indexes and volumes of target timing
+// points match the source ones. Source timing points before the first red line of
+// target are skipped. Returns number of added green lines.
+func (b *Beatmap) copySampleTimingPoints(target *Beatmap) int {
+	target.SortTimingPoints()
+
+	first := target.RedLineAt(MIN_TIME)
+	if first == nil {
+		return 0
+	}
+	offsets := make(map[int]bool, len(target.TimingPoints))
+	for _, ttp := range target.TimingPoints {
+		offsets[ttp.Offset] = true
+	}
+
+	// added green lines copy the active timing point, so it is looked up among the original ones
+	original := &Beatmap{TimingPoints: target.TimingPoints}
+	added := 0
+	for _, tp := range b.TimingPoints {
+		if offsets[tp.Offset] || tp.Offset < first.Offset {
+			continue
+		}
+		offsets[tp.Offset] = true
+
+		active := original.TimingPointAt(tp.Offset)
+		green := &TimingPoint{
+			Offset:              tp.Offset,
+			MillisecondsPerBeat: -100,
+			Meter:               active.Meter,
+			Kiai:                active.Kiai,
+		}
+		if !active.Inherited {
+			green.MillisecondsPerBeat = active.MillisecondsPerBeat
+		}
+		target.TimingPoints = append(target.TimingPoints, green)
+		added++
+	}
+	if added > 0 {
+		target.SortTimingPoints()
+	}
+
+	for _, ttp := range target.TimingPoints {
+		if src := b.TimingPointAt(ttp.Offset); src != nil {
+			ttp.SampleSet = src.SampleSet
+			ttp.SampleIndex = src.SampleIndex
+			ttp.Volume = src.Volume
+		}
+	}
+
+	return added
+}
@@ -0,0 +1,90 @@
+package pcircle
+
+import (
+	"testing"
+)
+
+func TestMapset_CopyHitSounds(t *testing.T) {
+	source := newTestBeatmap()
+	source.HitObjects[0].(*Circle).HitSound = CLAP_HITSOUND
+	source.HitObjects[0].(*Circle).Extras = &Extras{SampleSet: DRUM_SAMPLESET, CustomIndex: 2}
+	slider := source.HitObjects[1].(*Slider)
+	slider.HitSound = WHISTLE_HITSOUND
+	slider.EdgeHitSounds = []HitSound{FINISH_HITSOUND, CLAP_HITSOUND}
+	source.HitObjects = append(source.HitObjects, &Circle{BaseHitObject{X: 50, Y: 50, Time: 14000, HitSound: WHISTLE_HITSOUND, Extras: &Extras{}}})
+	source.TimingPoints = append(source.TimingPoints, &TimingPoint{Offset: 9000, MillisecondsPerBeat: -100, Meter: 4, SampleSet: DRUM_SAMPLESET, Volume: 80})
+
+	target := newTestBeatmap()
+	target.HitObjects = []interface{}{
+		&Circle{BaseHitObject{X: 10, Y: 10, Time: 1502, Extras: &Extras{}}},
+		&Circle{BaseHitObject{X: 10, Y: 10, Time: 3000, Extras: &Extras{}}},
+		&Circle{BaseHitObject{X: 10, Y: 10, Time: 4000, HitSound: FINISH_HITSOUND, Extras: &Extras{}}},
+	}
+	other := target.Clone()
+
+	m := &Mapset{Beatmaps: []*Beatmap{source, target, other}}
+	reports, err := m.CopyHitSounds(source, nil, HitSoundCopyOptions{Tolerance: 5, CopyTimingPoints: true, ResetUnmatched: true})
+	if err != nil {
+		t.Fatalf("Mapset.CopyHitSounds() error = %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Mapset.CopyHitSounds() returned %v reports, want 2", len(reports))
+	}
+
+	report := reports[0]
+	if report.Copied != 2 {
+		t.Errorf("Mapset.CopyHitSounds() copied = %v, want 2", report.Copied)
+	}
+	if report.AddedTimingPoints != 1 {
+		t.Errorf("Mapset.CopyHitSounds() added timing points = %v, want 1", report.AddedTimingPoints)
+	}
+	if len(report.Unmatched) != 2 || report.Unmatched[0].Time != 3250 || report.Unmatched[1].Time != 14000 {
+		t.Errorf("Mapset.CopyHitSounds() unmatched = %v, want slider tail and last circle", report.Unmatched)
+	}
+
+	first := target.HitObjects[0].(*Circle)
+	if first.HitSound != CLAP_HITSOUND || first.Extras.SampleSet != DRUM_SAMPLESET || first.Extras.CustomIndex != 2 {
+		t.Errorf("Mapset.CopyHitSounds() first circle = %v", first)
+	}
+	if got := target.HitObjects[1].(*Circle).HitSound; got != FINISH_HITSOUND {
+		t.Errorf("Mapset.CopyHitSounds() slider head sound = %v, want %v", got, FINISH_HITSOUND)
+	}
+	if got := target.HitObjects[2].(*Circle).HitSound; got != NO_HITSOUND {
+		t.Errorf("Mapset.CopyHitSounds() unmatched circle sound = %v, want reset", got)
+	}
+
+	if len(target.TimingPoints) != 3 {
+		t.Fatalf("Mapset.CopyHitSounds() target has %v timing points, want 3", len(target.TimingPoints))
+	}
+	added := target.TimingPoints[2]
+	if added.Offset != 9000 || added.MillisecondsPerBeat != -50 || added.SampleSet != DRUM_SAMPLESET || added.Volume != 80 {
+		t.Errorf("Mapset.CopyHitSounds() added timing point = %v", added)
+	}
+}
+
+func TestBeatmap_copySampleTimingPoints(t *testing.T) {
+	source := &Beatmap{TimingPoints: []*TimingPoint{
+		{Offset: 0, MillisecondsPerBeat: 500, Meter: 4, SampleSet: SOFT_SAMPLESET, Volume: 50, Inherited: true},
+		{Offset: 500, MillisecondsPerBeat: -100, Meter: 4, SampleSet: DRUM_SAMPLESET, Volume: 60},
+		{Offset: 2000, MillisecondsPerBeat: -100, Meter: 4, SampleSet: NORMAL_SAMPLESET, Volume: 80},
+		{Offset: 3000, MillisecondsPerBeat: -100, Meter: 4, SampleSet: DRUM_SAMPLESET, Volume: 70},
+	}}
+	target := &Beatmap{TimingPoints: []*TimingPoint{
+		{Offset: 1000, MillisecondsPerBeat: 400, Meter: 3, SampleSet: NORMAL_SAMPLESET, Volume: 100, Inherited: true},
+	}}
+
+	// timing points before the first red line of target are skipped
+	if added := source.copySampleTimingPoints(target); added != 2 {
+		t.Errorf("Beatmap.copySampleTimingPoints() = %v, want 2", added)
+	}
+	var offsets []int
+	for _, tp := range target.TimingPoints {
+		offsets = append(offsets, tp.Offset)
+	}
+	if len(offsets) != 3 || offsets[0] != 1000 || offsets[1] != 2000 || offsets[2] != 3000 {
+		t.Fatalf("Beatmap.copySampleTimingPoints() offsets = %v, want [1000 2000 3000]", offsets)
+	}
+	if tp := target.TimingPoints[2]; tp.MillisecondsPerBeat != -100 || tp.Meter != 3 || tp.SampleSet != DRUM_SAMPLESET || tp.Volume != 70 {
+		t.Errorf("Beatmap.copySampleTimingPoints() added timing point = %+v", tp)
+	}
+}
@@ -0,0 +1,253 @@
+package pcircle
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Timing points are looked up slightly after the hit time, so green lines
+// placed a few milliseconds late still affect the hit object.
+const SAMPLE_CONTROL_POINT_LENIENCY = 5
+
+// Ticks closer than this to the end of a slider span are not played.
+const SLIDER_TICK_END_LENIENCY = 10
+
+// Names of sounds played by hit objects.
+const (
+	HIT_NORMAL_SOUND     = "hitnormal"
+	HIT_WHISTLE_SOUND    = "hitwhistle"
+	HIT_FINISH_SOUND     = "hitfinish"
+	HIT_CLAP_SOUND       = "hitclap"
+	SLIDER_SLIDE_SOUND   = "sliderslide"
+	SLIDER_WHISTLE_SOUND = "sliderwhistle"
+	SLIDER_TICK_SOUND    = "slidertick"
+)
+
+// Audio file extensions of samples, in order of lookup.
+var sampleExtensions = []string{".wav", ".ogg", ".mp3"}
+
+// SampleLookup finds custom sample by its name without extension (like "soft-hitclap2")
+// and returns its actual filename. It reports false if there is no such file.
+type SampleLookup func(name string) (filename string, ok bool)
+
+// HitSample is a single sound which plays during the beatmap.
+type HitSample struct {
+	Time      int       // When the sample starts to play
+	Duration  int       // How long looping samples (slider slides) play, zero otherwise
+	Sound     string    // Name of the sound, like "hitclap"
+	SampleSet SampleSet // Resolved sample set, never AUTO_SAMPLESET. Zero for custom files
+	Index     int       // Custom sample index, zero means default skin sample
+	Volume    int       // Volume of the sample, ranges from 0 to 100 (percent)
+
+	// Filename of the sample, like "soft-hitclap2.wav". If Skin is true,
+	// the file comes from the skin, otherwise from the beatmap directory.
+	Filename string
+	Skin     bool
+
+	HitObject interface{} // Hit object which plays the sample
+}
+
+// sampleBank is the resolved set of hit sound parameters for one hit.
+type sampleBank struct {
+	normalSet, additionSet SampleSet
+	index, volume          int
+	filename               string
+}
+
+// HitSamples resolves all sounds which play during the beatmap: hit sounds
+// of circles, slider edges, spinners and hold notes, slider slides, slider
+// whistles and slider ticks. Samples are sorted by hit objects.
+//
+// Custom index samples are looked up with specified lookup; if it reports that
+// the file does not exist, the skin sample is used instead. Nil lookup
+// assumes that every custom sample exists as .wav file.
+func (b *Beatmap) HitSamples(lookup SampleLookup) []*HitSample {
+	if lookup == nil {
+		lookup = func(name string) (string, bool) {
+			return name + ".wav", true
+		}
+	}
+
+	var samples []*HitSample
+	for _, hitObject := range b.HitObjects {
+		base := BaseOf(hitObject)
+
+		switch o := hitObject.(type) {
+		case *Slider:
+			samples = append(samples, b.sliderSamples(o, lookup)...)
+		case *Spinner:
+			bank := b.resolveBank(o.EndTime, o.Extras, nil)
+			samples = append(samples, b.hitSamples(hitObject, o.EndTime, o.HitSound, bank, lookup)...)
+		default:
+			bank := b.resolveBank(base.Time, base.Extras, nil)
+			samples = append(samples, b.hitSamples(hitObject, base.Time, base.HitSound, bank, lookup)...)
+		}
+	}
+	return samples
+}
+
+// HitSamples resolves all sounds of specified beatmap, looking custom samples up
+// in the mapset directory.
+func (m *Mapset) HitSamples(b *Beatmap) []*HitSample {
+	return b.HitSamples(m.LookupSample)
+}
+
+// LookupSample finds custom sample in the mapset directory by its name without extension.
+func (m *Mapset) LookupSample(name string) (string, bool) {
+	return lookupSampleIn(m.DirectoryPath, name)
+}
+
+// lookupSampleIn finds sample in specified directory trying all sample extensions.
+func lookupSampleIn(dir, name string) (string, bool) {
+	for _, ext := range sampleExtensions {
+		fi, err := os.Stat(filepath.Join(dir, name+ext))
+		if err == nil && !fi.IsDir() {
+			return name + ext, true
+		}
+	}
+	return "", false
+}
+
+// resolveBank resolves sample sets, index and volume of the hit at specified time.
+// Non-zero values of extras and slider edge additions override the timing point ones.
+func (b *Beatmap) resolveBank(time int, extras *Extras, edge *SliderEdgeAddition) sampleBank {
+	bank := sampleBank{normalSet: b.SampleSet, volume: 100}
+	if tp := b.TimingPointAt(time + SAMPLE_CONTROL_POINT_LENIENCY); tp != nil {
+		if tp.SampleSet != AUTO_SAMPLESET {
+			bank.normalSet = tp.SampleSet
+		}
+		bank.index = tp.SampleIndex
+		bank.volume = tp.Volume
+	}
+	if bank.normalSet == AUTO_SAMPLESET {
+		bank.normalSet = NORMAL_SAMPLESET
+	}
+
+	if extras != nil {
+		if extras.SampleSet != AUTO_SAMPLESET {
+			bank.normalSet = extras.SampleSet
+		}
+		bank.additionSet = extras.AdditionalSet
+		if extras.CustomIndex != 0 {
+			bank.index = extras.CustomIndex
+		}
+		if extras.SampleVolume != 0 {
+			bank.volume = extras.SampleVolume
+		}
+		bank.filename = extras.Filename
+	}
+
+	if edge != nil {
+		if edge.SampleSet != AUTO_SAMPLESET {
+			bank.normalSet = edge.SampleSet
+		}
+		if edge.AdditionSet != AUTO_SAMPLESET {
+			bank.additionSet = edge.AdditionSet
+		}
+	}
+
+	if bank.additionSet == AUTO_SAMPLESET {
+		bank.additionSet = bank.normalSet
+	}
+	return bank
+}
+
+// hitSamples returns samples played by a single hit: hit normal and additions.
+func (b *Beatmap) hitSamples(hitObject interface{}, time int, hs HitSound, bank sampleBank, lookup SampleLookup) []*HitSample {
+	if bank.filename != "" {
+		return []*HitSample{{
+			Time:      time,
+			Volume:    bank.volume,
+			Filename:  bank.filename,
+			HitObject: hitObject,
+		}}
+	}
+
+	samples := []*HitSample{newHitSample(hitObject, time, HIT_NORMAL_SOUND, bank.normalSet, bank, lookup)}
+	for _, addition := range []struct {
+		hitSound HitSound
+		sound    string
+	}{
+		{WHISTLE_HITSOUND, HIT_WHISTLE_SOUND},
+		{FINISH_HITSOUND, HIT_FINISH_SOUND},
+		{CLAP_HITSOUND, HIT_CLAP_SOUND},
+	} {
+		if hs&addition.hitSound > 0 {
+			samples = append(samples, newHitSample(hitObject, time, addition.sound, bank.additionSet, bank, lookup))
+		}
+	}
+	return samples
+}
+
+// sliderSamples returns samples of slider edges, body and ticks.
+func (b *Beatmap) sliderSamples(s *Slider, lookup SampleLookup) []*HitSample {
+	var samples []*HitSample
+
+	repeats := s.Repeat
+	if repeats < 1 {
+		repeats = 1
+	}
+	duration := b.SliderDuration(s)
+	spanDuration := duration / float64(repeats)
+
+	for i := 0; i <= repeats; i++ {
+		time := s.Time + int(math.Round(spanDuration*float64(i)))
+
+		hs := s.HitSound
+		if i < len(s.EdgeHitSounds) {
+			hs = s.EdgeHitSounds[i]
+		}
+		var edge *SliderEdgeAddition
+		if i < len(s.EdgeAdditions) {
+			edge = s.EdgeAdditions[i]
+		}
+
+		bank := b.resolveBank(time, s.Extras, edge)
+		samples = append(samples, b.hitSamples(s, time, hs, bank, lookup)...)
+	}
+
+	bank := b.resolveBank(s.Time, s.Extras, nil)
+	bank.filename = ""
+	slide := newHitSample(s, s.Time, SLIDER_SLIDE_SOUND, bank.normalSet, bank, lookup)
+	slide.Duration = int(math.Round(duration))
+	samples = append(samples, slide)
+	if s.HitSound&WHISTLE_HITSOUND > 0 {
+		whistle := newHitSample(s, s.Time, SLIDER_WHISTLE_SOUND, bank.additionSet, bank, lookup)
+		whistle.Duration = slide.Duration
+		samples = append(samples, whistle)
+	}
+
+	for _, time := range b.SliderTickTimes(s) {
+		tickBank := b.resolveBank(time, s.Extras, nil)
+		samples = append(samples, newHitSample(s, time, SLIDER_TICK_SOUND, tickBank.normalSet, tickBank, lookup))
+	}
+
+	return samples
+}
+
+// newHitSample resolves filename of the sound and creates sample.
+func newHitSample(hitObject interface{}, time int, sound string, set SampleSet, bank sampleBank, lookup SampleLookup) *HitSample {
+	sample := &HitSample{
+		Time:      time,
+		Sound:     sound,
+		SampleSet: set,
+		Index:     bank.index,
+		Volume:    bank.volume,
+		HitObject: hitObject,
+	}
+
+	name := strings.ToLower(set.String()) + "-" + sound
+	sample.Filename, sample.Skin = name+".wav", true
+	if bank.index > 0 {
+		if bank.index > 1 {
+			name += strconv.Itoa(bank.index)
+		}
+		if filename, ok := lookup(name); ok {
+			sample.Filename, sample.Skin = filename, false
+		}
+	}
+	return sample
+}
@@ -0,0 +1,73 @@
+package pcircle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMapset_HitSamples(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "soft-hitclap2.ogg"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &Mapset{DirectoryPath: dir}
+
+	b := &Beatmap{
+		SampleSet:        NORMAL_SAMPLESET,
+		SliderMultiplier: 1,
+		SliderTickRate:   1,
+		TimingPoints: []*TimingPoint{
+			{Offset: 0, MillisecondsPerBeat: 500, SampleSet: SOFT_SAMPLESET, SampleIndex: 2, Volume: 70, Inherited: true},
+			{Offset: 2000, MillisecondsPerBeat: -100, SampleSet: DRUM_SAMPLESET, SampleIndex: 3, Volume: 40},
+		},
+		HitObjects: []interface{}{
+			&Circle{BaseHitObject{Time: 1000, HitSound: CLAP_HITSOUND, Extras: &Extras{}}},
+			&Circle{BaseHitObject{Time: 1500, HitSound: FINISH_HITSOUND, Extras: &Extras{SampleSet: NORMAL_SAMPLESET, SampleVolume: 90}}},
+			&Circle{BaseHitObject{Time: 1800, Extras: &Extras{Filename: "kick.wav"}}},
+			&Slider{
+				BaseHitObject: BaseHitObject{Time: 1997, Extras: &Extras{}},
+				SliderPath:    &SliderPath{SliderType: LINEAR_CURVE, CurvePoints: []*SliderCurvePoint{{200, 0}}},
+				HitSound:      WHISTLE_HITSOUND,
+				Repeat:        1,
+				PixelLength:   200,
+				EdgeHitSounds: []HitSound{NO_HITSOUND, CLAP_HITSOUND},
+				EdgeAdditions: []*SliderEdgeAddition{{}, {SampleSet: NORMAL_SAMPLESET, AdditionSet: SOFT_SAMPLESET}},
+			},
+		},
+	}
+
+	want := []struct {
+		time     int
+		duration int
+		filename string
+		skin     bool
+		volume   int
+	}{
+		{1000, 0, "soft-hitnormal.wav", true, 70},
+		{1000, 0, "soft-hitclap2.ogg", false, 70},
+		{1500, 0, "normal-hitnormal.wav", true, 90},
+		{1500, 0, "normal-hitfinish.wav", true, 90},
+		{1800, 0, "kick.wav", false, 70},
+		{1997, 0, "drum-hitnormal.wav", true, 40},
+		{2997, 0, "normal-hitnormal.wav", true, 40},
+		{2997, 0, "soft-hitclap.wav", true, 40},
+		{1997, 1000, "drum-sliderslide.wav", true, 40},
+		{1997, 1000, "drum-sliderwhistle.wav", true, 40},
+		{2497, 0, "drum-slidertick.wav", true, 40},
+	}
+
+	got := m.HitSamples(b)
+	if len(got) != len(want) {
+		for _, s := range got {
+			t.Logf("%+v", *s)
+		}
+		t.Fatalf("Mapset.HitSamples() returned %v samples, want %v", len(got), len(want))
+	}
+	for i, w := range want {
+		s := got[i]
+		if s.Time != w.time || s.Duration != w.duration || s.Filename != w.filename || s.Skin != w.skin || s.Volume != w.volume {
+			t.Errorf("Mapset.HitSamples()[%v] = %+v, want %+v", i, *s, w)
+		}
+	}
+}
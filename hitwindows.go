@@ -0,0 +1,176 @@
+package pcircle
+
+import (
+	"math"
+)
+
+// Hard Rock and Easy multiplier of osu!mania hit windows.
+const MANIA_HIT_WINDOWS_MOD_MULTIPLIER = 1.4
+
+// HitWindows holds half-widths of judgement hit windows in milliseconds.
+// A hit is given a judgement if it happens within the window before or after hit object time.
+// Judgements which do not exist in the ruleset have zero windows.
+type HitWindows struct {
+	Perfect float64 // MAX (rainbow 300) in osu!mania
+	Great   float64 // 300 in osu! and osu!mania, GREAT in osu!taiko
+	Good    float64 // 200 in osu!mania
+	Ok      float64 // 100 in osu! and osu!mania, OK in osu!taiko
+	Meh     float64 // 50 in osu! and osu!mania
+	Miss    float64 // Hits outside of this window are ignored
+}
+
+// scale multiplies all hit windows.
+func (hw *HitWindows) scale(multiplier float64) {
+	hw.Perfect *= multiplier
+	hw.Great *= multiplier
+	hw.Good *= multiplier
+	hw.Ok *= multiplier
+	hw.Meh *= multiplier
+	hw.Miss *= multiplier
+}
+
+// HitWindows returns hit windows of beatmap's game mode with specified mods
+// and rate applied. Rate of zero means the default rate of the mods.
+// Windows are in real (played) time, i.e. they get narrower with DT.
+//
+// osu!mania uses ScoreV1 windows, which Hard Rock and Easy scale directly
+// instead of changing OverallDifficulty. osu!catch has no hit windows.
+func (b *Beatmap) HitWindows(mods Mods, rate float64) *HitWindows {
+	if rate <= 0 {
+		rate = mods.Rate()
+	}
+
+	od := b.OverallDifficulty
+	hw := new(HitWindows)
+
+	switch b.GameMode {
+	case OSU_GAMEMODE:
+		od = difficultyWithMods(od, mods)
+		hw.Great = difficultyRange(od, 80, 50, 20)
+		hw.Ok = difficultyRange(od, 140, 100, 60)
+		hw.Meh = difficultyRange(od, 200, 150, 100)
+		hw.Miss = 400
+	case TAIKO_GAMEMODE:
+		od = difficultyWithMods(od, mods)
+		hw.Great = difficultyRange(od, 50, 35, 20)
+		hw.Ok = difficultyRange(od, 120, 80, 50)
+		hw.Miss = difficultyRange(od, 135, 95, 70)
+	case MANIA_GAMEMODE:
+		hw.Perfect = 16
+		hw.Great = 64 - 3*od
+		hw.Good = 97 - 3*od
+		hw.Ok = 127 - 3*od
+		hw.Meh = 151 - 3*od
+		hw.Miss = 188 - 3*od
+		if mods.Has(HARD_ROCK_MOD) {
+			hw.scale(1 / MANIA_HIT_WINDOWS_MOD_MULTIPLIER)
+		} else if mods.Has(EASY_MOD) {
+			hw.scale(MANIA_HIT_WINDOWS_MOD_MULTIPLIER)
+		}
+	}
+
+	hw.scale(1 / rate)
+	return hw
+}
+
+// Preempt returns time in milliseconds between the moment hit object starts
+// to appear and the moment it should be hit, with specified mods and rate applied.
+// Rate of zero means the default rate of the mods. Time is in real (played) time.
+func (b *Beatmap) Preempt(mods Mods, rate float64) float64 {
+	if rate <= 0 {
+		rate = mods.Rate()
+	}
+	return approachRateToPreempt(difficultyWithMods(b.ApproachRate, mods)) / rate
+}
+
+// FadeIn returns duration of hit object fade-in animation in milliseconds
+// with specified mods and rate applied. Hidden makes hit objects fade in
+// over 40% of the preempt time.
+func (b *Beatmap) FadeIn(mods Mods, rate float64) float64 {
+	preempt := b.Preempt(mods, rate)
+	if mods.Has(HIDDEN_MOD) {
+		return preempt * 0.4
+	}
+	return 400 * math.Min(1, preempt/450)
+}
+
+// EffectiveApproachRate returns ApproachRate which preempt time at normal speed
+// is the same as the preempt of the beatmap with specified mods and rate.
+func (b *Beatmap) EffectiveApproachRate(mods Mods, rate float64) float64 {
+	return preemptToApproachRate(b.Preempt(mods, rate))
+}
+
+// EffectiveOverallDifficulty returns OverallDifficulty which hit windows at normal speed
+// are the same as the windows of the beatmap with specified mods and rate.
+func (b *Beatmap) EffectiveOverallDifficulty(mods Mods, rate float64) float64 {
+	if rate <= 0 {
+		rate = mods.Rate()
+	}
+	od := b.OverallDifficulty
+	if b.GameMode == MANIA_GAMEMODE {
+		// Hard Rock and Easy scale osu!mania windows like a rate does
+		if mods.Has(HARD_ROCK_MOD) {
+			rate *= MANIA_HIT_WINDOWS_MOD_MULTIPLIER
+		} else if mods.Has(EASY_MOD) {
+			rate /= MANIA_HIT_WINDOWS_MOD_MULTIPLIER
+		}
+	} else {
+		od = difficultyWithMods(od, mods)
+	}
+	return rateAdjustedOverallDifficulty(b.GameMode, od, rate)
+}
+
+// difficultyWithMods applies Hard Rock or Easy multiplier to difficulty value.
+func difficultyWithMods(value float64, mods Mods) float64 {
+	if mods.Has(HARD_ROCK_MOD) {
+		return math.Min(value*1.4, 10)
+	}
+	if mods.Has(EASY_MOD) {
+		return value * 0.5
+	}
+	return value
+}
+
+// difficultyRange maps difficulty value from 0-5-10 range to min-mid-max range.
+func difficultyRange(difficulty, min, mid, max float64) float64 {
+	if difficulty > 5 {
+		return mid + (max-mid)*(difficulty-5)/5
+	}
+	if difficulty < 5 {
+		return mid - (mid-min)*(5-difficulty)/5
+	}
+	return mid
+}
+
+// approachRateToPreempt returns time in milliseconds between the moment
+// hit object starts to appear and the moment it should be hit.
+func approachRateToPreempt(ar float64) float64 {
+	return difficultyRange(ar, 1800, 1200, 450)
+}
+
+// preemptToApproachRate is the inverse of approachRateToPreempt.
+func preemptToApproachRate(preempt float64) float64 {
+	if preempt > 1200 {
+		return 5 - (preempt-1200)/120
+	}
+	return 5 + (1200-preempt)/150
+}
+
+// greatWindowFormula returns base and multiplier of the linear formula of the
+// half-width of the best hit window (base - multiplier * OD) in specified game mode.
+func greatWindowFormula(mode int) (base, multiplier float64) {
+	switch mode {
+	case TAIKO_GAMEMODE:
+		return 50, 3
+	case MANIA_GAMEMODE:
+		return 64, 3
+	}
+	return 80, 6
+}
+
+// rateAdjustedOverallDifficulty returns OverallDifficulty which hit windows are
+// the same as the windows of specified OverallDifficulty played at specified rate.
+func rateAdjustedOverallDifficulty(mode int, od, rate float64) float64 {
+	base, multiplier := greatWindowFormula(mode)
+	return (base - (base-multiplier*od)/rate) / multiplier
+}
@@ -0,0 +1,145 @@
+package pcircle
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBeatmap_HitWindows(t *testing.T) {
+	tests := []struct {
+		name string
+		mode int
+		od   float64
+		mods Mods
+		rate float64
+		want HitWindows
+	}{
+		{
+			name: "osu! OD8",
+			mode: OSU_GAMEMODE,
+			od:   8,
+			want: HitWindows{Great: 32, Ok: 76, Meh: 120, Miss: 400},
+		},
+		{
+			name: "osu! OD8 DT",
+			mode: OSU_GAMEMODE,
+			od:   8,
+			mods: DOUBLE_TIME_MOD,
+			want: HitWindows{Great: 32 / 1.5, Ok: 76 / 1.5, Meh: 80, Miss: 400 / 1.5},
+		},
+		{
+			name: "osu!taiko OD5 HR",
+			mode: TAIKO_GAMEMODE,
+			od:   5,
+			mods: HARD_ROCK_MOD,
+			want: HitWindows{Great: 29, Ok: 68, Miss: 85},
+		},
+		{
+			name: "osu!mania OD8 HR",
+			mode: MANIA_GAMEMODE,
+			od:   8,
+			mods: HARD_ROCK_MOD,
+			want: HitWindows{Perfect: 16 / 1.4, Great: 40 / 1.4, Good: 73 / 1.4, Ok: 103 / 1.4, Meh: 127 / 1.4, Miss: 164 / 1.4},
+		},
+		{
+			name: "osu!mania OD8 custom rate",
+			mode: MANIA_GAMEMODE,
+			od:   8,
+			rate: 2,
+			want: HitWindows{Perfect: 8, Great: 20, Good: 36.5, Ok: 51.5, Meh: 63.5, Miss: 82},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Beatmap{GameMode: tt.mode, OverallDifficulty: tt.od}
+			got := b.HitWindows(tt.mods, tt.rate)
+			for _, pair := range [][2]float64{
+				{got.Perfect, tt.want.Perfect},
+				{got.Great, tt.want.Great},
+				{got.Good, tt.want.Good},
+				{got.Ok, tt.want.Ok},
+				{got.Meh, tt.want.Meh},
+				{got.Miss, tt.want.Miss},
+			} {
+				if math.Abs(pair[0]-pair[1]) > 1e-9 {
+					t.Errorf("Beatmap.HitWindows() = %+v, want %+v", *got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestBeatmap_EffectiveOverallDifficulty(t *testing.T) {
+	tests := []struct {
+		name string
+		mode int
+		mods Mods
+	}{
+		{"osu! DT", OSU_GAMEMODE, DOUBLE_TIME_MOD},
+		{"osu!taiko HR", TAIKO_GAMEMODE, HARD_ROCK_MOD},
+		{"osu!mania HR", MANIA_GAMEMODE, HARD_ROCK_MOD},
+		{"osu!mania EZ", MANIA_GAMEMODE, EASY_MOD},
+		{"osu!mania HR DT", MANIA_GAMEMODE, HARD_ROCK_MOD | DOUBLE_TIME_MOD},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Beatmap{GameMode: tt.mode, OverallDifficulty: 8}
+			want := b.HitWindows(tt.mods, 0).Great
+			effective := &Beatmap{GameMode: tt.mode, OverallDifficulty: b.EffectiveOverallDifficulty(tt.mods, 0)}
+			if got := effective.HitWindows(NO_MOD, 1).Great; math.Abs(got-want) > 1e-9 {
+				t.Errorf("Beatmap.EffectiveOverallDifficulty() = %v with great window %v, want window %v",
+					effective.OverallDifficulty, got, want)
+			}
+		})
+	}
+}
+
+func TestBeatmap_Preempt(t *testing.T) {
+	tests := []struct {
+		name        string
+		ar          float64
+		mods        Mods
+		wantPreempt float64
+		wantFadeIn  float64
+		wantAR      float64
+	}{
+		{
+			name:        "AR9",
+			ar:          9,
+			wantPreempt: 600,
+			wantFadeIn:  400,
+			wantAR:      9,
+		},
+		{
+			name:        "AR9 HDDT",
+			ar:          9,
+			mods:        HIDDEN_MOD | DOUBLE_TIME_MOD,
+			wantPreempt: 400,
+			wantFadeIn:  160,
+			wantAR:      10.3333333,
+		},
+		{
+			name:        "AR3 HT",
+			ar:          3,
+			mods:        HALF_TIME_MOD,
+			wantPreempt: 1440 / 0.75,
+			wantFadeIn:  400,
+			wantAR:      -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Beatmap{ApproachRate: tt.ar}
+			if got := b.Preempt(tt.mods, 0); math.Abs(got-tt.wantPreempt) > 1e-6 {
+				t.Errorf("Beatmap.Preempt() = %v, want %v", got, tt.wantPreempt)
+			}
+			if got := b.FadeIn(tt.mods, 0); math.Abs(got-tt.wantFadeIn) > 1e-6 {
+				t.Errorf("Beatmap.FadeIn() = %v, want %v", got, tt.wantFadeIn)
+			}
+			if got := b.EffectiveApproachRate(tt.mods, 0); math.Abs(got-tt.wantAR) > 1e-6 {
+				t.Errorf("Beatmap.EffectiveApproachRate() = %v, want %v", got, tt.wantAR)
+			}
+		})
+	}
+}
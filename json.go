@@ -0,0 +1,336 @@
+package pcircle
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// JSONSchema is JSON Schema of beatmaps and mapsets encoded by ToJSON.
+//
+//go:embed schema.json
+var JSONSchema string
+
+// JSONOptions configures JSON encoding of beatmaps and mapsets.
+type JSONOptions struct {
+	Computed bool   // Include computed fields of hit objects: end time, stack height and combo
+	Indent   string // Indentation of nested values, output is compact if empty
+}
+
+// Names of game modes, countdowns and slider types in JSON.
+var (
+	gameModeNames = map[int]string{
+		OSU_GAMEMODE:   "osu",
+		TAIKO_GAMEMODE: "taiko",
+		CTB_GAMEMODE:   "catch",
+		MANIA_GAMEMODE: "mania",
+	}
+	countdownNames = map[int]string{
+		NO_COUNTDOWN:     "none",
+		NORMAL_COUNTDOWN: "normal",
+		HALF_COUNTDOWN:   "half",
+		DOUBLE_COUNTDOWN: "double",
+	}
+	sliderTypeNames = map[string]string{
+		"B": "bezier",
+		"C": "catmull",
+		"L": "linear",
+		"P": "perfect",
+	}
+)
+
+// Names of hit object types in JSON.
+const (
+	CIRCLE_JSON_TYPE    = "circle"
+	SLIDER_JSON_TYPE    = "slider"
+	SPINNER_JSON_TYPE   = "spinner"
+	HOLD_NOTE_JSON_TYPE = "hold"
+)
+
+// namedInt is an integer enum encoded by its name. Values without a name are encoded as numbers.
+type namedInt struct {
+	value int
+	names map[int]string
+}
+
+// MarshalJSON encodes namedInt by its name.
+func (n namedInt) MarshalJSON() ([]byte, error) {
+	if name, ok := n.names[n.value]; ok {
+		return json.Marshal(name)
+	}
+	return json.Marshal(n.value)
+}
+
+// UnmarshalJSON decodes namedInt from its name or number.
+func (n *namedInt) UnmarshalJSON(data []byte) error {
+	if json.Unmarshal(data, &n.value) == nil {
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for value, s := range n.names {
+		if s == name {
+			n.value = value
+			return nil
+		}
+	}
+	return errors.New("invalid enum value: " + name)
+}
+
+// hitObjectJSON is JSON representation of any hit object.
+type hitObjectJSON struct {
+	Type      string   `json:"type"`
+	X         int      `json:"x"`
+	Y         int      `json:"y"`
+	Time      int      `json:"time"`
+	NewCombo  bool     `json:"new_combo,omitempty"`
+	ComboSkip int      `json:"combo_skip,omitempty"`
+	HitSound  HitSound `json:"hit_sound"`
+	Extras    *Extras  `json:"extras,omitempty"`
+
+	// Sliders
+	Curve         string                `json:"curve,omitempty"`
+	CurvePoints   []*SliderCurvePoint   `json:"curve_points,omitempty"`
+	Repeat        int                   `json:"repeat,omitempty"`
+	PixelLength   float64               `json:"pixel_length,omitempty"`
+	EdgeHitSounds []HitSound            `json:"edge_hit_sounds,omitempty"`
+	EdgeAdditions []*SliderEdgeAddition `json:"edge_additions,omitempty"`
+
+	// Spinners and hold notes
+	EndTime int `json:"end_time,omitempty"`
+
+	Computed *hitObjectComputed `json:"computed,omitempty"`
+}
+
+// hitObjectComputed holds computed fields of a hit object, they are ignored when decoding.
+type hitObjectComputed struct {
+	EndTime     int        `json:"end_time"`
+	StackHeight int        `json:"stack_height"`
+	Combo       *ComboInfo `json:"combo"`
+}
+
+// newHitObjectJSON returns JSON representation of a hit object.
+func newHitObjectJSON(hitObject interface{}) (*hitObjectJSON, error) {
+	base := BaseOf(hitObject)
+	if base == nil {
+		return nil, errors.New("unknown hit object type")
+	}
+	o := &hitObjectJSON{
+		X:         base.X,
+		Y:         base.Y,
+		Time:      base.Time,
+		NewCombo:  base.IsNewCombo(),
+		ComboSkip: base.ComboSkip(),
+		HitSound:  base.HitSound,
+		Extras:    base.Extras,
+	}
+	switch h := hitObject.(type) {
+	case *Circle:
+		o.Type = CIRCLE_JSON_TYPE
+	case *Slider:
+		o.Type = SLIDER_JSON_TYPE
+		o.HitSound = h.HitSound
+		if h.SliderPath != nil {
+			o.Curve = h.SliderPath.SliderType
+			if name, ok := sliderTypeNames[o.Curve]; ok {
+				o.Curve = name
+			}
+			o.CurvePoints = h.SliderPath.CurvePoints
+		}
+		o.Repeat = h.Repeat
+		o.PixelLength = h.PixelLength
+		o.EdgeHitSounds = h.EdgeHitSounds
+		o.EdgeAdditions = h.EdgeAdditions
+	case *Spinner:
+		o.Type = SPINNER_JSON_TYPE
+		o.EndTime = h.EndTime
+	case *ManiaHoldNote:
+		o.Type = HOLD_NOTE_JSON_TYPE
+		o.EndTime = h.EndTime
+	}
+	return o, nil
+}
+
+// hitObject returns hit object described by JSON representation.
+func (o *hitObjectJSON) hitObject() (interface{}, error) {
+	base := BaseHitObject{
+		X:        o.X,
+		Y:        o.Y,
+		Time:     o.Time,
+		HitSound: o.HitSound,
+		Extras:   o.Extras,
+	}
+	if o.NewCombo {
+		base.Type |= NEW_COMBO
+	}
+	base.Type |= (o.ComboSkip & 7) << 4
+
+	switch o.Type {
+	case CIRCLE_JSON_TYPE:
+		base.Type |= CIRCLE
+		return &Circle{BaseHitObject: base}, nil
+	case SLIDER_JSON_TYPE:
+		base.Type |= SLIDER
+		s := &Slider{
+			SliderPath:    &SliderPath{SliderType: o.Curve, CurvePoints: o.CurvePoints},
+			Repeat:        o.Repeat,
+			PixelLength:   o.PixelLength,
+			HitSound:      o.HitSound,
+			EdgeHitSounds: o.EdgeHitSounds,
+			EdgeAdditions: o.EdgeAdditions,
+		}
+		for letter, name := range sliderTypeNames {
+			if name == o.Curve {
+				s.SliderPath.SliderType = letter
+			}
+		}
+		base.HitSound = NO_HITSOUND
+		s.BaseHitObject = base
+		return s, nil
+	case SPINNER_JSON_TYPE:
+		base.Type |= SPINNER
+		return &Spinner{BaseHitObject: base, EndTime: o.EndTime}, nil
+	case HOLD_NOTE_JSON_TYPE:
+		base.Type |= MANIA_HOLD_NOTE
+		return &ManiaHoldNote{BaseHitObject: base, EndTime: o.EndTime}, nil
+	}
+	return nil, errors.New("invalid hit object type: " + strconv.Quote(o.Type))
+}
+
+// beatmapAlias has fields of Beatmap without its methods.
+type beatmapAlias Beatmap
+
+// beatmapJSON is JSON representation of Beatmap with readable enums and typed hit objects.
+type beatmapJSON struct {
+	*beatmapAlias
+	Countdown  namedInt         `json:"countdown"`
+	GameMode   namedInt         `json:"game_mode"`
+	HitObjects []*hitObjectJSON `json:"hit_objects"`
+}
+
+// toJSON returns JSON representation of Beatmap.
+func (b *Beatmap) toJSON(computed bool) (*beatmapJSON, error) {
+	bj := &beatmapJSON{
+		beatmapAlias: (*beatmapAlias)(b),
+		Countdown:    namedInt{b.Countdown, countdownNames},
+		GameMode:     namedInt{b.GameMode, gameModeNames},
+		HitObjects:   make([]*hitObjectJSON, len(b.HitObjects)),
+	}
+
+	var combos []*ComboInfo
+	if computed {
+		combos = b.Combos(DefaultComboColours)
+	}
+	for i, hitObject := range b.HitObjects {
+		o, err := newHitObjectJSON(hitObject)
+		if err != nil {
+			return nil, err
+		}
+		if computed {
+			o.Computed = &hitObjectComputed{
+				EndTime:     b.EndTime(hitObject),
+				StackHeight: BaseOf(hitObject).StackHeight,
+				Combo:       combos[i],
+			}
+		}
+		bj.HitObjects[i] = o
+	}
+	return bj, nil
+}
+
+// MarshalJSON encodes Beatmap to JSON without computed fields.
+func (b *Beatmap) MarshalJSON() ([]byte, error) {
+	bj, err := b.toJSON(false)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(bj)
+}
+
+// UnmarshalJSON fills Beatmap with data decoded from JSON.
+func (b *Beatmap) UnmarshalJSON(data []byte) error {
+	bj := &beatmapJSON{
+		beatmapAlias: (*beatmapAlias)(b),
+		Countdown:    namedInt{names: countdownNames},
+		GameMode:     namedInt{names: gameModeNames},
+	}
+	if err := json.Unmarshal(data, bj); err != nil {
+		return err
+	}
+	b.Countdown = bj.Countdown.value
+	b.GameMode = bj.GameMode.value
+
+	b.HitObjects = make([]interface{}, len(bj.HitObjects))
+	for i, o := range bj.HitObjects {
+		hitObject, err := o.hitObject()
+		if err != nil {
+			return err
+		}
+		b.HitObjects[i] = hitObject
+	}
+	return nil
+}
+
+// ToJSON writes Beatmap encoded to JSON to w. If opts is nil, defaults are used.
+func (b *Beatmap) ToJSON(w io.Writer, opts *JSONOptions) error {
+	if opts == nil {
+		opts = new(JSONOptions)
+	}
+	bj, err := b.toJSON(opts.Computed)
+	if err != nil {
+		return err
+	}
+	return encodeJSON(w, bj, opts)
+}
+
+// FromJSON fills Beatmap with data decoded from JSON read from r.
+func (b *Beatmap) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(b)
+}
+
+// mapsetAlias has fields of Mapset without its methods.
+type mapsetAlias Mapset
+
+// mapsetJSON is JSON representation of Mapset, which allows to encode computed fields of its beatmaps.
+type mapsetJSON struct {
+	*mapsetAlias
+	Beatmaps []*beatmapJSON `json:"beatmaps"`
+}
+
+// ToJSON writes Mapset encoded to JSON to w. If opts is nil, defaults are used.
+func (m *Mapset) ToJSON(w io.Writer, opts *JSONOptions) error {
+	if opts == nil {
+		opts = new(JSONOptions)
+	}
+	mj := &mapsetJSON{
+		mapsetAlias: (*mapsetAlias)(m),
+		Beatmaps:    make([]*beatmapJSON, len(m.Beatmaps)),
+	}
+	for i, b := range m.Beatmaps {
+		bj, err := b.toJSON(opts.Computed)
+		if err != nil {
+			return err
+		}
+		mj.Beatmaps[i] = bj
+	}
+	return encodeJSON(w, mj, opts)
+}
+
+// FromJSON fills Mapset with data decoded from JSON read from r.
+func (m *Mapset) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(m)
+}
+
+// encodeJSON writes v encoded to JSON to w.
+func encodeJSON(w io.Writer, v interface{}, opts *JSONOptions) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if opts.Indent != "" {
+		enc.SetIndent("", opts.Indent)
+	}
+	return enc.Encode(v)
+}
@@ -0,0 +1,183 @@
+package pcircle
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newTestJSONBeatmap() *Beatmap {
+	b := newTestBeatmap()
+	b.Countdown = DOUBLE_COUNTDOWN
+	b.SampleSet = SOFT_SAMPLESET
+	b.Tags = []string{"first", "second"}
+	b.Background = &Background{FileName: "bg.jpg", XOffset: 10}
+	b.Video = &Video{StartTime: -200, FileName: "video.avi"}
+	b.Storyboard = []string{`Sprite,Foreground,Centre,"star.png",320,240`, " F,0,1000,2000,0,1"}
+	b.ComboColours = []*RGB{{255, 128, 0}, {0, 128, 255}}
+	b.SliderBorder = &RGB{255, 255, 255}
+	BaseOf(b.HitObjects[1]).Type |= NEW_COMBO | COMBO_SKIP_2
+	b.HitObjects = append(b.HitObjects, &ManiaHoldNote{
+		BaseHitObject: BaseHitObject{X: 64, Y: 192, Time: 13000, Type: MANIA_HOLD_NOTE, Extras: &Extras{SampleSet: DRUM_SAMPLESET, Filename: "hold.wav"}},
+		EndTime:       13500,
+	})
+	return b
+}
+
+func TestBeatmap_ToJSON(t *testing.T) {
+	b := newTestJSONBeatmap()
+
+	var buf bytes.Buffer
+	if err := b.ToJSON(&buf, &JSONOptions{Indent: "  "}); err != nil {
+		t.Fatalf("Beatmap.ToJSON() error = %v", err)
+	}
+	data := buf.String()
+	for _, want := range []string{`"countdown": "double"`, `"game_mode": "osu"`, `"sample_set": "Soft"`, `"type": "slider"`, `"curve": "linear"`, `"type": "hold"`} {
+		if !strings.Contains(data, want) {
+			t.Errorf("Beatmap.ToJSON() does not contain %v", want)
+		}
+	}
+	if strings.Contains(data, `"computed"`) {
+		t.Errorf("Beatmap.ToJSON() contains computed fields without JSONOptions.Computed")
+	}
+
+	nb := NewBeatmap()
+	if err := nb.FromJSON(&buf); err != nil {
+		t.Fatalf("Beatmap.FromJSON() error = %v", err)
+	}
+	if got, want := nb.String(), b.String(); got != want {
+		t.Errorf("Beatmap.String() after JSON round trip = %v, want %v", got, want)
+	}
+}
+
+func TestBeatmap_ToJSON_computed(t *testing.T) {
+	b := newTestJSONBeatmap()
+
+	var buf bytes.Buffer
+	if err := b.ToJSON(&buf, &JSONOptions{Computed: true}); err != nil {
+		t.Fatalf("Beatmap.ToJSON() error = %v", err)
+	}
+	var decoded struct {
+		HitObjects []*hitObjectJSON `json:"hit_objects"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	slider := decoded.HitObjects[1]
+	if slider.Computed == nil {
+		t.Fatalf("Beatmap.ToJSON() slider has no computed fields")
+	}
+	if got, want := slider.Computed.EndTime, 3250; got != want {
+		t.Errorf("Beatmap.ToJSON() slider end time = %v, want %v", got, want)
+	}
+	if got, want := slider.Computed.Combo.ComboIndex, 2; got != want {
+		t.Errorf("Beatmap.ToJSON() slider combo index = %v, want %v", got, want)
+	}
+	if got, want := slider.ComboSkip, 2; got != want {
+		t.Errorf("Beatmap.ToJSON() slider combo skip = %v, want %v", got, want)
+	}
+
+	// computed fields are ignored when decoding
+	nb := NewBeatmap()
+	if err := nb.FromJSON(&buf); err != nil {
+		t.Fatalf("Beatmap.FromJSON() error = %v", err)
+	}
+	if got, want := nb.String(), b.String(); got != want {
+		t.Errorf("Beatmap.String() after JSON round trip = %v, want %v", got, want)
+	}
+}
+
+func TestMapset_ToJSON(t *testing.T) {
+	m := NewMapset()
+	m.BeatmapSetID = 42
+	m.Beatmaps = []*Beatmap{newTestJSONBeatmap(), newTestBeatmap()}
+	m.Storyboard = &Storyboard{Events: []string{`Sprite,Background,Centre,"bg.jpg",320,240`}}
+
+	var buf bytes.Buffer
+	if err := m.ToJSON(&buf, nil); err != nil {
+		t.Fatalf("Mapset.ToJSON() error = %v", err)
+	}
+	nm := NewMapset()
+	if err := nm.FromJSON(&buf); err != nil {
+		t.Fatalf("Mapset.FromJSON() error = %v", err)
+	}
+	if nm.BeatmapSetID != m.BeatmapSetID || len(nm.Beatmaps) != len(m.Beatmaps) || nm.Storyboard.String() != m.Storyboard.String() {
+		t.Fatalf("Mapset.FromJSON() = %+v, want %+v", nm, m)
+	}
+	for i := range m.Beatmaps {
+		if got, want := nm.Beatmaps[i].String(), m.Beatmaps[i].String(); got != want {
+			t.Errorf("Beatmap.String() of beatmap %d after JSON round trip = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestBeatmap_FromJSON_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"hit object type", `{"hit_objects": [{"type": "note", "x": 0, "y": 0, "time": 0}]}`},
+		{"game mode", `{"game_mode": "drums", "hit_objects": []}`},
+		{"sample set", `{"sample_set": "Loud", "hit_objects": []}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewBeatmap().FromJSON(strings.NewReader(tt.data)); err == nil {
+				t.Errorf("Beatmap.FromJSON() error = nil, want error")
+			}
+		})
+	}
+}
+
+// schemaProperties returns names of properties of a definition in JSONSchema.
+func schemaProperties(t *testing.T, def string) map[string]interface{} {
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal([]byte(JSONSchema), &schema); err != nil {
+		t.Fatalf("JSONSchema is invalid: %v", err)
+	}
+	return schema.Defs[def].Properties
+}
+
+func TestJSONSchema(t *testing.T) {
+	m := NewMapset()
+	m.Beatmaps = []*Beatmap{newTestJSONBeatmap()}
+	m.Storyboard = &Storyboard{FilePath: "a.osb", Variables: []string{"$a=b"}, Events: []string{"a"}}
+	m.DirectoryPath = "mapset"
+
+	var buf bytes.Buffer
+	if err := m.ToJSON(&buf, &JSONOptions{Computed: true}); err != nil {
+		t.Fatalf("Mapset.ToJSON() error = %v", err)
+	}
+	var decoded struct {
+		Beatmaps []struct {
+			HitObjects []map[string]interface{} `json:"hit_objects"`
+		} `json:"beatmaps"`
+	}
+	var mapset map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &mapset); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(def string, fields map[string]interface{}) {
+		properties := schemaProperties(t, def)
+		for name := range fields {
+			if _, ok := properties[name]; !ok {
+				t.Errorf("JSONSchema %s has no property %v", def, name)
+			}
+		}
+	}
+	check("mapset", mapset)
+	check("beatmap", mapset["beatmaps"].([]interface{})[0].(map[string]interface{}))
+	for _, o := range decoded.Beatmaps[0].HitObjects {
+		check("hit_object", o)
+	}
+}
@@ -0,0 +1,141 @@
+package pcircle
+
+import (
+	"math"
+)
+
+// Gaps which the editor keeps around automatically inserted breaks, in milliseconds.
+const (
+	BREAK_GAP_BEFORE = 200 // After the end of the previous hit object
+	BREAK_GAP_AFTER  = 450 // Before the next hit object, unless its preempt is longer
+)
+
+// LengthMetrics are length related metrics of a beatmap.
+type LengthMetrics struct {
+	TotalLength   int     // Milliseconds from the first hit object to the end of the last one
+	DrainTime     int     // TotalLength excluding breaks, in milliseconds
+	RankedDrain   int     // DrainTime in whole seconds, as ranking criteria count it
+	ObjectDensity float64 // Hit objects per second of drain time
+	KiaiTime      int     // Milliseconds of kiai within TotalLength
+	KiaiCoverage  float64 // Part of TotalLength covered by kiai, from 0 to 1
+}
+
+// playRange returns start of the first hit object and end of the last one.
+func (b *Beatmap) playRange() (start, end int) {
+	if len(b.HitObjects) == 0 {
+		return 0, 0
+	}
+	start = BaseOf(b.HitObjects[0]).Time
+	end = start
+	for _, hitObject := range b.HitObjects {
+		if t := BaseOf(hitObject).Time; t < start {
+			start = t
+		}
+		if t := b.EndTime(hitObject); t > end {
+			end = t
+		}
+	}
+	return start, end
+}
+
+// TotalLength returns time in milliseconds from the first hit object to the end of the last one.
+func (b *Beatmap) TotalLength() int {
+	start, end := b.playRange()
+	return end - start
+}
+
+// DrainTime returns TotalLength excluding breaks, in milliseconds.
+func (b *Beatmap) DrainTime() int {
+	start, end := b.playRange()
+	drain := end - start
+	for _, br := range b.Breaks {
+		// only parts of breaks between hit objects count
+		from, to := max(br.StartTime, start), min(br.EndTime, end)
+		if to > from {
+			drain -= to - from
+		}
+	}
+	return drain
+}
+
+// RankedDrainTime returns drain time in whole seconds, rounded down like ranking criteria
+// and the website do, so 3:29.9 is still below 3:30.
+func (b *Beatmap) RankedDrainTime() int {
+	return b.DrainTime() / 1000
+}
+
+// ObjectDensity returns number of hit objects per second of drain time.
+func (b *Beatmap) ObjectDensity() float64 {
+	drain := b.DrainTime()
+	if drain <= 0 {
+		return 0
+	}
+	return float64(len(b.HitObjects)) / (float64(drain) / 1000)
+}
+
+// KiaiTime returns time in milliseconds between the first hit object and the end of
+// the last one when kiai is active.
+func (b *Beatmap) KiaiTime() int {
+	start, end := b.playRange()
+	kiai := 0
+	for i, tp := range b.TimingPoints {
+		if !tp.Kiai {
+			continue
+		}
+		to := end
+		if i+1 < len(b.TimingPoints) {
+			to = min(to, b.TimingPoints[i+1].Offset)
+		}
+		if from := max(tp.Offset, start); to > from {
+			kiai += to - from
+		}
+	}
+	return kiai
+}
+
+// LengthMetrics returns length metrics of the beatmap played at specified rate, like
+// Mods.Rate(). Rate of zero or less means normal rate.
+func (b *Beatmap) LengthMetrics(rate float64) *LengthMetrics {
+	if rate <= 0 {
+		rate = 1
+	}
+	scale := func(time int) int {
+		return int(math.Round(float64(time) / rate))
+	}
+
+	m := &LengthMetrics{
+		TotalLength: scale(b.TotalLength()),
+		DrainTime:   scale(b.DrainTime()),
+		KiaiTime:    scale(b.KiaiTime()),
+	}
+	m.RankedDrain = m.DrainTime / 1000
+	if m.DrainTime > 0 {
+		m.ObjectDensity = float64(len(b.HitObjects)) / (float64(m.DrainTime) / 1000)
+	}
+	if m.TotalLength > 0 {
+		m.KiaiCoverage = float64(m.KiaiTime) / float64(m.TotalLength)
+	}
+	return m
+}
+
+// GenerateBreaks returns breaks the editor would insert between hit objects: a break starts
+// BREAK_GAP_BEFORE after the end of a hit object and ends before the next one, early enough
+// for it to appear. Breaks shorter than MIN_BREAK_DURATION are not inserted.
+// Hit objects must be sorted. Assign the result to Beatmap.Breaks to use it.
+func (b *Beatmap) GenerateBreaks() []*Break {
+	gapAfter := int(math.Max(BREAK_GAP_AFTER, b.Preempt(NO_MOD, 1)))
+
+	var breaks []*Break
+	lastEnd := 0
+	for i, hitObject := range b.HitObjects {
+		if i > 0 {
+			start := lastEnd + BREAK_GAP_BEFORE
+			end := BaseOf(hitObject).Time - gapAfter
+			if end-start >= MIN_BREAK_DURATION {
+				breaks = append(breaks, &Break{StartTime: start, EndTime: end})
+			}
+		}
+		lastEnd = max(lastEnd, b.EndTime(hitObject))
+	}
+	return breaks
+}
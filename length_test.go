@@ -0,0 +1,38 @@
+package pcircle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBeatmap_LengthMetrics(t *testing.T) {
+	b := newTestBeatmap()
+	b.TimingPoints[1].Kiai = true
+
+	tests := []struct {
+		rate float64
+		want *LengthMetrics
+	}{
+		{1, &LengthMetrics{TotalLength: 10500, DrainTime: 7500, RankedDrain: 7, ObjectDensity: 0.4, KiaiTime: 9000, KiaiCoverage: 9000.0 / 10500}},
+		{1.5, &LengthMetrics{TotalLength: 7000, DrainTime: 5000, RankedDrain: 5, ObjectDensity: 0.6, KiaiTime: 6000, KiaiCoverage: 6000.0 / 7000}},
+	}
+	for _, tt := range tests {
+		if got := b.LengthMetrics(tt.rate); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Beatmap.LengthMetrics(%v) = %+v, want %+v", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestBeatmap_GenerateBreaks(t *testing.T) {
+	b := newTestBeatmap()
+
+	want := []*Break{{StartTime: 1700, EndTime: 2400}, {StartTime: 3450, EndTime: 9400}}
+	if got := b.GenerateBreaks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Beatmap.GenerateBreaks() = %v, want %v", got, want)
+	}
+
+	b.ApproachRate = 5 // preempt of 1200ms leaves too little time for the first break
+	if got := b.GenerateBreaks(); len(got) != 1 || got[0].EndTime != 8800 {
+		t.Errorf("Beatmap.GenerateBreaks() with AR 5 = %v", got)
+	}
+}
@@ -0,0 +1,129 @@
+package pcircle
+
+import (
+	"sort"
+)
+
+// Severity specifies how serious the lint issue is.
+type Severity int
+
+// All possible severities.
+const (
+	INFO_SEVERITY    Severity = iota // Something worth checking manually
+	WARNING_SEVERITY                 // Probably a mistake
+	PROBLEM_SEVERITY                 // Definitely a mistake which must be fixed
+)
+
+// String returns string of Severity in readable format.
+func (s Severity) String() string {
+	return map[Severity]string{
+		INFO_SEVERITY:    "info",
+		WARNING_SEVERITY: "warning",
+		PROBLEM_SEVERITY: "problem",
+	}[s]
+}
+
+// MarshalText encodes Severity as its readable name.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// NO_TIME is the Issue.Time of issues which are not related to any moment of the beatmap.
+const NO_TIME = -1
+
+// Issue is a single problem found by a lint check.
+type Issue struct {
+	Check     string      // Name of the check which found the issue
+	Severity  Severity    // How serious the issue is
+	Message   string      // Human readable description
+	Time      int         // When the issue happens, NO_TIME if it is not related to time
+	HitObject interface{} // Related hit object, if any
+	Beatmap   *Beatmap    // Related beatmap, nil for mapset-wide issues
+}
+
+// BeatmapCheck is a lint check which inspects a single beatmap.
+type BeatmapCheck interface {
+	Name() string
+	CheckBeatmap(b *Beatmap) []*Issue
+}
+
+// MapsetCheck is a lint check which inspects the whole mapset.
+type MapsetCheck interface {
+	Name() string
+	CheckMapset(m *Mapset) []*Issue
+}
+
+// Linter runs lint checks over beatmaps and mapsets.
+type Linter struct {
+	BeatmapChecks []BeatmapCheck
+	MapsetChecks  []MapsetCheck
+}
+
+// NewLinter returns Linter with all default checks.
+func NewLinter() *Linter {
+	return &Linter{
+		BeatmapChecks: []BeatmapCheck{
+			UnsnappedObjectsCheck{},
+			BreaksCheck{},
+			OverlappingObjectsCheck{},
+			OffscreenSlidersCheck{},
+			MissingMetadataCheck{},
+			ComboColoursCheck{},
+			PreviewPointCheck{},
+		},
+		MapsetChecks: []MapsetCheck{
+			InconsistentMetadataCheck{},
+			FilesCheck{},
+		},
+	}
+}
+
+// LintBeatmap runs all beatmap checks over specified beatmap.
+// Issues are sorted by time, issues without time go first.
+func (l *Linter) LintBeatmap(b *Beatmap) []*Issue {
+	var issues []*Issue
+	for _, check := range l.BeatmapChecks {
+		for _, issue := range check.CheckBeatmap(b) {
+			issue.Check = check.Name()
+			issue.Beatmap = b
+			issues = append(issues, issue)
+		}
+	}
+	sortIssues(issues)
+	return issues
+}
+
+// LintMapset runs mapset checks over specified mapset and beatmap checks
+// over each of its beatmaps.
+func (l *Linter) LintMapset(m *Mapset) []*Issue {
+	var issues []*Issue
+	for _, check := range l.MapsetChecks {
+		for _, issue := range check.CheckMapset(m) {
+			issue.Check = check.Name()
+			issues = append(issues, issue)
+		}
+	}
+	sortIssues(issues)
+
+	for _, b := range m.Beatmaps {
+		issues = append(issues, l.LintBeatmap(b)...)
+	}
+	return issues
+}
+
+// sortIssues sorts issues by time keeping order of the checks.
+func sortIssues(issues []*Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Time < issues[j].Time
+	})
+}
+
+// newIssue creates issue with specified parameters.
+func newIssue(severity Severity, time int, hitObject interface{}, message string) *Issue {
+	return &Issue{
+		Severity:  severity,
+		Message:   message,
+		Time:      time,
+		HitObject: hitObject,
+	}
+}
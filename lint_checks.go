@@ -0,0 +1,366 @@
+package pcircle
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Thresholds used by lint checks.
+const (
+	MIN_BREAK_DURATION   = 650 // The shortest break the editor allows, in milliseconds
+	MIN_COMBO_LUMINOSITY = 50  // Combo colours darker than this are hard to see on dark backgrounds
+	MAX_COMBO_LUMINOSITY = 220 // Combo colours brighter than this blend with hit circle overlay
+)
+
+// Visible area of the screen in osu!pixels, relative to the playfield.
+const (
+	visibleAreaLeft   = -64
+	visibleAreaRight  = PLAYFIELD_WIDTH + 64
+	visibleAreaTop    = -56
+	visibleAreaBottom = PLAYFIELD_HEIGHT + 40
+)
+
+// UnsnappedObjectsCheck reports hit objects, slider repeats and ends, spinner and hold note
+// ends which are not snapped to any common beat divisor of the active red line.
+type UnsnappedObjectsCheck struct{}
+
+// Name returns name of the check.
+func (UnsnappedObjectsCheck) Name() string { return "unsnapped-objects" }
+
+// CheckBeatmap runs the check over a beatmap.
+func (UnsnappedObjectsCheck) CheckBeatmap(b *Beatmap) []*Issue {
+	var issues []*Issue
+	for _, p := range b.SnapAnalysis() {
+		if p.IsSnapped() {
+			continue
+		}
+		severity := WARNING_SEVERITY
+		if math.Abs(p.Error) >= 2 {
+			severity = PROBLEM_SEVERITY
+		}
+		issues = append(issues, newIssue(severity, int(p.Time), p.HitObject,
+			fmt.Sprintf("%s is unsnapped by %gms (closest to 1/%d)", p.Kind, math.Round(p.Error), p.Divisor)))
+	}
+	return issues
+}
+
+// BreaksCheck reports hit objects which happen during breaks and breaks which are too short.
+type BreaksCheck struct{}
+
+// Name returns name of the check.
+func (BreaksCheck) Name() string { return "breaks" }
+
+// CheckBeatmap runs the check over a beatmap.
+func (BreaksCheck) CheckBeatmap(b *Beatmap) []*Issue {
+	var issues []*Issue
+	for _, br := range b.Breaks {
+		if br.EndTime-br.StartTime < MIN_BREAK_DURATION {
+			issues = append(issues, newIssue(WARNING_SEVERITY, br.StartTime, nil,
+				fmt.Sprintf("break is only %dms long, it should be at least %dms", br.EndTime-br.StartTime, MIN_BREAK_DURATION)))
+		}
+
+		for _, hitObject := range b.HitObjects {
+			start, end := BaseOf(hitObject).Time, b.EndTime(hitObject)
+			if start < br.EndTime && end > br.StartTime {
+				issues = append(issues, newIssue(PROBLEM_SEVERITY, start, hitObject, "hit object is in a break"))
+			}
+		}
+	}
+	return issues
+}
+
+// OverlappingObjectsCheck reports hit objects which start before the previous one ends.
+// In osu!mania notes are compared within their columns.
+type OverlappingObjectsCheck struct{}
+
+// Name returns name of the check.
+func (OverlappingObjectsCheck) Name() string { return "overlapping-objects" }
+
+// CheckBeatmap runs the check over a beatmap.
+func (OverlappingObjectsCheck) CheckBeatmap(b *Beatmap) []*Issue {
+	var issues []*Issue
+
+	if b.GameMode == MANIA_GAMEMODE {
+		keys := int(b.CircleSize)
+		columnEnd := make(map[int]int)
+		for _, hitObject := range b.HitObjects {
+			base := BaseOf(hitObject)
+			column := ManiaColumn(base.X, keys)
+			if end, ok := columnEnd[column]; ok && base.Time <= end {
+				issues = append(issues, newIssue(PROBLEM_SEVERITY, base.Time, hitObject,
+					fmt.Sprintf("note is stacked on another note in column %d", column+1)))
+			}
+			columnEnd[column] = b.EndTime(hitObject)
+		}
+		return issues
+	}
+
+	for i := 1; i < len(b.HitObjects); i++ {
+		prevEnd := b.EndTime(b.HitObjects[i-1])
+		base := BaseOf(b.HitObjects[i])
+		if base.Time < prevEnd || base.Time == BaseOf(b.HitObjects[i-1]).Time {
+			issues = append(issues, newIssue(PROBLEM_SEVERITY, base.Time, b.HitObjects[i], "hit object overlaps the previous one"))
+		}
+	}
+	return issues
+}
+
+// OffscreenSlidersCheck reports osu!standard sliders which bodies go (partly) off the screen.
+type OffscreenSlidersCheck struct{}
+
+// Name returns name of the check.
+func (OffscreenSlidersCheck) Name() string { return "offscreen-sliders" }
+
+// CheckBeatmap runs the check over a beatmap.
+func (OffscreenSlidersCheck) CheckBeatmap(b *Beatmap) []*Issue {
+	if b.GameMode != OSU_GAMEMODE {
+		return nil
+	}
+
+	var issues []*Issue
+	radius := b.CircleRadius()
+	for _, hitObject := range b.HitObjects {
+		s, ok := hitObject.(*Slider)
+		if !ok {
+			continue
+		}
+		for _, p := range s.Path() {
+			if p.X-radius < visibleAreaLeft || p.X+radius > visibleAreaRight ||
+				p.Y-radius < visibleAreaTop || p.Y+radius > visibleAreaBottom {
+				issues = append(issues, newIssue(PROBLEM_SEVERITY, s.Time, s, "slider body goes offscreen"))
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// MissingMetadataCheck reports empty required metadata fields.
+type MissingMetadataCheck struct{}
+
+// Name returns name of the check.
+func (MissingMetadataCheck) Name() string { return "missing-metadata" }
+
+// CheckBeatmap runs the check over a beatmap.
+func (MissingMetadataCheck) CheckBeatmap(b *Beatmap) []*Issue {
+	var issues []*Issue
+	for _, field := range []struct {
+		name, value string
+	}{
+		{"AudioFilename", b.AudioFilename},
+		{"Title", b.Title},
+		{"Artist", b.Artist},
+		{"Creator", b.Creator},
+		{"Version", b.Version},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			issues = append(issues, newIssue(PROBLEM_SEVERITY, NO_TIME, nil, field.name+" is not set"))
+		}
+	}
+	return issues
+}
+
+// ComboColoursCheck reports combo colours which are too dark or too bright.
+type ComboColoursCheck struct{}
+
+// Name returns name of the check.
+func (ComboColoursCheck) Name() string { return "combo-colours" }
+
+// CheckBeatmap runs the check over a beatmap.
+func (ComboColoursCheck) CheckBeatmap(b *Beatmap) []*Issue {
+	if b.GameMode != OSU_GAMEMODE && b.GameMode != CTB_GAMEMODE {
+		return nil
+	}
+
+	var issues []*Issue
+	for i, colour := range b.ComboColours {
+		luminosity := colour.Luminosity()
+		if luminosity < MIN_COMBO_LUMINOSITY {
+			issues = append(issues, newIssue(WARNING_SEVERITY, NO_TIME, nil,
+				fmt.Sprintf("Combo%d (%v) is too dark, luminosity %.0f is below %d", i+1, colour, luminosity, MIN_COMBO_LUMINOSITY)))
+		}
+		if luminosity > MAX_COMBO_LUMINOSITY {
+			issues = append(issues, newIssue(WARNING_SEVERITY, NO_TIME, nil,
+				fmt.Sprintf("Combo%d (%v) is too bright, luminosity %.0f is above %d", i+1, colour, luminosity, MAX_COMBO_LUMINOSITY)))
+		}
+	}
+	return issues
+}
+
+// PreviewPointCheck reports beatmaps without preview point.
+type PreviewPointCheck struct{}
+
+// Name returns name of the check.
+func (PreviewPointCheck) Name() string { return "preview-point" }
+
+// CheckBeatmap runs the check over a beatmap.
+func (PreviewPointCheck) CheckBeatmap(b *Beatmap) []*Issue {
+	if b.PreviewTime > 0 {
+		return nil
+	}
+	return []*Issue{newIssue(WARNING_SEVERITY, NO_TIME, nil, "preview point is not set")}
+}
+
+// InconsistentMetadataCheck reports metadata which differs between difficulties of the mapset.
+type InconsistentMetadataCheck struct{}
+
+// Name returns name of the check.
+func (InconsistentMetadataCheck) Name() string { return "inconsistent-metadata" }
+
+// CheckMapset runs the check over a mapset.
+func (InconsistentMetadataCheck) CheckMapset(m *Mapset) []*Issue {
+	if len(m.Beatmaps) < 2 {
+		return nil
+	}
+
+	var issues []*Issue
+	for _, field := range []struct {
+		name     string
+		severity Severity
+		value    func(b *Beatmap) string
+	}{
+		{"Title", PROBLEM_SEVERITY, func(b *Beatmap) string { return b.Title }},
+		{"TitleUnicode", PROBLEM_SEVERITY, func(b *Beatmap) string { return b.TitleUnicode }},
+		{"Artist", PROBLEM_SEVERITY, func(b *Beatmap) string { return b.Artist }},
+		{"ArtistUnicode", PROBLEM_SEVERITY, func(b *Beatmap) string { return b.ArtistUnicode }},
+		{"Creator", PROBLEM_SEVERITY, func(b *Beatmap) string { return b.Creator }},
+		{"Source", PROBLEM_SEVERITY, func(b *Beatmap) string { return b.Source }},
+		{"Tags", WARNING_SEVERITY, func(b *Beatmap) string { return strings.Join(b.Tags, " ") }},
+		{"BeatmapSetID", PROBLEM_SEVERITY, func(b *Beatmap) string { return fmt.Sprint(b.BeatmapSetID) }},
+		{"AudioFilename", WARNING_SEVERITY, func(b *Beatmap) string { return b.AudioFilename }},
+		{"PreviewTime", WARNING_SEVERITY, func(b *Beatmap) string { return fmt.Sprint(b.PreviewTime) }},
+	} {
+		reference := field.value(m.Beatmaps[0])
+		for _, b := range m.Beatmaps[1:] {
+			if value := field.value(b); value != reference {
+				issue := newIssue(field.severity, NO_TIME, nil,
+					fmt.Sprintf("%s of %q is %q, but %q in %q", field.name, b.Version, value, reference, m.Beatmaps[0].Version))
+				issue.Beatmap = b
+				issues = append(issues, issue)
+			}
+		}
+	}
+	return issues
+}
+
+// FilesCheck reports files which beatmaps and the storyboard of the mapset refer to but
+// which do not exist in the mapset directory, and files which none of them refers to.
+type FilesCheck struct{}
+
+// Name returns name of the check.
+func (FilesCheck) Name() string { return "files" }
+
+// CheckMapset runs the check over a mapset.
+func (FilesCheck) CheckMapset(m *Mapset) []*Issue {
+	if m.DirectoryPath == "" {
+		return nil
+	}
+
+	files, err := m.Files()
+	if err != nil {
+		return []*Issue{newIssue(PROBLEM_SEVERITY, NO_TIME, nil, "failed to list mapset files: "+err.Error())}
+	}
+	existing := make(map[string]bool, len(files))
+	for _, f := range files {
+		existing[strings.ToLower(f)] = true
+	}
+
+	var issues []*Issue
+	used := make(map[string]bool)
+	// check reports missing files of a beatmap, or of the mapset storyboard if b is nil
+	check := func(b *Beatmap, refs []*fileReference) {
+		reported := make(map[string]bool)
+		for _, ref := range refs {
+			name := strings.ToLower(filepath.ToSlash(ref.filename))
+			used[name] = true
+			if !existing[name] && !reported[name] {
+				issue := newIssue(ref.severity, ref.time, nil, fmt.Sprintf("%s %q does not exist", ref.what, ref.filename))
+				issue.Beatmap = b
+				issues = append(issues, issue)
+				reported[name] = true
+			}
+		}
+	}
+	for _, b := range m.Beatmaps {
+		check(b, b.referencedFiles(m))
+	}
+	if m.Storyboard != nil {
+		check(nil, storyboardReferences(m.Storyboard.Events, m.Storyboard.Variables))
+	}
+
+	for _, f := range files {
+		name := strings.ToLower(f)
+		ext := filepath.Ext(name)
+		if used[name] || ext == ".osu" || ext == ".osb" {
+			continue
+		}
+		issues = append(issues, newIssue(INFO_SEVERITY, NO_TIME, nil, fmt.Sprintf("file %q is not used by any beatmap", f)))
+	}
+
+	return issues
+}
+
+// fileReference is a file which beatmap refers to.
+type fileReference struct {
+	filename string
+	what     string
+	severity Severity
+	time     int
+}
+
+// referencedFiles returns all files which beatmap refers to: audio, background, video,
+// files of its storyboard events, custom hit sound samples and custom filenames of hit
+// objects.
+func (b *Beatmap) referencedFiles(m *Mapset) []*fileReference {
+	var refs []*fileReference
+
+	if b.AudioFilename != "" {
+		refs = append(refs, &fileReference{b.AudioFilename, "audio file", PROBLEM_SEVERITY, NO_TIME})
+	}
+	if b.Background != nil && b.Background.FileName != "" {
+		refs = append(refs, &fileReference{b.Background.FileName, "background", PROBLEM_SEVERITY, NO_TIME})
+	}
+	if b.Video != nil && b.Video.FileName != "" {
+		refs = append(refs, &fileReference{b.Video.FileName, "video", WARNING_SEVERITY, b.Video.StartTime})
+	}
+	refs = append(refs, storyboardReferences(b.Storyboard, nil)...)
+	for _, s := range m.HitSamples(b) {
+		if !s.Skin {
+			refs = append(refs, &fileReference{s.Filename, "hit sound", WARNING_SEVERITY, s.Time})
+		}
+	}
+	return refs
+}
+
+// storyboardReferences returns files used by storyboard events.
+func storyboardReferences(events, variables []string) []*fileReference {
+	var refs []*fileReference
+	for _, filename := range storyboardFiles(events, variables) {
+		refs = append(refs, &fileReference{filename, "storyboard file", WARNING_SEVERITY, NO_TIME})
+	}
+	return refs
+}
+
+// Files returns paths of all files in the mapset directory relative to it,
+// with forward slashes as separators.
+func (m *Mapset) Files() ([]string, error) {
+	var files []string
+	err := filepath.Walk(m.DirectoryPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(m.DirectoryPath, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,147 @@
+package pcircle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func issuesOf(issues []*Issue, check string) []*Issue {
+	var found []*Issue
+	for _, issue := range issues {
+		if issue.Check == check {
+			found = append(found, issue)
+		}
+	}
+	return found
+}
+
+func TestLinter_LintBeatmap(t *testing.T) {
+	b := newTestBeatmap()
+	b.AudioFilename = "audio.mp3"
+	b.Title, b.Artist, b.Creator, b.Version = "Title", "Artist", "Creator", "Hard"
+	b.PreviewTime = -1
+	b.ComboColours = []*RGB{{10, 10, 10}, {255, 128, 0}}
+	b.Breaks = append(b.Breaks, &Break{StartTime: 12500, EndTime: 12900})
+	b.HitObjects = append(b.HitObjects,
+		&Circle{BaseHitObject{X: 100, Y: 100, Time: 7000, Extras: &Extras{}}},
+		&Circle{BaseHitObject{X: 100, Y: 100, Time: 11998, Extras: &Extras{}}},
+		&Slider{
+			BaseHitObject: BaseHitObject{X: 500, Y: 100, Time: 13000, Extras: &Extras{}},
+			SliderPath:    &SliderPath{SliderType: LINEAR_CURVE, CurvePoints: []*SliderCurvePoint{{700, 100}}},
+			Repeat:        1,
+			PixelLength:   70,
+		},
+	)
+	b.SortHitObjects()
+
+	issues := NewLinter().LintBeatmap(b)
+
+	tests := []struct {
+		check string
+		want  int
+	}{
+		{"unsnapped-objects", 1},
+		{"breaks", 2},
+		{"overlapping-objects", 1},
+		{"offscreen-sliders", 1},
+		{"missing-metadata", 0},
+		{"combo-colours", 1},
+		{"preview-point", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.check, func(t *testing.T) {
+			if got := issuesOf(issues, tt.check); len(got) != tt.want {
+				for _, issue := range got {
+					t.Logf("%v %v: %v", issue.Time, issue.Severity, issue.Message)
+				}
+				t.Errorf("Linter.LintBeatmap() found %v %v issues, want %v", len(got), tt.check, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinter_LintMapset(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"audio.mp3", "unused.png", "map.osu"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := newTestBeatmap()
+	first.AudioFilename, first.Title, first.Version = "audio.mp3", "Title", "Easy"
+	first.Background = &Background{FileName: "bg.jpg"}
+	second := first.Clone()
+	second.Title, second.Version = "Other Title", "Hard"
+
+	m := &Mapset{DirectoryPath: dir, Beatmaps: []*Beatmap{first, second}}
+	l := &Linter{MapsetChecks: []MapsetCheck{InconsistentMetadataCheck{}, FilesCheck{}}}
+	issues := l.LintMapset(m)
+
+	if got := issuesOf(issues, "inconsistent-metadata"); len(got) != 1 || got[0].Beatmap != second {
+		t.Errorf("Linter.LintMapset() inconsistent metadata issues = %v, want 1 for second beatmap", len(got))
+	}
+
+	files := issuesOf(issues, "files")
+	var missing, unused int
+	for _, issue := range files {
+		switch issue.Severity {
+		case PROBLEM_SEVERITY:
+			missing++
+		case INFO_SEVERITY:
+			unused++
+		}
+	}
+	if missing != 2 || unused != 1 {
+		t.Errorf("Linter.LintMapset() missing = %v, unused = %v, want 2 and 1", missing, unused)
+	}
+}
+
+func TestFilesCheck_CheckMapset(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"audio.mp3", "video.mp4"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := newTestBeatmap()
+	first.AudioFilename, first.Version = "audio.mp3", "Easy"
+	first.Video = &Video{FileName: "video.mp4"}
+	second := first.Clone()
+	second.Version = "Hard"
+	second.Video = &Video{StartTime: -200, FileName: "other.avi"}
+
+	issues := FilesCheck{}.CheckMapset(&Mapset{DirectoryPath: dir, Beatmaps: []*Beatmap{first, second}})
+	if len(issues) != 1 || issues[0].Beatmap != second || issues[0].Severity != WARNING_SEVERITY {
+		t.Fatalf("FilesCheck.CheckMapset() = %v, want missing video of second beatmap", issues)
+	}
+}
+
+func TestFilesCheck_CheckMapset_storyboard(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"audio.mp3", "star.png", "anim0.png", "anim1.png", "hit.wav"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := newTestBeatmap()
+	b.AudioFilename = "audio.mp3"
+	b.Storyboard = []string{`Sprite,Foreground,Centre,"star.png",320,240`, " F,0,1000,2000,0,1"}
+	sb := &Storyboard{
+		Variables: []string{`$anim="anim.png"`},
+		Events: []string{
+			"Animation,Background,Centre,$anim,320,240,2,100,LoopForever",
+			`Sample,1000,0,"HIT.wav",100`,
+			`Sprite,Background,Centre,"sb\missing.png",320,240`,
+		},
+	}
+
+	// files used only by storyboards are not unused
+	issues := FilesCheck{}.CheckMapset(&Mapset{DirectoryPath: dir, Beatmaps: []*Beatmap{b}, Storyboard: sb})
+	if len(issues) != 1 || issues[0].Beatmap != nil || issues[0].Message != `storyboard file "sb/missing.png" does not exist` {
+		t.Fatalf("FilesCheck.CheckMapset() = %v, want missing storyboard file", issues)
+	}
+}
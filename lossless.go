@@ -0,0 +1,375 @@
+package pcircle
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Kinds of list elements of .osu file.
+const (
+	BACKGROUND_ELEMENT   = "Background"
+	VIDEO_ELEMENT        = "Video"
+	BREAK_ELEMENT        = "Break"
+	STORYBOARD_ELEMENT   = "Storyboard"
+	TIMING_POINT_ELEMENT = "TimingPoint"
+	HIT_OBJECT_ELEMENT   = "HitObject"
+)
+
+// Kinds of list elements in each section, in canonical order.
+var sectionElements = map[string][]string{
+	"Events":       {BACKGROUND_ELEMENT, VIDEO_ELEMENT, BREAK_ELEMENT, STORYBOARD_ELEMENT},
+	"TimingPoints": {TIMING_POINT_ELEMENT},
+	"HitObjects":   {HIT_OBJECT_ELEMENT},
+}
+
+// Sections of .osu file in canonical order.
+var canonicalSections = []string{"General", "Editor", "Metadata", "Difficulty", "Events", "TimingPoints", "Colours", "HitObjects"}
+
+// The largest number of cells of the table used to align changed elements. Larger
+// changes are written as removal of all old elements and addition of new ones.
+const maxAlignCells = 1 << 24
+
+// eventKind returns kind of element of a line of Events section. Event types are given
+// by number or, in old versions, by name.
+func eventKind(line string) string {
+	switch {
+	case strings.HasPrefix(line, "Video,"), strings.HasPrefix(line, "1,"):
+		return VIDEO_ELEMENT
+	case strings.HasPrefix(line, "Break,"), strings.HasPrefix(line, "2,"):
+		return BREAK_ELEMENT
+	case strings.HasPrefix(line, "Background,"), strings.HasPrefix(line, "0,"):
+		return BACKGROUND_ELEMENT
+	}
+	return STORYBOARD_ELEMENT
+}
+
+// sourceKey returns key of a parsed line of specified section and whether the line is
+// an element of a list.
+func sourceKey(section, line string) (key string, element bool) {
+	switch section {
+	case "General", "Editor", "Metadata", "Difficulty", "Colours":
+		key, _ = tokenize(line)
+		return key, false
+	case "Events":
+		return eventKind(line), true
+	case "TimingPoints":
+		return TIMING_POINT_ELEMENT, true
+	case "HitObjects":
+		return HIT_OBJECT_ELEMENT, true
+	}
+	return "", false
+}
+
+// sourceLine is a line of .osu file parsed in lossless mode.
+type sourceLine struct {
+	raw     string
+	section string
+	key     string // Key of key-value lines or kind of list elements, empty for lines kept verbatim
+	element bool   // Whether the line is an element of a list, like a hit object
+	value   string // Canonical form of the line when it was parsed
+}
+
+// beatmapSource is the original text of a beatmap parsed in lossless mode.
+type beatmapSource struct {
+	lines        []*sourceLine
+	version      int                    // File format version of the original text
+	fields       map[string][][2]string // Canonical key-value lines when the beatmap was parsed
+	newline      string
+	finalNewline bool
+}
+
+// newBeatmapSource returns beatmapSource with line endings of specified file contents.
+func newBeatmapSource(data []byte) *beatmapSource {
+	s := &beatmapSource{newline: "\n", finalNewline: bytes.HasSuffix(data, []byte("\n"))}
+	if i := bytes.IndexByte(data, '\n'); i > 0 && data[i-1] == '\r' {
+		s.newline = "\r\n"
+	}
+	return s
+}
+
+// assignValues records canonical form of parsed lines. Lines of repeated keys and
+// elements, which are overridden by later ones, are kept verbatim.
+func (s *beatmapSource) assignValues(b *Beatmap) {
+	s.version = b.FileFormatVersion
+	fields := canonicalFields(b.canonicalString(s.version))
+	elements := b.elementLines(s.version)
+	s.fields = fields
+	seen := make(map[string]bool)
+	for i := len(s.lines) - 1; i >= 0; i-- {
+		l := s.lines[i]
+		switch {
+		case l.key == "":
+		case l.element:
+			es := elements[l.key]
+			if len(es) == 0 {
+				l.key = ""
+				continue
+			}
+			l.value, elements[l.key] = es[len(es)-1], es[:len(es)-1]
+		default:
+			value, ok := fieldLine(fields[l.section], l.key)
+			if !ok || seen[l.section+"."+l.key] {
+				l.key = ""
+				continue
+			}
+			seen[l.section+"."+l.key] = true
+			l.value = value
+		}
+	}
+}
+
+// render returns the original text with lines of changed values replaced by canonical
+// ones. Added elements are inserted next to the original elements of the same kind.
+// Fields missing in the original text are inserted at the end of their section only
+// if they were changed, sections where the canonical format has them.
+func (s *beatmapSource) render(b *Beatmap) string {
+	fields := canonicalFields(b.canonicalString(s.version))
+	elements := b.elementLines(s.version)
+
+	old := make(map[string][]string)
+	for _, l := range s.lines {
+		if l.element && l.key != "" {
+			old[l.key] = append(old[l.key], l.value)
+		}
+	}
+	kept := make(map[string][]bool)
+	before := make(map[string][][]string)
+	for _, kinds := range sectionElements {
+		for _, kind := range kinds {
+			kept[kind], before[kind] = alignElements(old[kind], elements[kind])
+		}
+	}
+
+	// sections of the original lines, where they start and end
+	sections := make([]string, len(s.lines))
+	starts, ends := make(map[string]int), make(map[string]int)
+	section := ""
+	for i, l := range s.lines {
+		line := strings.TrimSpace(l.raw)
+		if strings.HasPrefix(line, "[") && len(line) > 2 {
+			section = strings.TrimRight(strings.TrimLeft(line, "["), "]")
+			if _, ok := starts[section]; !ok {
+				starts[section] = i
+			}
+		}
+		sections[i] = section
+		if line != "" {
+			ends[section] = i + 1
+		}
+	}
+
+	// sections missing in the original are inserted before the next present one
+	missing := make(map[int][]string)
+	for i, section := range canonicalSections {
+		if _, ok := starts[section]; ok {
+			continue
+		}
+		at := len(s.lines)
+		for _, next := range canonicalSections[i+1:] {
+			if start, ok := starts[next]; ok {
+				at = start
+				break
+			}
+		}
+		missing[at] = append(missing[at], section)
+	}
+
+	var lines []string
+	written := make(map[string]bool)
+	next := make(map[string]int)
+	writeMissing := func(section string) []string {
+		var added []string
+		for _, f := range fields[section] {
+			if written[section+"."+f[0]] {
+				continue
+			}
+			written[section+"."+f[0]] = true
+			if parsed, ok := fieldLine(s.fields[section], f[0]); !ok || parsed != f[1] {
+				added = append(added, f[1])
+			}
+		}
+		for _, kind := range sectionElements[section] {
+			if len(old[kind]) == 0 {
+				added = append(added, before[kind][0]...)
+			}
+		}
+		return added
+	}
+	writeSections := func(at int) {
+		for _, section := range missing[at] {
+			if added := writeMissing(section); len(added) > 0 {
+				lines = append(lines, "["+section+"]")
+				lines = append(lines, added...)
+				lines = append(lines, "")
+			}
+		}
+	}
+
+	for i, l := range s.lines {
+		writeSections(i)
+		switch {
+		case l.element && l.key != "":
+			k := next[l.key]
+			next[l.key]++
+			lines = append(lines, before[l.key][k]...)
+			if kept[l.key][k] {
+				lines = append(lines, l.raw)
+			}
+			if k == len(old[l.key])-1 {
+				lines = append(lines, before[l.key][k+1]...)
+			}
+		case l.key != "":
+			written[l.section+"."+l.key] = true
+			current, ok := fieldLine(fields[l.section], l.key)
+			switch {
+			case !ok:
+				// removed, like bookmarks of a beatmap without them
+			case current == l.value:
+				lines = append(lines, l.raw)
+			default:
+				lines = append(lines, replaceValue(l.raw, current))
+			}
+		default:
+			lines = append(lines, l.raw)
+		}
+		if ends[sections[i]] == i+1 {
+			lines = append(lines, writeMissing(sections[i])...)
+		}
+	}
+	writeSections(len(s.lines))
+
+	text := strings.Join(lines, s.newline)
+	if s.finalNewline {
+		text += s.newline
+	}
+	return text
+}
+
+// canonicalFields returns keys and lines of key-value lines of canonical .osu text
+// by section.
+func canonicalFields(text string) map[string][][2]string {
+	fields := make(map[string][][2]string)
+	var section string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[]")
+			continue
+		}
+		switch section {
+		case "General", "Editor", "Metadata", "Difficulty", "Colours":
+			if strings.ContainsRune(line, ':') {
+				head, _ := tokenize(line)
+				fields[section] = append(fields[section], [2]string{head, line})
+			}
+		}
+	}
+	return fields
+}
+
+// fieldLine returns line of specified key among fields of a section.
+func fieldLine(fields [][2]string, key string) (string, bool) {
+	for _, f := range fields {
+		if f[0] == key {
+			return f[1], true
+		}
+	}
+	return "", false
+}
+
+// replaceValue returns key-value line raw with the value of line, keeping the key and
+// spacing around the separator of raw.
+func replaceValue(raw, line string) string {
+	sep := strings.IndexRune(raw, ':') + 1
+	for sep < len(raw) && (raw[sep] == ' ' || raw[sep] == '\t') {
+		sep++
+	}
+	_, value := tokenize(line)
+	return raw[:sep] + value
+}
+
+// elementLines returns canonical lines of list elements of Beatmap in specified file
+// format version by kind.
+func (b *Beatmap) elementLines(version int) map[string][]string {
+	b = b.forVersion(version)
+	elements := make(map[string][]string)
+	if b.Background != nil {
+		elements[BACKGROUND_ELEMENT] = []string{b.Background.String()}
+	}
+	if b.Video != nil {
+		elements[VIDEO_ELEMENT] = []string{b.Video.String()}
+	}
+	for _, br := range b.Breaks {
+		elements[BREAK_ELEMENT] = append(elements[BREAK_ELEMENT], br.String())
+	}
+	elements[STORYBOARD_ELEMENT] = append([]string(nil), b.Storyboard...)
+	for _, tp := range b.TimingPoints {
+		elements[TIMING_POINT_ELEMENT] = append(elements[TIMING_POINT_ELEMENT], timingPointLine(tp, version))
+	}
+	for _, hitObject := range b.HitObjects {
+		if ho, ok := hitObject.(fmt.Stringer); ok {
+			elements[HIT_OBJECT_ELEMENT] = append(elements[HIT_OBJECT_ELEMENT], ho.String())
+		}
+	}
+	return elements
+}
+
+// alignElements aligns old and new lines of elements by their longest common subsequence.
+// It returns whether each old element is kept and new elements inserted before each of
+// them, the last item being new elements inserted after all old ones.
+func alignElements(old, new []string) (kept []bool, before [][]string) {
+	kept = make([]bool, len(old))
+	before = make([][]string, len(old)+1)
+
+	// common prefix and suffix are kept without building the table
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		kept[prefix] = true
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix && old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		kept[len(old)-1-suffix] = true
+		suffix++
+	}
+	o, n := old[prefix:len(old)-suffix], new[prefix:len(new)-suffix]
+
+	var pairs [][2]int
+	if len(o)*len(n) <= maxAlignCells {
+		lcs := make([][]int32, len(o)+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, len(n)+1)
+		}
+		for i := len(o) - 1; i >= 0; i-- {
+			for j := len(n) - 1; j >= 0; j-- {
+				if o[i] == n[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		for i, j := 0, 0; i < len(o) && j < len(n); {
+			switch {
+			case o[i] == n[j]:
+				pairs = append(pairs, [2]int{i, j})
+				i, j = i+1, j+1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				i++
+			default:
+				j++
+			}
+		}
+	}
+
+	j := 0
+	for _, pair := range pairs {
+		at := prefix + pair[0]
+		before[at] = append(before[at], n[j:pair[1]]...)
+		kept[at] = true
+		j = pair[1] + 1
+	}
+	at := len(old) - suffix
+	before[at] = append(before[at], n[j:]...)
+	return kept, before
+}
@@ -0,0 +1,131 @@
+package pcircle
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const losslessTestBeatmap = `osu file format v14
+
+[General]
+AudioFilename: audio.mp3
+AudioLeadIn: 0
+PreviewTime: 3000
+Countdown: 0
+SampleSet: Soft
+StackLeniency: 0.70
+Mode: 0
+LetterboxInBreaks: 0
+AlwaysShowPlayfield: 0
+
+[Editor]
+// mapped on a train
+DistanceSpacing: 1.2
+BeatDivisor: 4
+GridSize: 8
+
+[Metadata]
+Title: Title
+Artist:Artist
+Creator:Mapper
+Version:Normal
+Tags:tag1  tag2
+
+[Difficulty]
+HPDrainRate:5.0
+CircleSize:4
+OverallDifficulty:8
+ApproachRate:9
+SliderMultiplier:1.40
+SliderTickRate:1
+
+[Events]
+//Background and Video events
+0,0,"bg.jpg",0,0
+//Break Periods
+2,6000,9000
+//Storyboard Layer 0 (Background)
+Sprite,Background,Centre,"sb.png",320,240
+ F,0,1000,2000,0,1
+
+[TimingPoints]
+0,500.000,4,2,0,60,1,0
+3000,-50.0,4,1,0,40,0,8
+
+[HitObjects]
+100,100,1500,5,0,0:0:0:0:
+200,100,2000,1,0,0:0:0:0:
+256,192,10000,12,0,12000,0:0:0:0:
+`
+
+// writeLosslessTestBeatmap writes test beatmap with CRLF line endings and parses it in
+// lossless mode.
+func writeLosslessTestBeatmap(t *testing.T) (*Beatmap, string) {
+	text := strings.ReplaceAll(losslessTestBeatmap, "\n", "\r\n")
+	path := filepath.Join(t.TempDir(), "beatmap.osu")
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := NewBeatmap()
+	if err := b.FromFileLossless(path); err != nil {
+		t.Fatalf("Beatmap.FromFileLossless() error = %v", err)
+	}
+	return b, text
+}
+
+func TestBeatmap_FromFileLossless(t *testing.T) {
+	b, text := writeLosslessTestBeatmap(t)
+	if got := b.String(); got != text {
+		t.Errorf("Beatmap.String() = %q, want %q", got, text)
+	}
+
+	path := filepath.Join(t.TempDir(), "written.osu")
+	if err := b.ToFile(path); err != nil {
+		t.Fatalf("Beatmap.ToFile() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != text {
+		t.Errorf("Beatmap.ToFile() = %q, want %q", data, text)
+	}
+
+	b.DiscardSource()
+	if got := b.String(); got != b.canonicalString(LATEST_FORMAT_VERSION) {
+		t.Errorf("Beatmap.String() after DiscardSource = %q, want canonical", got)
+	}
+}
+
+func TestBeatmap_FromFileLossless_edited(t *testing.T) {
+	b, text := writeLosslessTestBeatmap(t)
+
+	b.Title = "New Title"
+	b.SliderMultiplier = 1.6
+	b.Bookmarks = []int{1500}
+	b.ComboColours = []*RGB{{255, 0, 0}}
+	b.TimingPoints[1].Volume = 70
+	b.HitObjects = append(b.HitObjects[:1], b.HitObjects[2:]...)
+	b.HitObjects = append(b.HitObjects, &Circle{BaseHitObject{X: 300, Y: 300, Time: 5000, Type: CIRCLE, Extras: &Extras{}}})
+	b.SortHitObjects()
+
+	want := strings.NewReplacer(
+		"Title: Title", "Title: New Title",
+		"SliderMultiplier:1.40", "SliderMultiplier:1.6",
+		"GridSize: 8", "GridSize: 8\nBookmarks: 1500",
+		"3000,-50.0,4,1,0,40,0,8", "3000,-50,4,1,0,70,0,8",
+		"200,100,2000,1,0,0:0:0:0:\n", "300,300,5000,1,0,0:0:0:0:\n",
+		"[HitObjects]", "[Colours]\nCombo1 : 255,0,0\n\n[HitObjects]",
+	).Replace(losslessTestBeatmap)
+	want = strings.ReplaceAll(want, "\n", "\r\n")
+	if got := b.String(); got != want {
+		t.Errorf("Beatmap.String() = %q, want %q", got, want)
+	}
+
+	// unchanged beatmap of the same file is still written as is
+	if got := b.Clone(); got.source == nil {
+		t.Errorf("Beatmap.Clone() dropped source")
+	}
+	nb := NewBeatmap()
+	if err := nb.FromFileLossless(b.FilePath); err != nil || nb.String() != text {
+		t.Errorf("Beatmap.FromFileLossless() again = %v, %q", err, nb.String())
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"archive/zip"
 	"bytes"
 	"errors"
+	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 func NewMapset() *Mapset {
@@ -15,10 +18,11 @@ func NewMapset() *Mapset {
 
 // Mapset stores information about beatmaps, its location and other.
 type Mapset struct {
-	DirectoryPath string // The location of mapset directory, where located beatmaps.
+	DirectoryPath string `json:"directory_path,omitempty"` // The location of mapset directory, where located beatmaps.
 
-	Beatmaps     []*Beatmap // Unordered list of beatmaps
-	BeatmapSetID int        // The web ID of the beatmap set
+	Beatmaps     []*Beatmap  `json:"beatmaps"`             // Unordered list of beatmaps
+	BeatmapSetID int         `json:"beatmap_set_id"`       // The web ID of the beatmap set
+	Storyboard   *Storyboard `json:"storyboard,omitempty"` // Storyboard shared by all beatmaps (.osb file), nil if there is none
 }
 
 // FromDirectory scans provided (from structure) directory and loads .osu files into Mapset.
@@ -52,25 +56,41 @@ func (m *Mapset) FromDirectory(path string) (err error) {
 		m.BeatmapSetID = m.Beatmaps[0].BeatmapSetID
 	}
 
+	sbfiles, err := filepath.Glob(filepath.Join(path, "*.osb"))
+	if err != nil {
+		return err
+	}
+	if len(sbfiles) > 0 {
+		m.Storyboard = new(Storyboard)
+		if err := m.Storyboard.FromFile(sbfiles[0]); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // Compresses Mapset into .osz and returns its buffer.
 func (m Mapset) ToOSZ() (buf *bytes.Buffer, err error) {
+	buf = new(bytes.Buffer)
 	w := zip.NewWriter(buf)
 
 	err = filepath.Walk(m.DirectoryPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 		fp, err := filepath.Rel(m.DirectoryPath, path)
 		if err != nil {
 			return err
 		}
+		fp = filepath.ToSlash(fp)
 
 		if info.IsDir() {
-			_, err := w.Create(fp)
-			if err != nil {
-				return err
+			if fp == "." {
+				return nil
 			}
-			return nil
+			_, err := w.Create(fp + "/")
+			return err
 		}
 
 		realFileData, err := ioutil.ReadFile(path)
@@ -97,3 +117,70 @@ func (m Mapset) ToOSZ() (buf *bytes.Buffer, err error) {
 
 	return buf, nil
 }
+
+// ExtractOSZ extracts specified .osz archive into directory dir, which is created if needed.
+// Paths of archived files are cleaned, so they can not point outside of dir.
+func ExtractOSZ(osz, dir string) error {
+	r, err := zip.OpenReader(osz)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		name := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(f.Name, `\`, "/")), "/")
+		if name == "" {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := extractZipFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipFile writes contents of archived file to target path.
+func extractZipFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Save writes all beatmaps and the storyboard back to the files they were loaded from.
+func (m *Mapset) Save() error {
+	for _, b := range m.Beatmaps {
+		if b.FilePath == "" {
+			return errors.New("beatmap has no file path: " + b.Version)
+		}
+		if err := b.ToFile(b.FilePath); err != nil {
+			return err
+		}
+	}
+	if m.Storyboard != nil && m.Storyboard.FilePath != "" {
+		return m.Storyboard.ToFile(m.Storyboard.FilePath)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package pcircle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/gif"  // register gif decoder for image.DecodeConfig
+	_ "image/jpeg" // register jpeg decoder for image.DecodeConfig
+	_ "image/png"  // register png decoder for image.DecodeConfig
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MPEG audio bitrates in kbps indexed by bitrate index, for MPEG-1 and MPEG-2/2.5 Layer III.
+var (
+	mpeg1Layer3Bitrates = []int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mpeg2Layer3Bitrates = []int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+)
+
+// ImageSize returns dimensions of png, jpeg or gif image by reading its header.
+func ImageSize(path string) (width, height int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(bufio.NewReader(f))
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
+
+// AudioBitrate returns bitrate of mp3 or ogg file in kbps by reading its headers.
+// For mp3 files with a Xing, Info or VBRI header it is the average bitrate computed from
+// its frame and byte counts, otherwise the bitrate of the first frame. For ogg files it
+// is the nominal one.
+func AudioBitrate(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+
+	// headers are always in the beginning, except of large ID3 tags which we skip
+	head := make([]byte, 64*1024)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, err
+	}
+	head = head[:n]
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ogg":
+		return oggBitrate(head)
+	}
+
+	if len(head) >= 10 && string(head[:3]) == "ID3" {
+		tagSize := int64(int(head[6]&0x7f)<<21 | int(head[7]&0x7f)<<14 | int(head[8]&0x7f)<<7 | int(head[9]&0x7f))
+		if _, err := f.Seek(10+tagSize, io.SeekStart); err != nil {
+			return 0, err
+		}
+		size -= 10 + tagSize
+		n, err := io.ReadFull(f, head[:cap(head)])
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		head = head[:n]
+	}
+	return mp3Bitrate(head, size)
+}
+
+// mp3Bitrate finds the first MPEG Layer III frame header in audio data of specified size
+// and returns its bitrate, or the average bitrate if the frame is a VBR header.
+func mp3Bitrate(data []byte, size int64) (int, error) {
+	for i := 0; i+4 <= len(data); i++ {
+		if data[i] != 0xff || data[i+1]&0xe0 != 0xe0 {
+			continue
+		}
+		version := (data[i+1] >> 3) & 3 // 3 is MPEG-1, 2 is MPEG-2, 0 is MPEG-2.5
+		layer := (data[i+1] >> 1) & 3   // 1 is Layer III
+		index := data[i+2] >> 4
+		rateIndex := (data[i+2] >> 2) & 3
+		if version == 1 || layer != 1 || index == 0 || index == 15 || rateIndex == 3 {
+			continue
+		}
+		if bitrate, ok := mp3AverageBitrate(data[i:], size-int64(i)); ok {
+			return bitrate, nil
+		}
+		if version == 3 {
+			return mpeg1Layer3Bitrates[index], nil
+		}
+		return mpeg2Layer3Bitrates[index], nil
+	}
+	return 0, errors.New("no mp3 frame found")
+}
+
+// mp3AverageBitrate reads Xing, Info or VBRI header of the frame in the beginning of
+// audio data of specified size and returns average bitrate of the file in kbps.
+// Reports false if the frame has no such header.
+func mp3AverageBitrate(frame []byte, size int64) (int, bool) {
+	version := (frame[1] >> 3) & 3
+	mono := frame[3]>>6 == 3
+	sampleRate := []int{44100, 48000, 32000}[(frame[2]>>2)&3]
+	samples := 1152
+	sideInfo := 32
+	switch {
+	case version != 3 && mono:
+		sideInfo = 9
+	case version != 3 || mono:
+		sideInfo = 17
+	}
+	if version != 3 {
+		samples = 576
+		sampleRate /= 2
+		if version == 0 {
+			sampleRate /= 2
+		}
+	}
+
+	var frames, bytes int64
+	if i := 4 + sideInfo; len(frame) >= i+8 && (string(frame[i:i+4]) == "Xing" || string(frame[i:i+4]) == "Info") {
+		flags := binary.BigEndian.Uint32(frame[i+4:])
+		i += 8
+		if flags&1 != 0 && len(frame) >= i+4 {
+			frames = int64(binary.BigEndian.Uint32(frame[i:]))
+			i += 4
+		}
+		bytes = size
+		if flags&2 != 0 && len(frame) >= i+4 {
+			bytes = int64(binary.BigEndian.Uint32(frame[i:]))
+		}
+	} else if i := 4 + 32; len(frame) >= i+18 && string(frame[i:i+4]) == "VBRI" {
+		// version (2), delay (2) and quality (2) precede the counts
+		bytes = int64(binary.BigEndian.Uint32(frame[i+10:]))
+		frames = int64(binary.BigEndian.Uint32(frame[i+14:]))
+	}
+	if frames <= 0 || bytes <= 0 {
+		return 0, false
+	}
+
+	seconds := float64(frames) * float64(samples) / float64(sampleRate)
+	return int(math.Round(float64(bytes) * 8 / seconds / 1000)), true
+}
+
+// oggBitrate returns nominal bitrate from the Vorbis identification header.
+func oggBitrate(data []byte) (int, error) {
+	i := bytes.Index(data, []byte("\x01vorbis"))
+	if i < 0 || i+7+20 > len(data) {
+		return 0, errors.New("no vorbis identification header found")
+	}
+	header := data[i+7:]
+	// version (4), channels (1), sample rate (4), maximum (4), nominal (4) bitrates
+	nominal := int32(binary.LittleEndian.Uint32(header[13:17]))
+	if nominal <= 0 {
+		maximum := int32(binary.LittleEndian.Uint32(header[9:13]))
+		if maximum <= 0 {
+			return 0, errors.New("vorbis stream has no bitrate information")
+		}
+		nominal = maximum
+	}
+	return int(nominal) / 1000, nil
+}
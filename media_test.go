@@ -0,0 +1,47 @@
+package pcircle
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+)
+
+func TestAudioBitrate(t *testing.T) {
+	// MPEG-1 Layer III frame header of 128 kbps, 44100 Hz, stereo
+	frame := func(tag string, counts ...uint32) []byte {
+		data := make([]byte, 417)
+		copy(data, []byte{0xff, 0xfb, 0x90, 0x00})
+		copy(data[36:], tag)
+		for i, count := range counts {
+			binary.BigEndian.PutUint32(data[40+4*i:], count)
+		}
+		return data
+	}
+	vbri := frame("VBRI")
+	binary.BigEndian.PutUint32(vbri[46:], 626939)
+	binary.BigEndian.PutUint32(vbri[50:], 1000)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"CBR", append(frame(""), frame("")...), 128},
+		{"Xing", frame("Xing", 3, 1000, 626939), 192},
+		{"Info", append([]byte("ID3\x03\x00\x00\x00\x00\x00\x02xx"), frame("Info", 3, 1000, 261225)...), 80},
+		{"VBRI", vbri, 192},
+		{"Xing without counts", frame("Xing", 0), 128},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "audio.mp3", string(tt.data))
+			if got, err := AudioBitrate(path); err != nil || got != tt.want {
+				t.Errorf("AudioBitrate() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := AudioBitrate(filepath.Join(t.TempDir(), "missing.mp3")); err == nil {
+		t.Errorf("AudioBitrate() of missing file expected error")
+	}
+}
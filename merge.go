@@ -0,0 +1,304 @@
+package pcircle
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Sections of MergeConflict.
+const (
+	FIELDS_MERGE_SECTION        = "fields"
+	TIMING_POINTS_MERGE_SECTION = "timing_points"
+	HIT_OBJECTS_MERGE_SECTION   = "hit_objects"
+)
+
+// MergeConflict is an element of a beatmap which was changed differently on both sides
+// of a merge. The merged beatmap contains our version of the element.
+//
+// For fields Base, Ours and Theirs are values as in JSON encoding of Beatmap, for timing
+// points they are *TimingPoint and for hit objects they are hit objects. Missing elements
+// are nil.
+type MergeConflict struct {
+	Section string      // One of *_MERGE_SECTION constants
+	Field   string      // Name of the field as in JSON encoding, empty for timing points and hit objects
+	Time    int         // Time of the element, NO_TIME for fields
+	Base    interface{} // Version of the common ancestor
+	Ours    interface{}
+	Theirs  interface{}
+}
+
+// String returns MergeConflict in readable format.
+func (c *MergeConflict) String() string {
+	what := c.Field
+	switch c.Section {
+	case TIMING_POINTS_MERGE_SECTION:
+		what = "timing point " + EditorTimestamp(c.Time)
+	case HIT_OBJECTS_MERGE_SECTION:
+		what = "hit object " + EditorTimestamp(c.Time)
+	}
+	format := func(v interface{}) string {
+		if v == nil {
+			return "(none)"
+		}
+		if s, ok := v.(fmt.Stringer); ok {
+			return s.String()
+		}
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprintf("conflict in %s: base %s, ours %s, theirs %s", what, format(c.Base), format(c.Ours), format(c.Theirs))
+}
+
+// Merge merges changes made in ours and theirs versions of base beatmap.
+//
+// Fields, like metadata and difficulty settings, are merged one by one. Hit objects and
+// timing points are matched like in Diff and merged per element, so that changes of
+// different elements never conflict. Breaks are merged as sets. Elements changed
+// differently on both sides are reported as conflicts and our version is kept.
+// Hit objects and timing points of the result are sorted. If ours was parsed with
+// FromFileLossless, the result keeps its original text.
+func Merge(base, ours, theirs *Beatmap) (*Beatmap, []*MergeConflict, error) {
+	merged, conflicts, err := mergeFields(base, ours, theirs)
+	if err != nil {
+		return nil, nil, err
+	}
+	merged.FilePath = ours.FilePath
+	merged.source = ours.source
+
+	tps, tpConflicts := mergeElements(
+		timingPointElements(base.TimingPoints),
+		timingPointElements(ours.TimingPoints),
+		timingPointElements(theirs.TimingPoints),
+		matchTimingPoints,
+		func(e *diffElement) string { return e.key + "|" + strconv.Itoa(e.time) },
+	)
+	merged.TimingPoints = nil
+	for _, v := range tps {
+		merged.TimingPoints = append(merged.TimingPoints, copyTimingPoint(v.(*TimingPoint)))
+	}
+	merged.SortTimingPoints()
+	for _, c := range tpConflicts {
+		c.Section = TIMING_POINTS_MERGE_SECTION
+	}
+
+	hitObjects, hoConflicts := mergeElements(
+		hitObjectElements(base.HitObjects),
+		hitObjectElements(ours.HitObjects),
+		hitObjectElements(theirs.HitObjects),
+		matchHitObjects,
+		func(e *diffElement) string {
+			return fmt.Sprintf("%s|%d|%v|%v", e.key, e.time, BaseOf(e.value).X, BaseOf(e.value).Y)
+		},
+	)
+	merged.HitObjects = nil
+	for _, v := range hitObjects {
+		merged.HitObjects = append(merged.HitObjects, copyHitObject(v))
+	}
+	merged.SortHitObjects()
+	for _, c := range hoConflicts {
+		c.Section = HIT_OBJECTS_MERGE_SECTION
+	}
+
+	merged.Breaks = mergeBreaks(base.Breaks, ours.Breaks, theirs.Breaks)
+
+	conflicts = append(conflicts, tpConflicts...)
+	conflicts = append(conflicts, hoConflicts...)
+	return merged, conflicts, nil
+}
+
+// mergeFields merges fields of beatmaps one by one, using their JSON encoding.
+// Timing points, hit objects and breaks are taken from ours.
+func mergeFields(base, ours, theirs *Beatmap) (*Beatmap, []*MergeConflict, error) {
+	decode := func(b *Beatmap) (map[string]interface{}, error) {
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]interface{}
+		err = json.Unmarshal(data, &fields)
+		return fields, err
+	}
+	baseFields, err := decode(base)
+	if err != nil {
+		return nil, nil, err
+	}
+	ourFields, err := decode(ours)
+	if err != nil {
+		return nil, nil, err
+	}
+	theirFields, err := decode(theirs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make(map[string]bool)
+	for _, fields := range []map[string]interface{}{baseFields, ourFields, theirFields} {
+		for name := range fields {
+			names[name] = true
+		}
+	}
+	delete(names, "file_path")
+	delete(names, "timing_points")
+	delete(names, "hit_objects")
+	delete(names, "breaks")
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var conflicts []*MergeConflict
+	for _, name := range sorted {
+		b, o, t := baseFields[name], ourFields[name], theirFields[name]
+		switch {
+		case reflect.DeepEqual(o, t), reflect.DeepEqual(b, t):
+			// ours is kept
+		case reflect.DeepEqual(b, o):
+			setField(ourFields, name, t)
+		default:
+			conflicts = append(conflicts, &MergeConflict{
+				Section: FIELDS_MERGE_SECTION,
+				Field:   name,
+				Time:    NO_TIME,
+				Base:    b,
+				Ours:    o,
+				Theirs:  t,
+			})
+		}
+	}
+
+	data, err := json.Marshal(ourFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	merged := NewBeatmap()
+	if err := json.Unmarshal(data, merged); err != nil {
+		return nil, nil, err
+	}
+	return merged, conflicts, nil
+}
+
+// setField sets value of field, removing it if value is nil.
+func setField(fields map[string]interface{}, name string, value interface{}) {
+	if value == nil {
+		delete(fields, name)
+		return
+	}
+	fields[name] = value
+}
+
+// mergeElements merges matched elements of base, ours and theirs and returns values of
+// merged elements. Additions on both sides with the same slot, but different fields,
+// conflict.
+func mergeElements(base, ours, theirs []*diffElement, match func(old, new []*diffElement), slot func(*diffElement) string) ([]interface{}, []*MergeConflict) {
+	match(base, ours)
+	baseMatches := make([]*diffElement, len(base))
+	for i, e := range base {
+		baseMatches[i] = e.match
+		e.match = nil
+	}
+	match(base, theirs)
+
+	var merged []interface{}
+	var conflicts []*MergeConflict
+	value := func(e *diffElement) interface{} {
+		if e == nil {
+			return nil
+		}
+		return e.value
+	}
+	changed := func(b, e *diffElement) bool {
+		return e == nil || !b.equal(e)
+	}
+
+	for i, b := range base {
+		o, t := baseMatches[i], b.match
+		var result *diffElement
+		switch {
+		case !changed(b, t):
+			result = o
+		case !changed(b, o):
+			result = t
+		case o != nil && t != nil && o.equal(t), o == nil && t == nil:
+			result = o
+		default:
+			result = o
+			conflicts = append(conflicts, &MergeConflict{Time: b.time, Base: b.value, Ours: value(o), Theirs: value(t)})
+		}
+		if result != nil {
+			merged = append(merged, result.value)
+		}
+	}
+
+	// additions
+	ourAdditions := make(map[string][]*diffElement)
+	for _, o := range ours {
+		if o.match == nil {
+			ourAdditions[slot(o)] = append(ourAdditions[slot(o)], o)
+			merged = append(merged, o.value)
+		}
+	}
+	for _, t := range theirs {
+		if t.match != nil {
+			continue
+		}
+		added := ourAdditions[slot(t)]
+		duplicate := false
+		for _, o := range added {
+			duplicate = duplicate || o.equal(t)
+		}
+		switch {
+		case duplicate:
+		case len(added) > 0:
+			conflicts = append(conflicts, &MergeConflict{Time: t.time, Ours: added[0].value, Theirs: t.value})
+		default:
+			merged = append(merged, t.value)
+		}
+	}
+
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].Time < conflicts[j].Time
+	})
+	return merged, conflicts
+}
+
+// mergeBreaks merges breaks as sets: breaks removed on any side are removed and breaks
+// added on any side are added.
+func mergeBreaks(base, ours, theirs []*Break) []*Break {
+	contains := func(breaks []*Break, br *Break) bool {
+		for _, b := range breaks {
+			if *b == *br {
+				return true
+			}
+		}
+		return false
+	}
+
+	var merged []*Break
+	for _, br := range base {
+		if contains(ours, br) && contains(theirs, br) {
+			merged = append(merged, &Break{br.StartTime, br.EndTime})
+		}
+	}
+	for _, added := range [][]*Break{ours, theirs} {
+		for _, br := range added {
+			if !contains(base, br) && !contains(merged, br) {
+				merged = append(merged, &Break{br.StartTime, br.EndTime})
+			}
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].StartTime < merged[j].StartTime
+	})
+	return merged
+}
+
+// copyTimingPoint returns copy of timing point.
+func copyTimingPoint(tp *TimingPoint) *TimingPoint {
+	c := *tp
+	return &c
+}
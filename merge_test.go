@@ -0,0 +1,148 @@
+package pcircle
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	base := newTestBeatmap()
+	base.Title = "Title"
+
+	ours := base.Clone()
+	ours.Title = "New Title"
+	BaseOf(ours.HitObjects[0]).X = 120
+	ours.HitObjects = append(ours.HitObjects, &Circle{BaseHitObject{X: 300, Y: 300, Time: 13000, Type: CIRCLE, Extras: &Extras{}}})
+	ours.TimingPoints[1].Volume = 70
+
+	theirs := base.Clone()
+	theirs.ApproachRate = 9.5
+	theirs.HitObjects[1].(*Slider).EdgeHitSounds[0] = CLAP_HITSOUND
+	theirs.HitObjects = append([]interface{}{&Circle{BaseHitObject{X: 50, Y: 50, Time: 500, Type: CIRCLE, Extras: &Extras{}}}}, theirs.HitObjects...)
+	theirs.Breaks = nil
+
+	merged, conflicts, err := Merge(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("Merge() conflicts = %v, want none", conflicts)
+	}
+	if merged.Title != "New Title" || merged.ApproachRate != 9.5 {
+		t.Errorf("Merge() fields = %v, %v", merged.Title, merged.ApproachRate)
+	}
+	if merged.TimingPoints[1].Volume != 70 {
+		t.Errorf("Merge() timing point volume = %v, want 70", merged.TimingPoints[1].Volume)
+	}
+	if len(merged.Breaks) != 0 {
+		t.Errorf("Merge() breaks = %v, want none", merged.Breaks)
+	}
+
+	times := []int{500, 1500, 3000, 10000, 13000}
+	if len(merged.HitObjects) != len(times) {
+		t.Fatalf("Merge() hit objects = %v, want %v", len(merged.HitObjects), len(times))
+	}
+	for i, time := range times {
+		if got := BaseOf(merged.HitObjects[i]).Time; got != time {
+			t.Errorf("Merge() hit object %d time = %v, want %v", i, got, time)
+		}
+	}
+	if x := BaseOf(merged.HitObjects[1]).X; x != 120 {
+		t.Errorf("Merge() circle x = %v, want 120", x)
+	}
+	if hs := merged.HitObjects[2].(*Slider).EdgeHitSounds[0]; hs != CLAP_HITSOUND {
+		t.Errorf("Merge() slider edge hit sound = %v, want %v", hs, CLAP_HITSOUND)
+	}
+
+	// merged beatmap is written sorted and parsed back unchanged
+	path := filepath.Join(t.TempDir(), "merged.osu")
+	if err := merged.ToFile(path); err != nil {
+		t.Fatalf("Beatmap.ToFile() error = %v", err)
+	}
+	parsed := NewBeatmap()
+	if err := parsed.FromFile(path); err != nil {
+		t.Fatalf("Beatmap.FromFile() error = %v", err)
+	}
+	if d := Diff(merged, parsed); !d.Empty() {
+		t.Errorf("Merge() round trip diff = %v, want empty", d)
+	}
+}
+
+func TestMerge_conflicts(t *testing.T) {
+	base := newTestBeatmap()
+
+	ours := base.Clone()
+	ours.ApproachRate = 10
+	BaseOf(ours.HitObjects[0]).X = 120
+	ours.HitObjects = append(ours.HitObjects, &Circle{BaseHitObject{X: 300, Y: 300, Time: 13000, Type: CIRCLE, Extras: &Extras{}}})
+
+	theirs := base.Clone()
+	theirs.ApproachRate = 8
+	BaseOf(theirs.HitObjects[0]).X = 80
+	theirs.HitObjects = append(theirs.HitObjects, &Circle{BaseHitObject{X: 300, Y: 300, Time: 13000, Type: CIRCLE | NEW_COMBO, Extras: &Extras{}}})
+
+	merged, conflicts, err := Merge(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+
+	want := []struct {
+		section string
+		field   string
+		time    int
+	}{
+		{FIELDS_MERGE_SECTION, "approach_rate", NO_TIME},
+		{HIT_OBJECTS_MERGE_SECTION, "", 1500},
+		{HIT_OBJECTS_MERGE_SECTION, "", 13000},
+	}
+	if len(conflicts) != len(want) {
+		t.Fatalf("Merge() conflicts = %v, want %v", conflicts, len(want))
+	}
+	for i, w := range want {
+		c := conflicts[i]
+		if c.Section != w.section || c.Field != w.field || c.Time != w.time {
+			t.Errorf("Merge() conflict %d = %v, want %v %v %v", i, c, w.section, w.field, w.time)
+		}
+	}
+
+	// our version is kept
+	if merged.ApproachRate != 10 || BaseOf(merged.HitObjects[0]).X != 120 || len(merged.HitObjects) != 4 {
+		t.Errorf("Merge() = AR %v, x %v, %v hit objects", merged.ApproachRate, BaseOf(merged.HitObjects[0]).X, len(merged.HitObjects))
+	}
+}
+
+// largeMergeTestBeatmaps returns base of beatmap with specified number of hit objects, our
+// version with the first 100 hit objects moved in position and their version with hit
+// objects after 200000 ms shifted by 10 ms.
+func largeMergeTestBeatmaps(n int) (base, ours, theirs *Beatmap) {
+	base = newLargeTestBeatmap(n)
+	ours = base.Clone()
+	for _, hitObject := range ours.HitObjects[:100] {
+		BaseOf(hitObject).X++
+	}
+	theirs = base.Clone()
+	theirs.ShiftAfter(10, 200000)
+	return base, ours, theirs
+}
+
+func TestMerge_large(t *testing.T) {
+	base, ours, theirs := largeMergeTestBeatmaps(4000)
+	merged, conflicts, err := Merge(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if len(conflicts) != 0 || len(merged.HitObjects) != 4000 {
+		t.Fatalf("Merge() = %v hit objects, %v conflicts", len(merged.HitObjects), len(conflicts))
+	}
+	first, last := BaseOf(merged.HitObjects[0]), BaseOf(merged.HitObjects[3999])
+	if first.X != BaseOf(base.HitObjects[0]).X+1 || last.Time != BaseOf(base.HitObjects[3999]).Time+10 {
+		t.Errorf("Merge() first x = %v, last time = %v", first.X, last.Time)
+	}
+}
+
+func BenchmarkMerge(b *testing.B) {
+	base, ours, theirs := largeMergeTestBeatmaps(4000)
+	for i := 0; i < b.N; i++ {
+		Merge(base, ours, theirs)
+	}
+}
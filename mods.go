@@ -0,0 +1,346 @@
+package pcircle
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// Mods is a bitmap of gameplay modifiers as they are stored in replays and scores.
+type Mods int
+
+// All possible mods.
+const (
+	NO_MOD      Mods = 0
+	NO_FAIL_MOD Mods = 1 << (iota - 1)
+	EASY_MOD
+	TOUCH_DEVICE_MOD
+	HIDDEN_MOD
+	HARD_ROCK_MOD
+	SUDDEN_DEATH_MOD
+	DOUBLE_TIME_MOD
+	RELAX_MOD
+	HALF_TIME_MOD
+	NIGHTCORE_MOD
+	FLASHLIGHT_MOD
+	AUTOPLAY_MOD
+	SPUN_OUT_MOD
+	AUTOPILOT_MOD
+	PERFECT_MOD
+	KEY4_MOD
+	KEY5_MOD
+	KEY6_MOD
+	KEY7_MOD
+	KEY8_MOD
+	FADE_IN_MOD
+	RANDOM_MOD
+	CINEMA_MOD
+	TARGET_MOD
+	KEY9_MOD
+	KEY_COOP_MOD
+	KEY1_MOD
+	KEY3_MOD
+	KEY2_MOD
+	SCORE_V2_MOD
+	MIRROR_MOD
+)
+
+// Default speed rates of rate changing mods.
+const (
+	DOUBLE_TIME_RATE = 1.5
+	HALF_TIME_RATE   = 0.75
+)
+
+var modNames = []struct {
+	mod  Mods
+	name string
+}{
+	{NO_FAIL_MOD, "NF"},
+	{EASY_MOD, "EZ"},
+	{TOUCH_DEVICE_MOD, "TD"},
+	{HIDDEN_MOD, "HD"},
+	{HARD_ROCK_MOD, "HR"},
+	{SUDDEN_DEATH_MOD, "SD"},
+	{DOUBLE_TIME_MOD, "DT"},
+	{RELAX_MOD, "RX"},
+	{HALF_TIME_MOD, "HT"},
+	{NIGHTCORE_MOD, "NC"},
+	{FLASHLIGHT_MOD, "FL"},
+	{AUTOPLAY_MOD, "AT"},
+	{SPUN_OUT_MOD, "SO"},
+	{AUTOPILOT_MOD, "AP"},
+	{PERFECT_MOD, "PF"},
+	{KEY4_MOD, "4K"},
+	{KEY5_MOD, "5K"},
+	{KEY6_MOD, "6K"},
+	{KEY7_MOD, "7K"},
+	{KEY8_MOD, "8K"},
+	{FADE_IN_MOD, "FI"},
+	{RANDOM_MOD, "RD"},
+	{CINEMA_MOD, "CN"},
+	{TARGET_MOD, "TP"},
+	{KEY9_MOD, "9K"},
+	{KEY_COOP_MOD, "CO"},
+	{KEY1_MOD, "1K"},
+	{KEY3_MOD, "3K"},
+	{KEY2_MOD, "2K"},
+	{SCORE_V2_MOD, "V2"},
+	{MIRROR_MOD, "MR"},
+}
+
+// Has reports whether all of specified mods are enabled.
+func (m Mods) Has(mods Mods) bool {
+	return m&mods == mods
+}
+
+// Rate returns default speed rate of the mods (1.5 for DT/NC, 0.75 for HT).
+func (m Mods) Rate() float64 {
+	if m&(DOUBLE_TIME_MOD|NIGHTCORE_MOD) > 0 {
+		return DOUBLE_TIME_RATE
+	}
+	if m&HALF_TIME_MOD > 0 {
+		return HALF_TIME_RATE
+	}
+	return 1
+}
+
+// String returns string of Mods in readable format, like "HDDT".
+// NC and PF hide DT and SD they always come with.
+func (m Mods) String() string {
+	if m == NO_MOD {
+		return "NM"
+	}
+	if m.Has(NIGHTCORE_MOD) {
+		m &^= DOUBLE_TIME_MOD
+	}
+	if m.Has(PERFECT_MOD) {
+		m &^= SUDDEN_DEATH_MOD
+	}
+
+	var sb strings.Builder
+	for _, mn := range modNames {
+		if m&mn.mod > 0 {
+			sb.WriteString(mn.name)
+		}
+	}
+	return sb.String()
+}
+
+// FromString allows you to set mods with strings of two-letter acronyms, like "HDDT".
+func (m *Mods) FromString(str string) error {
+	str = strings.ToUpper(strings.TrimSpace(str))
+	if len(str)%2 != 0 {
+		return errors.New("invalid mods string: " + str)
+	}
+
+	var mods Mods
+	for i := 0; i < len(str); i += 2 {
+		acronym := str[i : i+2]
+		if acronym == "NM" {
+			continue
+		}
+
+		found := false
+		for _, mn := range modNames {
+			if mn.name == acronym {
+				mods |= mn.mod
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("invalid mod identifier: " + acronym)
+		}
+	}
+
+	if mods.Has(NIGHTCORE_MOD) {
+		mods |= DOUBLE_TIME_MOD
+	}
+	if mods.Has(PERFECT_MOD) {
+		mods |= SUDDEN_DEATH_MOD
+	}
+	*m = mods
+	return nil
+}
+
+// DifficultyAdjust holds custom difficulty values of the Difficulty Adjust mod.
+// Nil fields are left unchanged.
+type DifficultyAdjust struct {
+	HPDrainRate       *float64
+	CircleSize        *float64
+	OverallDifficulty *float64
+	ApproachRate      *float64
+}
+
+// ModSettings specifies how mods should be applied to a beatmap.
+type ModSettings struct {
+	Mods Mods
+
+	// Custom speed rate. Zero means the default rate of the enabled mods.
+	Rate float64
+
+	// Custom difficulty values. Can not be combined with HR or EZ.
+	DifficultyAdjust *DifficultyAdjust
+}
+
+// rate returns speed rate which should be applied with these settings.
+func (s ModSettings) rate() float64 {
+	if s.Rate > 0 {
+		return s.Rate
+	}
+	return s.Mods.Rate()
+}
+
+// ApplyMods returns new Beatmap with specified mods applied.
+// Original beatmap is left untouched.
+func (b *Beatmap) ApplyMods(mods Mods) (*Beatmap, error) {
+	return b.ApplyModSettings(ModSettings{Mods: mods})
+}
+
+// ApplyModSettings returns new Beatmap with mods applied according to specified settings.
+//
+// Difficulty changing mods (HR, EZ, Difficulty Adjust) change difficulty values,
+// HR also flips objects vertically in osu!standard. In osu!mania HR and EZ scale hit
+// windows, which OverallDifficulty can not express, so it is kept. Rate changing mods (DT, NC, HT)
+// rescale all time values and set ApproachRate and OverallDifficulty to effective
+// values, so the result plays at normal speed. Mirror flips the playfield horizontally
+// or reverses columns in osu!mania.
+func (b *Beatmap) ApplyModSettings(settings ModSettings) (*Beatmap, error) {
+	mods := settings.Mods
+	if mods.Has(HARD_ROCK_MOD | EASY_MOD) {
+		return nil, errors.New("incompatible mods: HR and EZ")
+	}
+	if mods&(DOUBLE_TIME_MOD|NIGHTCORE_MOD) > 0 && mods.Has(HALF_TIME_MOD) {
+		return nil, errors.New("incompatible mods: DT and HT")
+	}
+	if settings.DifficultyAdjust != nil && mods&(HARD_ROCK_MOD|EASY_MOD) > 0 {
+		return nil, errors.New("incompatible mods: Difficulty Adjust and " + (mods & (HARD_ROCK_MOD | EASY_MOD)).String())
+	}
+	rate := settings.rate()
+	if rate <= 0 {
+		return nil, errors.New("invalid rate")
+	}
+
+	nb := b.Clone()
+
+	switch {
+	case mods.Has(HARD_ROCK_MOD):
+		nb.scaleDifficulty(1.3, 1.4)
+		if nb.GameMode == OSU_GAMEMODE {
+			nb.flipVertically()
+		}
+	case mods.Has(EASY_MOD):
+		nb.scaleDifficulty(0.5, 0.5)
+	case settings.DifficultyAdjust != nil:
+		da := settings.DifficultyAdjust
+		if da.HPDrainRate != nil {
+			nb.HPDrainRate = *da.HPDrainRate
+		}
+		if da.CircleSize != nil {
+			nb.CircleSize = *da.CircleSize
+		}
+		if da.OverallDifficulty != nil {
+			nb.OverallDifficulty = *da.OverallDifficulty
+		}
+		if da.ApproachRate != nil {
+			nb.ApproachRate = *da.ApproachRate
+		}
+	}
+
+	if mods.Has(MIRROR_MOD) {
+		nb.mirror()
+	}
+
+	if rate != 1 {
+		nb.scaleTime(rate)
+		nb.ApproachRate = preemptToApproachRate(approachRateToPreempt(nb.ApproachRate) / rate)
+		if nb.GameMode != MANIA_GAMEMODE {
+			nb.OverallDifficulty = rateAdjustedOverallDifficulty(nb.GameMode, nb.OverallDifficulty, rate)
+		}
+	}
+
+	return nb, nil
+}
+
+// scaleDifficulty multiplies difficulty values, capping them at 10.
+// CircleSize is left untouched in osu!mania where it is the number of columns, and so
+// is OverallDifficulty, as Hard Rock and Easy scale osu!mania hit windows instead.
+func (b *Beatmap) scaleDifficulty(csMultiplier, multiplier float64) {
+	if b.GameMode != MANIA_GAMEMODE {
+		b.CircleSize = math.Min(b.CircleSize*csMultiplier, 10)
+		b.OverallDifficulty = math.Min(b.OverallDifficulty*multiplier, 10)
+	}
+	b.ApproachRate = math.Min(b.ApproachRate*multiplier, 10)
+	b.HPDrainRate = math.Min(b.HPDrainRate*multiplier, 10)
+}
+
+// flipVertically flips all hit objects and slider curves over the horizontal axis of the playfield.
+func (b *Beatmap) flipVertically() {
+	for _, hitObject := range b.HitObjects {
+		base := BaseOf(hitObject)
+		base.Y = PLAYFIELD_HEIGHT - base.Y
+		if s, ok := hitObject.(*Slider); ok && s.SliderPath != nil {
+			for _, p := range s.SliderPath.CurvePoints {
+				p.Y = PLAYFIELD_HEIGHT - p.Y
+			}
+		}
+	}
+}
+
+// mirror flips all hit objects and slider curves over the vertical axis of the playfield.
+// In osu!mania the order of columns is reversed instead.
+func (b *Beatmap) mirror() {
+	if b.GameMode == MANIA_GAMEMODE {
+		keys := int(b.CircleSize)
+		for _, hitObject := range b.HitObjects {
+			base := BaseOf(hitObject)
+			base.X = ManiaColumnX(keys-1-ManiaColumn(base.X, keys), keys)
+		}
+		return
+	}
+
+	for _, hitObject := range b.HitObjects {
+		base := BaseOf(hitObject)
+		base.X = PLAYFIELD_WIDTH - base.X
+		if s, ok := hitObject.(*Slider); ok && s.SliderPath != nil {
+			for _, p := range s.SliderPath.CurvePoints {
+				p.X = PLAYFIELD_WIDTH - p.X
+			}
+		}
+	}
+}
+
+// scaleTime speeds beatmap up by specified rate: all time values are divided by it.
+func (b *Beatmap) scaleTime(rate float64) {
+	scale := func(time int) int {
+		return int(math.Round(float64(time) / rate))
+	}
+
+	b.AudioLeadIn = scale(b.AudioLeadIn)
+	if b.PreviewTime > 0 {
+		b.PreviewTime = scale(b.PreviewTime)
+	}
+	for i := range b.Bookmarks {
+		b.Bookmarks[i] = scale(b.Bookmarks[i])
+	}
+	for _, br := range b.Breaks {
+		br.StartTime = scale(br.StartTime)
+		br.EndTime = scale(br.EndTime)
+	}
+	for _, tp := range b.TimingPoints {
+		tp.Offset = scale(tp.Offset)
+		if tp.Inherited {
+			tp.MillisecondsPerBeat /= rate
+		}
+	}
+	for _, hitObject := range b.HitObjects {
+		base := BaseOf(hitObject)
+		base.Time = scale(base.Time)
+		switch o := hitObject.(type) {
+		case *Spinner:
+			o.EndTime = scale(o.EndTime)
+		case *ManiaHoldNote:
+			o.EndTime = scale(o.EndTime)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package pcircle
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMods_String(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Mods
+		want string
+	}{
+		{
+			name: "No Mod",
+			m:    NO_MOD,
+			want: "NM",
+		},
+		{
+			name: "HDDT",
+			m:    HIDDEN_MOD | DOUBLE_TIME_MOD,
+			want: "HDDT",
+		},
+		{
+			name: "Nightcore hides DoubleTime",
+			m:    NIGHTCORE_MOD | DOUBLE_TIME_MOD | HARD_ROCK_MOD,
+			want: "HRNC",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.String(); got != tt.want {
+				t.Errorf("Mods.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMods_FromString(t *testing.T) {
+	tests := []struct {
+		name    string
+		str     string
+		want    Mods
+		wantErr bool
+	}{
+		{
+			name: "HDHR",
+			str:  "hdhr",
+			want: HIDDEN_MOD | HARD_ROCK_MOD,
+		},
+		{
+			name: "Nightcore implies DoubleTime",
+			str:  "NC",
+			want: NIGHTCORE_MOD | DOUBLE_TIME_MOD,
+		},
+		{
+			name:    "invalid acronym",
+			str:     "XX",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Mods
+			err := m.FromString(tt.str)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Mods.FromString() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && m != tt.want {
+				t.Errorf("Mods.FromString() = %v, want %v", m, tt.want)
+			}
+		})
+	}
+}
+
+func newTestBeatmap() *Beatmap {
+	return &Beatmap{
+		FileFormatVersion: 14,
+		HPDrainRate:       5,
+		CircleSize:        4,
+		OverallDifficulty: 8,
+		ApproachRate:      9,
+		SliderMultiplier:  1.4,
+		SliderTickRate:    1,
+		PreviewTime:       3000,
+		Bookmarks:         []int{1500},
+		Breaks:            []*Break{{StartTime: 6000, EndTime: 9000}},
+		TimingPoints: []*TimingPoint{
+			{Offset: 0, MillisecondsPerBeat: 500, Meter: 4, SampleSet: SOFT_SAMPLESET, Volume: 60, Inherited: true},
+			{Offset: 3000, MillisecondsPerBeat: -50, Meter: 4, SampleSet: NORMAL_SAMPLESET, Volume: 40},
+		},
+		HitObjects: []interface{}{
+			&Circle{BaseHitObject{X: 100, Y: 100, Time: 1500, Type: CIRCLE | NEW_COMBO, Extras: &Extras{}}},
+			&Slider{
+				BaseHitObject: BaseHitObject{X: 200, Y: 50, Time: 3000, Type: SLIDER, Extras: &Extras{}},
+				SliderPath:    &SliderPath{SliderType: "L", CurvePoints: []*SliderCurvePoint{{300, 50}}},
+				Repeat:        1,
+				PixelLength:   140,
+				EdgeHitSounds: []HitSound{NO_HITSOUND, NO_HITSOUND},
+				EdgeAdditions: []*SliderEdgeAddition{{}, {}},
+			},
+			&Spinner{BaseHitObject: BaseHitObject{X: 256, Y: 192, Time: 10000, Type: SPINNER | NEW_COMBO}, EndTime: 12000},
+		},
+	}
+}
+
+func TestBeatmap_ApplyMods(t *testing.T) {
+	b := newTestBeatmap()
+
+	hr, err := b.ApplyMods(HARD_ROCK_MOD)
+	if err != nil {
+		t.Fatalf("Beatmap.ApplyMods() error = %v", err)
+	}
+	if hr.CircleSize != 5.2 || hr.ApproachRate != 10 || hr.HPDrainRate != 7 {
+		t.Errorf("Beatmap.ApplyMods(HR) difficulty = CS %v AR %v HP %v", hr.CircleSize, hr.ApproachRate, hr.HPDrainRate)
+	}
+	if got := BaseOf(hr.HitObjects[0]).Y; got != 284 {
+		t.Errorf("Beatmap.ApplyMods(HR) circle Y = %v, want 284", got)
+	}
+	if got := hr.HitObjects[1].(*Slider).SliderPath.CurvePoints[0].Y; got != 334 {
+		t.Errorf("Beatmap.ApplyMods(HR) curve point Y = %v, want 334", got)
+	}
+	if got := BaseOf(b.HitObjects[0]).Y; got != 100 {
+		t.Errorf("Beatmap.ApplyMods(HR) modified original beatmap, Y = %v", got)
+	}
+
+	dt, err := b.ApplyMods(DOUBLE_TIME_MOD)
+	if err != nil {
+		t.Fatalf("Beatmap.ApplyMods() error = %v", err)
+	}
+	if got := BaseOf(dt.HitObjects[0]).Time; got != 1000 {
+		t.Errorf("Beatmap.ApplyMods(DT) time = %v, want 1000", got)
+	}
+	if got := dt.HitObjects[2].(*Spinner).EndTime; got != 8000 {
+		t.Errorf("Beatmap.ApplyMods(DT) spinner end time = %v, want 8000", got)
+	}
+	if math.Abs(dt.ApproachRate-10.33333) > 1e-4 {
+		t.Errorf("Beatmap.ApplyMods(DT) AR = %v, want 10.33", dt.ApproachRate)
+	}
+	if got := dt.TimingPoints[0].MillisecondsPerBeat; math.Abs(got-333.33333) > 1e-4 {
+		t.Errorf("Beatmap.ApplyMods(DT) beat length = %v, want 333.33", got)
+	}
+
+	if _, err := b.ApplyMods(HARD_ROCK_MOD | EASY_MOD); err == nil {
+		t.Errorf("Beatmap.ApplyMods(HREZ) expected error")
+	}
+}
+
+func TestBeatmap_ApplyMods_hitWindows(t *testing.T) {
+	for _, mode := range []int{OSU_GAMEMODE, TAIKO_GAMEMODE, MANIA_GAMEMODE} {
+		for _, mods := range []Mods{HARD_ROCK_MOD, EASY_MOD} {
+			b := newTestBeatmap()
+			b.GameMode = mode
+			nb, err := b.ApplyMods(mods)
+			if err != nil {
+				t.Fatalf("Beatmap.ApplyMods() error = %v", err)
+			}
+
+			// osu!mania windows are scaled by the mods, which the exported beatmap
+			// can not express, so it keeps them only when played with the mods
+			played := NO_MOD
+			if mode == MANIA_GAMEMODE {
+				played = mods
+			}
+			if got, want := *nb.HitWindows(played, 0), *b.HitWindows(mods, 0); got != want {
+				t.Errorf("Beatmap.ApplyMods(%v) in mode %v hit windows = %+v, want %+v", mods, mode, got, want)
+			}
+		}
+	}
+}
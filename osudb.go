@@ -0,0 +1,385 @@
+package pcircle
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Versions of osu!.db which changed its layout.
+const (
+	OSU_DB_FLOAT_DIFFICULTY_VERSION  = 20140609 // AR, CS, HP and OD are stored as floats, star ratings are added
+	OSU_DB_NO_ENTRY_SIZE_VERSION     = 20191106 // Size of beatmap entries is not stored anymore
+	OSU_DB_FLOAT_STAR_RATING_VERSION = 20250108 // Star ratings are stored as floats instead of doubles
+)
+
+// Markers of values in int-double and int-float pairs of osu!.db.
+const (
+	dbIntMarker    = 0x08
+	dbFloatMarker  = 0x0c
+	dbDoubleMarker = 0x0d
+)
+
+// RankedStatus is a ranked status of a beatmap stored in osu!.db.
+type RankedStatus byte
+
+// All possible ranked statuses.
+const (
+	UNKNOWN_STATUS     RankedStatus = 0
+	UNSUBMITTED_STATUS RankedStatus = 1
+	PENDING_STATUS     RankedStatus = 2 // Pending, WIP or graveyard
+	RANKED_STATUS      RankedStatus = 4
+	APPROVED_STATUS    RankedStatus = 5
+	QUALIFIED_STATUS   RankedStatus = 6
+	LOVED_STATUS       RankedStatus = 7
+)
+
+// String returns string of RankedStatus in readable format.
+func (s RankedStatus) String() string {
+	return map[RankedStatus]string{
+		UNKNOWN_STATUS:     "unknown",
+		UNSUBMITTED_STATUS: "unsubmitted",
+		PENDING_STATUS:     "pending",
+		RANKED_STATUS:      "ranked",
+		APPROVED_STATUS:    "approved",
+		QUALIFIED_STATUS:   "qualified",
+		LOVED_STATUS:       "loved",
+	}[s]
+}
+
+// Grade is a grade achieved on a beatmap.
+type Grade byte
+
+// All possible grades.
+const (
+	XH_GRADE Grade = iota // Silver SS
+	SH_GRADE              // Silver S
+	X_GRADE               // SS
+	S_GRADE
+	A_GRADE
+	B_GRADE
+	C_GRADE
+	D_GRADE
+	F_GRADE
+	NO_GRADE // Beatmap was not played
+)
+
+// String returns string of Grade in readable format.
+func (g Grade) String() string {
+	return map[Grade]string{
+		XH_GRADE: "SSH",
+		SH_GRADE: "SH",
+		X_GRADE:  "SS",
+		S_GRADE:  "S",
+		A_GRADE:  "A",
+		B_GRADE:  "B",
+		C_GRADE:  "C",
+		D_GRADE:  "D",
+		F_GRADE:  "F",
+	}[g]
+}
+
+// NewOsuDB returns a new empty OsuDB.
+func NewOsuDB() *OsuDB {
+	return new(OsuDB)
+}
+
+// OsuDB stores contents of osu!.db, the beatmap database of osu! client.
+type OsuDB struct {
+	Version         int // Version of the client which wrote the file, like 20250108
+	FolderCount     int
+	AccountUnlocked bool
+	UnlockDate      time.Time // When the account will be unlocked
+	PlayerName      string
+	Beatmaps        []*OsuDBBeatmap
+	Permissions     int // User permissions bit flags
+}
+
+// OsuDBBeatmap is a single beatmap entry of osu!.db.
+type OsuDBBeatmap struct {
+	Artist        string
+	ArtistUnicode string
+	Title         string
+	TitleUnicode  string
+	Creator       string
+	Version       string // Difficulty name
+	AudioFilename string
+	MD5           string // MD5 hash of the .osu file
+	FileName      string // Name of the .osu file in the beatmap folder
+	RankedStatus  RankedStatus
+
+	HitCircles int
+	Sliders    int
+	Spinners   int
+
+	LastModified time.Time
+
+	ApproachRate      float64
+	CircleSize        float64
+	HPDrainRate       float64
+	OverallDifficulty float64
+	SliderMultiplier  float64
+
+	// Star ratings with mod combinations, indexed by game mode.
+	// Empty for files older than OSU_DB_FLOAT_DIFFICULTY_VERSION.
+	StarRatings [4]map[Mods]float64
+
+	DrainTime   int // Seconds
+	TotalTime   int // Milliseconds
+	PreviewTime int // Milliseconds
+
+	// Only offset, beat length and whether timing point is a red line are stored in the database.
+	TimingPoints []*TimingPoint
+
+	BeatmapID    int
+	BeatmapSetID int
+	ThreadID     int
+
+	Grades [4]Grade // Grades achieved in each game mode
+
+	LocalOffset   int
+	StackLeniency float64
+	GameMode      int
+	Source        string
+	Tags          []string
+	OnlineOffset  int
+	TitleFont     string
+	Unplayed      bool
+	LastPlayed    time.Time
+	IsOsz2        bool
+	FolderName    string // Name of the beatmap folder relative to the Songs directory
+	LastChecked   time.Time
+
+	IgnoreBeatmapSounds bool
+	IgnoreBeatmapSkin   bool
+	DisableStoryboard   bool
+	DisableVideo        bool
+	VisualOverride      bool
+
+	ManiaScrollSpeed int
+}
+
+// FromFile parses specified osu!.db file and fills OsuDB with data.
+func (db *OsuDB) FromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return db.FromReader(f)
+}
+
+// FromReader parses osu!.db from r and fills OsuDB with data.
+func (db *OsuDB) FromReader(r io.Reader) error {
+	dr := newDBReader(r)
+
+	db.Version = dr.int()
+	db.FolderCount = dr.int()
+	db.AccountUnlocked = dr.bool()
+	db.UnlockDate = dr.dateTime()
+	db.PlayerName = dr.string()
+
+	count := dr.int()
+	if dr.err != nil {
+		return dr.err
+	}
+	if count < 0 {
+		return errors.New("invalid number of beatmaps")
+	}
+
+	db.Beatmaps = make([]*OsuDBBeatmap, 0, count)
+	for i := 0; i < count; i++ {
+		b := new(OsuDBBeatmap)
+		b.read(dr, db.Version)
+		if dr.err != nil {
+			return dr.err
+		}
+		db.Beatmaps = append(db.Beatmaps, b)
+	}
+
+	db.Permissions = dr.int()
+	return dr.err
+}
+
+// read reads beatmap entry of specified osu!.db version.
+func (b *OsuDBBeatmap) read(r *dbReader, version int) {
+	if version < OSU_DB_NO_ENTRY_SIZE_VERSION {
+		r.int() // size of the entry in bytes
+	}
+
+	b.Artist = r.string()
+	b.ArtistUnicode = r.string()
+	b.Title = r.string()
+	b.TitleUnicode = r.string()
+	b.Creator = r.string()
+	b.Version = r.string()
+	b.AudioFilename = r.string()
+	b.MD5 = r.string()
+	b.FileName = r.string()
+	b.RankedStatus = RankedStatus(r.byte())
+	b.HitCircles = r.short()
+	b.Sliders = r.short()
+	b.Spinners = r.short()
+	b.LastModified = r.dateTime()
+
+	if version < OSU_DB_FLOAT_DIFFICULTY_VERSION {
+		b.ApproachRate = float64(r.byte())
+		b.CircleSize = float64(r.byte())
+		b.HPDrainRate = float64(r.byte())
+		b.OverallDifficulty = float64(r.byte())
+	} else {
+		b.ApproachRate = single2double(r.single())
+		b.CircleSize = single2double(r.single())
+		b.HPDrainRate = single2double(r.single())
+		b.OverallDifficulty = single2double(r.single())
+	}
+	b.SliderMultiplier = r.double()
+
+	if version >= OSU_DB_FLOAT_DIFFICULTY_VERSION {
+		for mode := range b.StarRatings {
+			b.StarRatings[mode] = readStarRatings(r, version)
+		}
+	}
+
+	b.DrainTime = r.int()
+	b.TotalTime = r.int()
+	b.PreviewTime = r.int()
+
+	count := r.int()
+	if count < 0 && r.err == nil {
+		r.err = errors.New("invalid number of timing points")
+	}
+	for i := 0; i < count && r.err == nil; i++ {
+		tp := &TimingPoint{MillisecondsPerBeat: r.double()}
+		tp.Offset = int(r.double())
+		tp.Inherited = r.bool()
+		b.TimingPoints = append(b.TimingPoints, tp)
+	}
+
+	b.BeatmapID = r.int()
+	b.BeatmapSetID = r.int()
+	b.ThreadID = r.int()
+	for mode := range b.Grades {
+		b.Grades[mode] = Grade(r.byte())
+	}
+	b.LocalOffset = r.short()
+	b.StackLeniency = single2double(r.single())
+	b.GameMode = int(r.byte())
+	b.Source = r.string()
+	b.Tags = strings.Fields(r.string())
+	b.OnlineOffset = r.short()
+	b.TitleFont = r.string()
+	b.Unplayed = r.bool()
+	b.LastPlayed = r.dateTime()
+	b.IsOsz2 = r.bool()
+	b.FolderName = r.string()
+	b.LastChecked = r.dateTime()
+	b.IgnoreBeatmapSounds = r.bool()
+	b.IgnoreBeatmapSkin = r.bool()
+	b.DisableStoryboard = r.bool()
+	b.DisableVideo = r.bool()
+	b.VisualOverride = r.bool()
+	if version < OSU_DB_FLOAT_DIFFICULTY_VERSION {
+		r.short() // unknown
+	}
+	r.int() // last modification time, duplicated
+	b.ManiaScrollSpeed = int(r.byte())
+}
+
+// readStarRatings reads star ratings of one game mode: a list of int-double
+// or, since OSU_DB_FLOAT_STAR_RATING_VERSION, int-float pairs.
+func readStarRatings(r *dbReader, version int) map[Mods]float64 {
+	count := r.int()
+	if count < 0 && r.err == nil {
+		r.err = errors.New("invalid number of star ratings")
+	}
+
+	valueMarker := byte(dbDoubleMarker)
+	if version >= OSU_DB_FLOAT_STAR_RATING_VERSION {
+		valueMarker = dbFloatMarker
+	}
+
+	ratings := make(map[Mods]float64, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		if r.byte() != dbIntMarker && r.err == nil {
+			r.err = errors.New("invalid star rating mods marker")
+		}
+		mods := Mods(r.int())
+		if r.byte() != valueMarker && r.err == nil {
+			r.err = errors.New("invalid star rating value marker")
+		}
+		if valueMarker == dbFloatMarker {
+			ratings[mods] = float64(r.single())
+		} else {
+			ratings[mods] = r.double()
+		}
+	}
+	return ratings
+}
+
+// BeatmapPath returns path of the .osu file of the entry in specified Songs directory.
+func (b *OsuDBBeatmap) BeatmapPath(songsDirectory string) string {
+	return filepath.Join(songsDirectory, b.FolderName, b.FileName)
+}
+
+// MapsetPath returns path of the beatmap folder of the entry in specified Songs directory.
+func (b *OsuDBBeatmap) MapsetPath(songsDirectory string) string {
+	return filepath.Join(songsDirectory, b.FolderName)
+}
+
+// Beatmap returns Beatmap filled with data known from the database entry,
+// without parsing the .osu file. Hit objects are not known.
+func (b *OsuDBBeatmap) Beatmap(songsDirectory string) *Beatmap {
+	beatmap := NewBeatmap()
+	beatmap.FilePath = b.BeatmapPath(songsDirectory)
+	beatmap.AudioFilename = b.AudioFilename
+	beatmap.PreviewTime = b.PreviewTime
+	beatmap.StackLeniency = b.StackLeniency
+	beatmap.GameMode = b.GameMode
+	beatmap.Title = b.Title
+	beatmap.TitleUnicode = b.TitleUnicode
+	beatmap.Artist = b.Artist
+	beatmap.ArtistUnicode = b.ArtistUnicode
+	beatmap.Creator = b.Creator
+	beatmap.Version = b.Version
+	beatmap.Source = b.Source
+	beatmap.Tags = append([]string(nil), b.Tags...)
+	beatmap.BeatmapID = b.BeatmapID
+	beatmap.BeatmapSetID = b.BeatmapSetID
+	beatmap.HPDrainRate = b.HPDrainRate
+	beatmap.CircleSize = b.CircleSize
+	beatmap.OverallDifficulty = b.OverallDifficulty
+	beatmap.ApproachRate = b.ApproachRate
+	beatmap.SliderMultiplier = b.SliderMultiplier
+	for _, tp := range b.TimingPoints {
+		ntp := *tp
+		beatmap.TimingPoints = append(beatmap.TimingPoints, &ntp)
+	}
+	return beatmap
+}
+
+// Mapsets groups beatmaps of the database by their folders into mapsets located in
+// specified Songs directory. Beatmaps are created by OsuDBBeatmap.Beatmap.
+// Mapsets are sorted by directory path.
+func (db *OsuDB) Mapsets(songsDirectory string) []*Mapset {
+	byFolder := make(map[string]*Mapset)
+	var mapsets []*Mapset
+	for _, entry := range db.Beatmaps {
+		m, ok := byFolder[entry.FolderName]
+		if !ok {
+			m = &Mapset{DirectoryPath: entry.MapsetPath(songsDirectory), BeatmapSetID: entry.BeatmapSetID}
+			byFolder[entry.FolderName] = m
+			mapsets = append(mapsets, m)
+		}
+		m.Beatmaps = append(m.Beatmaps, entry.Beatmap(songsDirectory))
+	}
+
+	sort.Slice(mapsets, func(i, j int) bool {
+		return mapsets[i].DirectoryPath < mapsets[j].DirectoryPath
+	})
+	return mapsets
+}
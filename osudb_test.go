@@ -0,0 +1,128 @@
+package pcircle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+)
+
+// dbBuffer builds binary osu! databases for tests.
+type dbBuffer struct {
+	bytes.Buffer
+}
+
+func (b *dbBuffer) put(values ...interface{}) {
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			if s == "" {
+				b.WriteByte(0)
+				continue
+			}
+			b.WriteByte(0x0b)
+			b.WriteByte(byte(len(s)))
+			b.WriteString(s)
+			continue
+		}
+		binary.Write(&b.Buffer, binary.LittleEndian, v)
+	}
+}
+
+func newTestOsuDB(version int32) []byte {
+	entry := new(dbBuffer)
+	entry.put("Artist", "", "Title", "", "Mapper", "Insane", "audio.mp3", "0123456789abcdef", "beatmap.osu",
+		byte(RANKED_STATUS), int16(100), int16(50), int16(2), int64(0))
+	if version < OSU_DB_FLOAT_DIFFICULTY_VERSION {
+		entry.put(byte(9), byte(4), byte(6), byte(8))
+	} else {
+		entry.put(float32(9.3), float32(4), float32(6), float32(8))
+	}
+	entry.put(float64(1.4))
+	if version >= OSU_DB_FLOAT_DIFFICULTY_VERSION {
+		for mode := 0; mode < 4; mode++ {
+			if mode != OSU_GAMEMODE {
+				entry.put(int32(0))
+				continue
+			}
+			entry.put(int32(2), byte(0x08), int32(0))
+			if version >= OSU_DB_FLOAT_STAR_RATING_VERSION {
+				entry.put(byte(0x0c), float32(5.5), byte(0x08), int32(DOUBLE_TIME_MOD), byte(0x0c), float32(7.25))
+			} else {
+				entry.put(byte(0x0d), float64(5.5), byte(0x08), int32(DOUBLE_TIME_MOD), byte(0x0d), float64(7.25))
+			}
+		}
+	}
+	entry.put(int32(120), int32(125000), int32(30000))
+	entry.put(int32(1), float64(500), float64(1000), true)
+	entry.put(int32(42), int32(7), int32(0), byte(A_GRADE), byte(NO_GRADE), byte(NO_GRADE), byte(NO_GRADE))
+	entry.put(int16(0), float32(0.7), byte(OSU_GAMEMODE), "Source", "first second", int16(0), "", false, int64(0), false,
+		"7 Artist - Title", int64(0), false, false, false, false, false)
+	if version < OSU_DB_FLOAT_DIFFICULTY_VERSION {
+		entry.put(int16(0))
+	}
+	entry.put(int32(0), byte(0))
+
+	db := new(dbBuffer)
+	db.put(version, int32(1), true, int64(0), "Player", int32(1))
+	if version < OSU_DB_NO_ENTRY_SIZE_VERSION {
+		db.put(int32(entry.Len()))
+	}
+	db.Write(entry.Bytes())
+	db.put(int32(1))
+	return db.Bytes()
+}
+
+func TestOsuDB_FromReader(t *testing.T) {
+	tests := []struct {
+		version    int32
+		wantAR     float64
+		wantRating float64
+	}{
+		{20140608, 9, 0},
+		{20191105, 9.3, 7.25},
+		{20191106, 9.3, 7.25},
+		{20250108, 9.3, 7.25},
+	}
+	for _, tt := range tests {
+		db := NewOsuDB()
+		if err := db.FromReader(bytes.NewReader(newTestOsuDB(tt.version))); err != nil {
+			t.Errorf("OsuDB.FromReader(%d) error = %v", tt.version, err)
+			continue
+		}
+		if db.PlayerName != "Player" || db.Permissions != 1 || len(db.Beatmaps) != 1 {
+			t.Errorf("OsuDB.FromReader(%d) = %+v", tt.version, db)
+			continue
+		}
+
+		b := db.Beatmaps[0]
+		if b.ApproachRate != tt.wantAR || b.StarRatings[OSU_GAMEMODE][DOUBLE_TIME_MOD] != tt.wantRating {
+			t.Errorf("OsuDB.FromReader(%d) AR = %v, DT stars = %v, want %v, %v", tt.version,
+				b.ApproachRate, b.StarRatings[OSU_GAMEMODE][DOUBLE_TIME_MOD], tt.wantAR, tt.wantRating)
+		}
+		if b.BeatmapID != 42 || b.Grades[OSU_GAMEMODE] != A_GRADE || len(b.Tags) != 2 || b.FolderName != "7 Artist - Title" {
+			t.Errorf("OsuDB.FromReader(%d) beatmap = %+v", tt.version, b)
+		}
+		if len(b.TimingPoints) != 1 || b.TimingPoints[0].Offset != 1000 || !b.TimingPoints[0].Inherited {
+			t.Errorf("OsuDB.FromReader(%d) timing points = %+v", tt.version, b.TimingPoints)
+		}
+	}
+}
+
+func TestOsuDB_Mapsets(t *testing.T) {
+	db := NewOsuDB()
+	if err := db.FromReader(bytes.NewReader(newTestOsuDB(20250108))); err != nil {
+		t.Fatalf("OsuDB.FromReader() error = %v", err)
+	}
+
+	mapsets := db.Mapsets("Songs")
+	if len(mapsets) != 1 || len(mapsets[0].Beatmaps) != 1 {
+		t.Fatalf("OsuDB.Mapsets() = %v", mapsets)
+	}
+	b := mapsets[0].Beatmaps[0]
+	if want := filepath.Join("Songs", "7 Artist - Title", "beatmap.osu"); b.FilePath != want {
+		t.Errorf("OsuDB.Mapsets() beatmap path = %v, want %v", b.FilePath, want)
+	}
+	if b.Version != "Insane" || b.BeatmapSetID != 7 || b.ApproachRate != 9.3 {
+		t.Errorf("OsuDB.Mapsets() beatmap = %+v", b)
+	}
+}
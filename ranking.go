@@ -0,0 +1,765 @@
+package pcircle
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Version of the ranking criteria encoded by DefaultRankingCriteria.
+const RANKING_CRITERIA_VERSION = "2024-01"
+
+// DifficultyLevel is a difficulty class used by ranking criteria spread rules.
+type DifficultyLevel int
+
+// All possible difficulty levels.
+const (
+	UNKNOWN_DIFFICULTY DifficultyLevel = iota - 1
+	EASY_DIFFICULTY
+	NORMAL_DIFFICULTY
+	HARD_DIFFICULTY
+	INSANE_DIFFICULTY
+	EXPERT_DIFFICULTY
+)
+
+// String returns string of DifficultyLevel in readable format.
+func (l DifficultyLevel) String() string {
+	return map[DifficultyLevel]string{
+		UNKNOWN_DIFFICULTY: "Unknown",
+		EASY_DIFFICULTY:    "Easy",
+		NORMAL_DIFFICULTY:  "Normal",
+		HARD_DIFFICULTY:    "Hard",
+		INSANE_DIFFICULTY:  "Insane",
+		EXPERT_DIFFICULTY:  "Expert",
+	}[l]
+}
+
+// Names of difficulty levels in each game mode, from the highest level to the lowest,
+// so "Inner Oni" is matched before "Oni".
+var difficultyNames = map[int][]struct {
+	level DifficultyLevel
+	names []string
+}{
+	OSU_GAMEMODE: {
+		{EXPERT_DIFFICULTY, []string{"expert", "extra", "extreme"}},
+		{INSANE_DIFFICULTY, []string{"insane", "hyper"}},
+		{HARD_DIFFICULTY, []string{"hard", "advanced"}},
+		{NORMAL_DIFFICULTY, []string{"normal", "basic"}},
+		{EASY_DIFFICULTY, []string{"easy", "beginner", "novice"}},
+	},
+	TAIKO_GAMEMODE: {
+		{EXPERT_DIFFICULTY, []string{"inner oni", "ura oni", "hell oni"}},
+		{INSANE_DIFFICULTY, []string{"oni"}},
+		{HARD_DIFFICULTY, []string{"muzukashii"}},
+		{NORMAL_DIFFICULTY, []string{"futsuu"}},
+		{EASY_DIFFICULTY, []string{"kantan"}},
+	},
+	CTB_GAMEMODE: {
+		{EXPERT_DIFFICULTY, []string{"overdose", "deluge"}},
+		{INSANE_DIFFICULTY, []string{"rain"}},
+		{HARD_DIFFICULTY, []string{"platter"}},
+		{NORMAL_DIFFICULTY, []string{"salad"}},
+		{EASY_DIFFICULTY, []string{"cup"}},
+	},
+	MANIA_GAMEMODE: {
+		{EXPERT_DIFFICULTY, []string{"expert", "sc", "another"}},
+		{INSANE_DIFFICULTY, []string{"insane", "mx", "hyper"}},
+		{HARD_DIFFICULTY, []string{"hard", "hd"}},
+		{NORMAL_DIFFICULTY, []string{"normal", "nm"}},
+		{EASY_DIFFICULTY, []string{"easy", "ez", "beginner"}},
+	},
+}
+
+// ClassifyDifficulty guesses difficulty level from the difficulty name.
+func ClassifyDifficulty(b *Beatmap) DifficultyLevel {
+	words := strings.FieldsFunc(strings.ToLower(b.Version), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	joined := " " + strings.Join(words, " ") + " "
+	for _, level := range difficultyNames[b.GameMode] {
+		for _, name := range level.names {
+			if strings.Contains(joined, " "+name+" ") {
+				return level.level
+			}
+		}
+	}
+	return UNKNOWN_DIFFICULTY
+}
+
+// Violation is a single ranking criteria violation.
+type Violation struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Beatmap  string   `json:"beatmap,omitempty"` // Difficulty name, empty for mapset-wide violations
+	Time     int      `json:"time"`              // NO_TIME if violation is not related to time
+	Message  string   `json:"message"`
+}
+
+// ValidationReport is the machine-readable result of ranking criteria validation.
+type ValidationReport struct {
+	CriteriaVersion string       `json:"criteria_version"`
+	Rankable        bool         `json:"rankable"` // False if there is at least one problem
+	Violations      []*Violation `json:"violations"`
+}
+
+// RankingRule is a single versioned rule of the ranking criteria.
+type RankingRule struct {
+	ID          string
+	Description func(rc *RankingCriteria) string // Describes the rule with limits of the criteria
+	Validate    func(v *RankingValidation) []*Violation
+}
+
+// SpreadRequirement requires mapsets with drain time below MaxDrainTime to include
+// a difficulty of Level or lower.
+type SpreadRequirement struct {
+	MaxDrainTime float64 // Seconds
+	Level        DifficultyLevel
+}
+
+// RankingValidation holds data shared by rules during validation of a mapset.
+type RankingValidation struct {
+	Criteria *RankingCriteria
+	Mapset   *Mapset
+	Beatmaps []*Beatmap // Beatmaps of the criteria game mode
+}
+
+// RankingCriteria is a set of ranking rules for one game mode.
+type RankingCriteria struct {
+	Version  string
+	GameMode int
+	Rules    []*RankingRule
+
+	// Guesses difficulty level of a beatmap for spread rules.
+	Classify func(b *Beatmap) DifficultyLevel
+
+	// Detects whether storyboard or video of the beatmap contains rapidly flashing
+	// colours. If nil, only missing storyboard is checked.
+	StoryboardFlashes func(m *Mapset, b *Beatmap) bool
+
+	MinDrainTime              float64 // Seconds
+	MaxDifficultySpecificTags int
+	MinAudioLeadIn            int // Milliseconds before the first hit object, including lead-in
+	MinBackgroundWidth        int
+	MinBackgroundHeight       int
+	MaxBackgroundWidth        int
+	MaxBackgroundHeight       int
+	MinAudioBitrate           int     // kbps
+	MaxMP3Bitrate             int     // kbps
+	MaxOGGBitrate             int     // kbps
+	MaxHitSoundDelay          float64 // Milliseconds of leading silence
+	MaxHitSoundDuration       float64 // Milliseconds
+	MaxStoryboardObjects      int
+	MinManiaKeys              int
+	MaxManiaKeys              int
+
+	// Lowest difficulty required by drain time of the mapset, by increasing drain time.
+	SpreadRequirements []SpreadRequirement
+}
+
+// DefaultRankingCriteria returns ranking criteria for specified game mode. Rules common
+// to all game modes are extended by difficulty name and spread gap rules in osu!taiko
+// and osu!catch and by key count rules in osu!mania. osu!catch requires easier spread
+// for shorter mapsets.
+func DefaultRankingCriteria(mode int) *RankingCriteria {
+	rules := []*RankingRule{
+		DrainTimeRule,
+		DifficultySpreadRule,
+		MetadataConsistencyRule,
+		AudioLeadInRule,
+		CountdownRule,
+		EpilepsyWarningRule,
+		BackgroundRule,
+		AudioBitrateRule,
+		HitSoundFilesRule,
+		StoryboardObjectsRule,
+	}
+	spread := []SpreadRequirement{
+		{210, NORMAL_DIFFICULTY},
+		{255, HARD_DIFFICULTY},
+		{300, INSANE_DIFFICULTY},
+	}
+	switch mode {
+	case TAIKO_GAMEMODE:
+		rules = append(rules, DifficultyNamesRule, DifficultyGapRule)
+	case CTB_GAMEMODE:
+		rules = append(rules, DifficultyNamesRule, DifficultyGapRule)
+		spread = []SpreadRequirement{
+			{150, EASY_DIFFICULTY},
+			{195, NORMAL_DIFFICULTY},
+			{240, HARD_DIFFICULTY},
+			{285, INSANE_DIFFICULTY},
+		}
+	case MANIA_GAMEMODE:
+		rules = append(rules, ManiaKeyCountRule)
+	}
+
+	return &RankingCriteria{
+		Version:                   RANKING_CRITERIA_VERSION,
+		GameMode:                  mode,
+		Rules:                     rules,
+		Classify:                  ClassifyDifficulty,
+		MinDrainTime:              30,
+		MaxDifficultySpecificTags: 2,
+		MinAudioLeadIn:            2000,
+		MinBackgroundWidth:        160,
+		MinBackgroundHeight:       120,
+		MaxBackgroundWidth:        2560,
+		MaxBackgroundHeight:       1440,
+		MinAudioBitrate:           128,
+		MaxMP3Bitrate:             192,
+		MaxOGGBitrate:             208,
+		MaxHitSoundDelay:          5,
+		MaxHitSoundDuration:       1500,
+		MaxStoryboardObjects:      5000,
+		MinManiaKeys:              4,
+		MaxManiaKeys:              10,
+		SpreadRequirements:        spread,
+	}
+}
+
+// Validate checks beatmaps of the criteria game mode in specified mapset.
+func (rc *RankingCriteria) Validate(m *Mapset) *ValidationReport {
+	v := &RankingValidation{Criteria: rc, Mapset: m}
+	for _, b := range m.Beatmaps {
+		if b.GameMode == rc.GameMode {
+			v.Beatmaps = append(v.Beatmaps, b)
+		}
+	}
+
+	report := &ValidationReport{CriteriaVersion: rc.Version, Rankable: true, Violations: []*Violation{}}
+	if len(v.Beatmaps) == 0 {
+		return report
+	}
+
+	for _, rule := range rc.Rules {
+		for _, violation := range rule.Validate(v) {
+			violation.Rule = rule.ID
+			if violation.Severity == PROBLEM_SEVERITY {
+				report.Rankable = false
+			}
+			report.Violations = append(report.Violations, violation)
+		}
+	}
+	return report
+}
+
+// ValidateRanking checks the mapset against default ranking criteria of every
+// game mode it contains.
+func (m *Mapset) ValidateRanking() *ValidationReport {
+	modes := make(map[int]bool)
+	for _, b := range m.Beatmaps {
+		modes[b.GameMode] = true
+	}
+
+	report := &ValidationReport{CriteriaVersion: RANKING_CRITERIA_VERSION, Rankable: true, Violations: []*Violation{}}
+	for mode := OSU_GAMEMODE; mode <= MANIA_GAMEMODE; mode++ {
+		if !modes[mode] {
+			continue
+		}
+		r := DefaultRankingCriteria(mode).Validate(m)
+		report.Rankable = report.Rankable && r.Rankable
+		report.Violations = append(report.Violations, r.Violations...)
+	}
+	return report
+}
+
+// newViolation creates violation with specified parameters.
+func newViolation(severity Severity, b *Beatmap, time int, format string, args ...interface{}) *Violation {
+	v := &Violation{Severity: severity, Time: time, Message: fmt.Sprintf(format, args...)}
+	if b != nil {
+		v.Beatmap = b.Version
+	}
+	return v
+}
+
+// DrainTimeRule requires every difficulty to be long enough.
+var DrainTimeRule = &RankingRule{
+	ID: "drain-time",
+	Description: func(rc *RankingCriteria) string {
+		return fmt.Sprintf("Every difficulty must have drain time of at least %.0f seconds.", rc.MinDrainTime)
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		var violations []*Violation
+		for _, b := range v.Beatmaps {
+			if drain := float64(b.RankedDrainTime()); drain < v.Criteria.MinDrainTime {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, NO_TIME,
+					"drain time is %.0fs, it must be at least %.0fs", drain, v.Criteria.MinDrainTime))
+			}
+		}
+		return violations
+	},
+}
+
+// DifficultySpreadRule requires the lowest difficulty to be easy enough for the drain time of the mapset.
+var DifficultySpreadRule = &RankingRule{
+	ID: "difficulty-spread",
+	Description: func(rc *RankingCriteria) string {
+		var parts []string
+		for _, r := range rc.SpreadRequirements {
+			article := "a"
+			if strings.ContainsAny(r.Level.String()[:1], "AEIOU") {
+				article = "an"
+			}
+			parts = append(parts, fmt.Sprintf("below %s %s %v or easier", formatDrainTime(r.MaxDrainTime), article, r.Level))
+		}
+		return "The lowest difficulty of mapsets with drain time " + strings.Join(parts, ", ") + "."
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		return checkSpread(v.Criteria, v.Beatmaps, "")
+	},
+}
+
+// formatDrainTime returns drain time in seconds formatted like "3:30".
+func formatDrainTime(drain float64) string {
+	return fmt.Sprintf("%d:%02d", int(drain)/60, int(drain)%60)
+}
+
+// checkSpread checks that the lowest of beatmaps is easy enough for their drain time.
+// Violations are prefixed with specified prefix.
+func checkSpread(rc *RankingCriteria, beatmaps []*Beatmap, prefix string) []*Violation {
+	drain := 0.0
+	lowest := EXPERT_DIFFICULTY + 1
+	for _, b := range beatmaps {
+		drain = math.Max(drain, float64(b.RankedDrainTime()))
+		level := rc.Classify(b)
+		if level == UNKNOWN_DIFFICULTY {
+			return []*Violation{newViolation(INFO_SEVERITY, b, NO_TIME,
+				"%sdifficulty level can not be determined from its name, spread must be checked manually", prefix)}
+		}
+		if level < lowest {
+			lowest = level
+		}
+	}
+
+	required := EXPERT_DIFFICULTY
+	for _, r := range rc.SpreadRequirements {
+		if drain < r.MaxDrainTime {
+			required = r.Level
+			break
+		}
+	}
+	if lowest > required {
+		return []*Violation{newViolation(PROBLEM_SEVERITY, nil, NO_TIME,
+			"%sdrain time is %s, so the spread must include %v or lower difficulty, the lowest is %v",
+			prefix, formatDrainTime(drain), required, lowest)}
+	}
+	return nil
+}
+
+// DifficultyNamesRule requires difficulty names which show their level, like Kantan or Cup.
+var DifficultyNamesRule = &RankingRule{
+	ID: "difficulty-names",
+	Description: func(rc *RankingCriteria) string {
+		return "Difficulty names should show their level with the names of the game mode."
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		var violations []*Violation
+		for _, b := range v.Beatmaps {
+			if v.Criteria.Classify(b) == UNKNOWN_DIFFICULTY {
+				violations = append(violations, newViolation(WARNING_SEVERITY, b, NO_TIME,
+					"difficulty name does not show its level"))
+			}
+		}
+		return violations
+	},
+}
+
+// DifficultyGapRule forbids spreads which skip a difficulty level.
+var DifficultyGapRule = &RankingRule{
+	ID: "difficulty-gap",
+	Description: func(rc *RankingCriteria) string {
+		return "The spread must not skip a difficulty level between its lowest and highest difficulty."
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		present := make(map[DifficultyLevel]bool)
+		lowest, highest := EXPERT_DIFFICULTY, EASY_DIFFICULTY
+		for _, b := range v.Beatmaps {
+			level := v.Criteria.Classify(b)
+			if level == UNKNOWN_DIFFICULTY {
+				continue
+			}
+			present[level] = true
+			lowest, highest = min(lowest, level), max(highest, level)
+		}
+
+		var violations []*Violation
+		for level := lowest + 1; level < highest; level++ {
+			if !present[level] {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, nil, NO_TIME,
+					"the spread skips %v difficulty between %v and %v", level, lowest, highest))
+			}
+		}
+		return violations
+	},
+}
+
+// ManiaKeyCountRule requires allowed key counts and a separate spread of every key count.
+var ManiaKeyCountRule = &RankingRule{
+	ID: "mania-key-count",
+	Description: func(rc *RankingCriteria) string {
+		return fmt.Sprintf("Difficulties must have %d to %d keys, and every key count of the mapset must have its own spread.",
+			rc.MinManiaKeys, rc.MaxManiaKeys)
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		var violations []*Violation
+		byKeys := make(map[int][]*Beatmap)
+		var keyCounts []int
+		for _, b := range v.Beatmaps {
+			keys := int(b.CircleSize)
+			if float64(keys) != b.CircleSize || keys < v.Criteria.MinManiaKeys || keys > v.Criteria.MaxManiaKeys {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, NO_TIME,
+					"key count is %v, it must be from %d to %d", b.CircleSize, v.Criteria.MinManiaKeys, v.Criteria.MaxManiaKeys))
+				continue
+			}
+			if len(byKeys[keys]) == 0 {
+				keyCounts = append(keyCounts, keys)
+			}
+			byKeys[keys] = append(byKeys[keys], b)
+		}
+
+		// the spread of a single key count is checked by DifficultySpreadRule
+		if len(keyCounts) > 1 {
+			for _, keys := range keyCounts {
+				violations = append(violations, checkSpread(v.Criteria, byKeys[keys], fmt.Sprintf("%dK: ", keys))...)
+			}
+		}
+		return violations
+	},
+}
+
+// MetadataConsistencyRule requires metadata to be the same in all difficulties.
+// Up to two difficulty-specific tags (like guest mapper names) are allowed.
+var MetadataConsistencyRule = &RankingRule{
+	ID: "metadata-consistency",
+	Description: func(rc *RankingCriteria) string {
+		return fmt.Sprintf("Metadata must be consistent across difficulties, except of at most %d difficulty-specific tags.",
+			rc.MaxDifficultySpecificTags)
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		var violations []*Violation
+		for _, issue := range (InconsistentMetadataCheck{}).CheckMapset(&Mapset{Beatmaps: v.Beatmaps}) {
+			if strings.HasPrefix(issue.Message, "Tags ") {
+				continue
+			}
+			violations = append(violations, newViolation(PROBLEM_SEVERITY, issue.Beatmap, NO_TIME, "%s", issue.Message))
+		}
+
+		// tags present in all difficulties
+		common := make(map[string]int)
+		for _, b := range v.Beatmaps {
+			seen := make(map[string]bool)
+			for _, tag := range b.Tags {
+				tag = strings.ToLower(tag)
+				if !seen[tag] {
+					seen[tag] = true
+					common[tag]++
+				}
+			}
+		}
+		for _, b := range v.Beatmaps {
+			var specific []string
+			for _, tag := range b.Tags {
+				if common[strings.ToLower(tag)] < len(v.Beatmaps) {
+					specific = append(specific, tag)
+				}
+			}
+			if len(specific) > v.Criteria.MaxDifficultySpecificTags {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, NO_TIME,
+					"%d tags are not present in other difficulties (%s), at most %d are allowed",
+					len(specific), strings.Join(specific, " "), v.Criteria.MaxDifficultySpecificTags))
+			}
+		}
+		return violations
+	},
+}
+
+// AudioLeadInRule requires enough time before the first hit object.
+var AudioLeadInRule = &RankingRule{
+	ID: "audio-lead-in",
+	Description: func(rc *RankingCriteria) string {
+		return fmt.Sprintf("The first hit object must be at least %dms after the start, use AudioLeadIn if needed.", rc.MinAudioLeadIn)
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		var violations []*Violation
+		for _, b := range v.Beatmaps {
+			if len(b.HitObjects) == 0 {
+				continue
+			}
+			first := BaseOf(b.HitObjects[0]).Time
+			if first+b.AudioLeadIn < v.Criteria.MinAudioLeadIn {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, first,
+					"first hit object is only %dms after the start including lead-in, it must be at least %dms",
+					first+b.AudioLeadIn, v.Criteria.MinAudioLeadIn))
+			}
+		}
+		return violations
+	},
+}
+
+// CountdownRule requires countdown to fit before the first hit object.
+var CountdownRule = &RankingRule{
+	ID: "countdown",
+	Description: func(rc *RankingCriteria) string {
+		return "Countdown must not be enabled if there is not enough time for it before the first hit object."
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		var violations []*Violation
+		for _, b := range v.Beatmaps {
+			if b.Countdown == NO_COUNTDOWN || len(b.HitObjects) == 0 {
+				continue
+			}
+			first := BaseOf(b.HitObjects[0]).Time
+
+			beat := b.BeatLengthAt(first)
+			switch b.Countdown {
+			case HALF_COUNTDOWN:
+				beat *= 2
+			case DOUBLE_COUNTDOWN:
+				beat /= 2
+			}
+			// countdown takes three beats plus the offset before the first object
+			needed := beat * float64(3+b.CountdownOffset)
+			if float64(first+b.AudioLeadIn) < needed {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, first,
+					"countdown needs %.0fms before the first hit object, but there are only %dms",
+					needed, first+b.AudioLeadIn))
+			}
+		}
+		return violations
+	},
+}
+
+// EpilepsyWarningRule requires epilepsy warning for flashing storyboards and forbids it without storyboard.
+var EpilepsyWarningRule = &RankingRule{
+	ID: "epilepsy-warning",
+	Description: func(rc *RankingCriteria) string {
+		return "Epilepsy warning must be enabled if storyboard or video flashes, and only then."
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		var violations []*Violation
+		for _, b := range v.Beatmaps {
+			hasStoryboard := storyboardObjectCount(v.Mapset, b) > 0
+			switch {
+			case b.EpilepsyWarning && !hasStoryboard:
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, NO_TIME,
+					"epilepsy warning is enabled, but there is no storyboard"))
+			case !b.EpilepsyWarning && hasStoryboard && v.Criteria.StoryboardFlashes != nil && v.Criteria.StoryboardFlashes(v.Mapset, b):
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, NO_TIME,
+					"storyboard contains flashing colours, but epilepsy warning is disabled"))
+			case !b.EpilepsyWarning && hasStoryboard && v.Criteria.StoryboardFlashes == nil:
+				violations = append(violations, newViolation(INFO_SEVERITY, b, NO_TIME,
+					"storyboard must be checked for flashing colours manually"))
+			}
+		}
+		return violations
+	},
+}
+
+// BackgroundRule requires background images of allowed dimensions.
+var BackgroundRule = &RankingRule{
+	ID: "background",
+	Description: func(rc *RankingCriteria) string {
+		return fmt.Sprintf("Every difficulty must have a background between %dx%d and %dx%d.",
+			rc.MinBackgroundWidth, rc.MinBackgroundHeight, rc.MaxBackgroundWidth, rc.MaxBackgroundHeight)
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		var violations []*Violation
+		rc := v.Criteria
+		for _, b := range v.Beatmaps {
+			if b.Background == nil || b.Background.FileName == "" {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, NO_TIME, "background is not set"))
+				continue
+			}
+			width, height, err := ImageSize(filepath.Join(v.Mapset.DirectoryPath, b.Background.FileName))
+			if err != nil {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, NO_TIME,
+					"background %q can not be read: %v", b.Background.FileName, err))
+				continue
+			}
+			if width < rc.MinBackgroundWidth || height < rc.MinBackgroundHeight ||
+				width > rc.MaxBackgroundWidth || height > rc.MaxBackgroundHeight {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, NO_TIME,
+					"background %q is %dx%d, it must be between %dx%d and %dx%d", b.Background.FileName, width, height,
+					rc.MinBackgroundWidth, rc.MinBackgroundHeight, rc.MaxBackgroundWidth, rc.MaxBackgroundHeight))
+			}
+		}
+		return violations
+	},
+}
+
+// AudioBitrateRule requires audio of allowed bitrate.
+var AudioBitrateRule = &RankingRule{
+	ID: "audio-bitrate",
+	Description: func(rc *RankingCriteria) string {
+		return fmt.Sprintf("Audio must be mp3 of %d-%d kbps or ogg of %d-%d kbps.",
+			rc.MinAudioBitrate, rc.MaxMP3Bitrate, rc.MinAudioBitrate, rc.MaxOGGBitrate)
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		var violations []*Violation
+		rc := v.Criteria
+		checked := make(map[string]bool)
+		for _, b := range v.Beatmaps {
+			if b.AudioFilename == "" || checked[b.AudioFilename] {
+				continue
+			}
+			checked[b.AudioFilename] = true
+
+			bitrate, err := AudioBitrate(filepath.Join(v.Mapset.DirectoryPath, b.AudioFilename))
+			if err != nil {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, NO_TIME,
+					"audio %q can not be read: %v", b.AudioFilename, err))
+				continue
+			}
+
+			max := rc.MaxMP3Bitrate
+			if strings.EqualFold(filepath.Ext(b.AudioFilename), ".ogg") {
+				max = rc.MaxOGGBitrate
+			}
+			if bitrate > max {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, NO_TIME,
+					"audio bitrate is %d kbps, it must be at most %d kbps", bitrate, max))
+			}
+			if bitrate < rc.MinAudioBitrate {
+				violations = append(violations, newViolation(WARNING_SEVERITY, b, NO_TIME,
+					"audio bitrate is %d kbps, it should be at least %d kbps unless there is no better source", bitrate, rc.MinAudioBitrate))
+			}
+		}
+		return violations
+	},
+}
+
+// HitSoundFilesRule requires valid, not delayed and short custom hit sounds and forbids
+// muted active hit objects.
+var HitSoundFilesRule = &RankingRule{
+	ID: "hitsound-files",
+	Description: func(rc *RankingCriteria) string {
+		return fmt.Sprintf("Custom hit sounds must be valid, not delayed by more than %.0fms, not longer than %.0fms, "+
+			"and must not mute clickable hit objects.", rc.MaxHitSoundDelay, rc.MaxHitSoundDuration)
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		var violations []*Violation
+		type sampleInfo struct {
+			audio *Audio
+			err   error
+		}
+		cache := make(map[string]*sampleInfo)
+		load := func(filename string) *sampleInfo {
+			if info, ok := cache[filename]; ok {
+				return info
+			}
+			info := new(sampleInfo)
+			f, err := os.Open(filepath.Join(v.Mapset.DirectoryPath, filename))
+			if err == nil {
+				info.audio, info.err = DecodeWAV(f)
+				f.Close()
+			} else {
+				info.err = err
+			}
+			cache[filename] = info
+			return info
+		}
+
+		reported, reportedLong := make(map[string]bool), make(map[string]bool)
+		for _, b := range v.Beatmaps {
+			for _, s := range v.Mapset.HitSamples(b) {
+				if s.Skin || !strings.EqualFold(filepath.Ext(s.Filename), ".wav") {
+					continue
+				}
+				info := load(s.Filename)
+				if info.err != nil {
+					if !os.IsNotExist(info.err) && !reported[s.Filename] {
+						reported[s.Filename] = true
+						violations = append(violations, newViolation(PROBLEM_SEVERITY, nil, NO_TIME,
+							"hit sound %q is not a valid wav file: %v", s.Filename, info.err))
+					}
+					continue
+				}
+
+				delay, silent := leadingSilence(info.audio)
+				if !silent && delay > v.Criteria.MaxHitSoundDelay && !reported[s.Filename] {
+					reported[s.Filename] = true
+					violations = append(violations, newViolation(WARNING_SEVERITY, nil, NO_TIME,
+						"hit sound %q is delayed by %.1fms", s.Filename, delay))
+				}
+				if duration := info.audio.Duration(); duration > v.Criteria.MaxHitSoundDuration && !reportedLong[s.Filename] {
+					reportedLong[s.Filename] = true
+					violations = append(violations, newViolation(WARNING_SEVERITY, nil, NO_TIME,
+						"hit sound %q is %.0fms long, it should be at most %.0fms", s.Filename, duration, v.Criteria.MaxHitSoundDuration))
+				}
+				if silent && s.Sound == HIT_NORMAL_SOUND && isClickable(b, s) {
+					violations = append(violations, newViolation(PROBLEM_SEVERITY, b, s.Time,
+						"clickable hit object is muted by silent %q", s.Filename))
+				}
+			}
+		}
+		return violations
+	},
+}
+
+// isClickable reports whether the sample is played by a hit circle or slider head.
+func isClickable(b *Beatmap, s *HitSample) bool {
+	switch o := s.HitObject.(type) {
+	case *Circle:
+		return true
+	case *Slider:
+		return s.Time == o.Time
+	case *ManiaHoldNote:
+		return true
+	}
+	return false
+}
+
+// leadingSilence returns duration of silence in the beginning of audio in milliseconds,
+// and whether the whole audio is silent.
+func leadingSilence(a *Audio) (float64, bool) {
+	const threshold = 0.005
+	for i, s := range a.Samples {
+		if math.Abs(float64(s)) > threshold {
+			return float64(i/2) * 1000 / float64(a.SampleRate), false
+		}
+	}
+	return a.Duration(), true
+}
+
+// StoryboardObjectsRule limits the number of storyboard objects.
+var StoryboardObjectsRule = &RankingRule{
+	ID: "storyboard-objects",
+	Description: func(rc *RankingCriteria) string {
+		return fmt.Sprintf("Storyboards must have at most %d objects.", rc.MaxStoryboardObjects)
+	},
+	Validate: func(v *RankingValidation) []*Violation {
+		var violations []*Violation
+		for _, b := range v.Beatmaps {
+			if count := storyboardObjectCount(v.Mapset, b); count > v.Criteria.MaxStoryboardObjects {
+				violations = append(violations, newViolation(PROBLEM_SEVERITY, b, NO_TIME,
+					"storyboard has %d objects, at most %d are allowed", count, v.Criteria.MaxStoryboardObjects))
+			}
+		}
+		return violations
+	},
+}
+
+// storyboardObjectCount returns number of sprites, animations, samples and videos
+// used by the beatmap: in its own events and in the storyboard of the mapset.
+func storyboardObjectCount(m *Mapset, b *Beatmap) int {
+	events := b.Storyboard
+	if m.Storyboard != nil {
+		events = append(append([]string(nil), events...), m.Storyboard.Events...)
+	}
+
+	count := 0
+	if b.Video != nil {
+		count++
+	}
+	for _, event := range events {
+		for _, prefix := range []string{"Sprite,", "Animation,", "Sample,", "4,", "5,", "6,"} {
+			if strings.HasPrefix(event, prefix) {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,197 @@
+package pcircle
+
+import (
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassifyDifficulty(t *testing.T) {
+	tests := []struct {
+		mode    int
+		version string
+		want    DifficultyLevel
+	}{
+		{OSU_GAMEMODE, "Normal", NORMAL_DIFFICULTY},
+		{OSU_GAMEMODE, "Someone's Insane", INSANE_DIFFICULTY},
+		{OSU_GAMEMODE, "Hardcore", UNKNOWN_DIFFICULTY},
+		{TAIKO_GAMEMODE, "Inner Oni", EXPERT_DIFFICULTY},
+		{TAIKO_GAMEMODE, "Oni", INSANE_DIFFICULTY},
+		{CTB_GAMEMODE, "Salad", NORMAL_DIFFICULTY},
+		{MANIA_GAMEMODE, "4K HD", HARD_DIFFICULTY},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			b := &Beatmap{GameMode: tt.mode, Version: tt.version}
+			if got := ClassifyDifficulty(b); got != tt.want {
+				t.Errorf("ClassifyDifficulty() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankingCriteria_Validate(t *testing.T) {
+	dir, err := os.MkdirTemp("", "pcircle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := os.Create(filepath.Join(dir, "bg.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, image.NewRGBA(image.Rect(0, 0, 100, 100)))
+	f.Close()
+
+	b := newTestBeatmap()
+	b.Version = "Insane"
+	b.Tags = []string{"a", "b", "c"}
+	b.Background = &Background{FileName: "bg.png"}
+	b.EpilepsyWarning = true
+	other := newTestBeatmap()
+	other.Version = "Expert"
+	other.Background = &Background{FileName: "bg.png"}
+
+	m := &Mapset{DirectoryPath: dir, Beatmaps: []*Beatmap{b, other}}
+	rc := DefaultRankingCriteria(OSU_GAMEMODE)
+	rc.Rules = []*RankingRule{DrainTimeRule, DifficultySpreadRule, MetadataConsistencyRule, AudioLeadInRule,
+		EpilepsyWarningRule, BackgroundRule}
+	report := rc.Validate(m)
+
+	if report.Rankable {
+		t.Errorf("RankingCriteria.Validate() Rankable = true, want false")
+	}
+	want := map[string]int{
+		"drain-time":           2,
+		"difficulty-spread":    1,
+		"metadata-consistency": 1,
+		"audio-lead-in":        2,
+		"epilepsy-warning":     1,
+		"background":           2,
+	}
+	got := make(map[string]int)
+	for _, v := range report.Violations {
+		got[v.Rule]++
+	}
+	for rule, n := range want {
+		if got[rule] != n {
+			t.Errorf("RankingCriteria.Validate() %s violations = %d, want %d", rule, got[rule], n)
+		}
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"severity":"problem"`) {
+		t.Errorf("json.Marshal() = %s, want readable severities", data)
+	}
+}
+
+func TestDefaultRankingCriteria(t *testing.T) {
+	tests := []struct {
+		mode int
+		rule string
+		want bool
+	}{
+		{OSU_GAMEMODE, "difficulty-names", false},
+		{TAIKO_GAMEMODE, "difficulty-names", true},
+		{CTB_GAMEMODE, "difficulty-gap", true},
+		{MANIA_GAMEMODE, "mania-key-count", true},
+		{MANIA_GAMEMODE, "difficulty-gap", false},
+	}
+	for _, tt := range tests {
+		found := false
+		for _, rule := range DefaultRankingCriteria(tt.mode).Rules {
+			found = found || rule.ID == tt.rule
+		}
+		if found != tt.want {
+			t.Errorf("DefaultRankingCriteria(%d) has %s = %v, want %v", tt.mode, tt.rule, found, tt.want)
+		}
+	}
+
+	rc := DefaultRankingCriteria(CTB_GAMEMODE)
+	rc.MinDrainTime, rc.MaxBackgroundWidth = 45, 1920
+	for _, want := range []struct {
+		rule *RankingRule
+		text string
+	}{
+		{DrainTimeRule, "at least 45 seconds"},
+		{BackgroundRule, "and 1920x1440"},
+		{DifficultySpreadRule, "below 2:30 an Easy or easier, below 3:15 a Normal or easier"},
+		{HitSoundFilesRule, "not longer than 1500ms"},
+	} {
+		if got := want.rule.Description(rc); !strings.Contains(got, want.text) {
+			t.Errorf("RankingRule.Description() = %q, want to contain %q", got, want.text)
+		}
+	}
+}
+
+func TestRankingCriteria_Validate_modes(t *testing.T) {
+	newBeatmap := func(mode int, version string, keys float64) *Beatmap {
+		b := newTestBeatmap()
+		b.GameMode, b.Version, b.CircleSize = mode, version, keys
+		return b
+	}
+	tests := []struct {
+		mode     int
+		beatmaps []*Beatmap
+		rule     string
+		want     []string
+	}{
+		{TAIKO_GAMEMODE, []*Beatmap{newBeatmap(TAIKO_GAMEMODE, "Kantan", 5), newBeatmap(TAIKO_GAMEMODE, "Oni", 5)},
+			"difficulty-gap", []string{"skips Normal", "skips Hard"}},
+		{CTB_GAMEMODE, []*Beatmap{newBeatmap(CTB_GAMEMODE, "Cup", 5), newBeatmap(CTB_GAMEMODE, "Extra Hard", 5)},
+			"difficulty-names", []string{"does not show its level"}},
+		{MANIA_GAMEMODE, []*Beatmap{newBeatmap(MANIA_GAMEMODE, "4K EZ", 4), newBeatmap(MANIA_GAMEMODE, "7K HD", 7), newBeatmap(MANIA_GAMEMODE, "12K NM", 12)},
+			"mania-key-count", []string{"key count is 12", "7K: drain time"}},
+	}
+	for _, tt := range tests {
+		rc := DefaultRankingCriteria(tt.mode)
+		var got []string
+		for _, v := range rc.Validate(&Mapset{Beatmaps: tt.beatmaps}).Violations {
+			if v.Rule == tt.rule {
+				got = append(got, v.Message)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("RankingCriteria.Validate() %s violations = %q, want %q", tt.rule, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if !strings.Contains(got[i], tt.want[i]) {
+				t.Errorf("RankingCriteria.Validate() %s violation = %q, want to contain %q", tt.rule, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestHitSoundFilesRule(t *testing.T) {
+	dir := t.TempDir()
+	a := &Audio{SampleRate: 1000, Samples: make([]float32, 2*2000)}
+	for i := range a.Samples {
+		a.Samples[i] = 0.5
+	}
+	f, err := os.Create(filepath.Join(dir, "long.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.EncodeWAV(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	b := newTestBeatmap()
+	b.HitObjects = []interface{}{&Circle{BaseHitObject{Time: 1000, Type: CIRCLE, Extras: &Extras{Filename: "long.wav"}}}}
+	rc := DefaultRankingCriteria(OSU_GAMEMODE)
+	rc.Rules = []*RankingRule{HitSoundFilesRule}
+	violations := rc.Validate(&Mapset{DirectoryPath: dir, Beatmaps: []*Beatmap{b}}).Violations
+	if len(violations) != 1 || !strings.Contains(violations[0].Message, "is 2000ms long") {
+		t.Fatalf("HitSoundFilesRule violations = %v, want too long hit sound", violations)
+	}
+}
@@ -0,0 +1,64 @@
+package pcircle
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RateChangeOptions specifies how speed-adjusted copy of a beatmap is created.
+type RateChangeOptions struct {
+	Rate float64 // Speed multiplier, e.g. 1.2 for 20% faster beatmap
+
+	AdjustApproachRate      bool // Whether to keep approach time as it would be with the original beatmap played at Rate
+	AdjustOverallDifficulty bool // Whether to keep hit windows as they would be with the original beatmap played at Rate
+
+	// Difficulty name of the new beatmap.
+	// Defaults to the original name with rate appended, like "Insane 1.2x".
+	Version string
+
+	// Audio file of the new beatmap. Audio itself is not resampled.
+	// Defaults to the original name with rate appended, like "audio 1.2x.mp3".
+	AudioFilename string
+}
+
+// ChangeRate returns new Beatmap which plays at specified rate at normal speed.
+//
+// Every time value is divided by the rate: hit objects and their end times,
+// timing point offsets and red line beat lengths, breaks, bookmarks, preview time
+// and audio lead-in. Green lines keep their slider velocity multipliers.
+// BeatmapID is reset as the result is a new difficulty.
+func (b *Beatmap) ChangeRate(opts RateChangeOptions) (*Beatmap, error) {
+	if opts.Rate <= 0 {
+		return nil, errors.New("invalid rate: " + strconv.FormatFloat(opts.Rate, 'f', -1, 64))
+	}
+
+	nb := b.Clone()
+	nb.scaleTime(opts.Rate)
+
+	if opts.AdjustApproachRate {
+		nb.ApproachRate = preemptToApproachRate(approachRateToPreempt(nb.ApproachRate) / opts.Rate)
+	}
+	if opts.AdjustOverallDifficulty {
+		nb.OverallDifficulty = rateAdjustedOverallDifficulty(nb.GameMode, nb.OverallDifficulty, opts.Rate)
+	}
+
+	suffix := strconv.FormatFloat(opts.Rate, 'f', -1, 64) + "x"
+
+	nb.Version = opts.Version
+	if nb.Version == "" {
+		nb.Version = strings.TrimSpace(b.Version + " " + suffix)
+	}
+
+	nb.AudioFilename = opts.AudioFilename
+	if nb.AudioFilename == "" && b.AudioFilename != "" {
+		ext := filepath.Ext(b.AudioFilename)
+		nb.AudioFilename = strings.TrimSuffix(b.AudioFilename, ext) + " " + suffix + ext
+	}
+
+	nb.BeatmapID = 0
+	nb.FilePath = ""
+
+	return nb, nil
+}
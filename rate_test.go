@@ -0,0 +1,79 @@
+package pcircle
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBeatmap_ChangeRate(t *testing.T) {
+	b := newTestBeatmap()
+	b.Version = "Insane"
+	b.AudioFilename = "audio.mp3"
+
+	tests := []struct {
+		name            string
+		opts            RateChangeOptions
+		wantVersion     string
+		wantAudio       string
+		wantPreview     int
+		wantBreakEnd    int
+		wantOD          float64
+		wantBeatLength  float64
+		wantGreenLength float64
+		wantErr         bool
+	}{
+		{
+			name:            "1.25x with default names",
+			opts:            RateChangeOptions{Rate: 1.25},
+			wantVersion:     "Insane 1.25x",
+			wantAudio:       "audio 1.25x.mp3",
+			wantPreview:     2400,
+			wantBreakEnd:    7200,
+			wantOD:          8,
+			wantBeatLength:  400,
+			wantGreenLength: -50,
+		},
+		{
+			name:            "0.5x with adjusted OD and custom names",
+			opts:            RateChangeOptions{Rate: 0.5, AdjustOverallDifficulty: true, Version: "Slow", AudioFilename: "slow.mp3"},
+			wantVersion:     "Slow",
+			wantAudio:       "slow.mp3",
+			wantPreview:     6000,
+			wantBreakEnd:    18000,
+			wantOD:          2.6666666,
+			wantBeatLength:  1000,
+			wantGreenLength: -50,
+		},
+		{
+			name:    "invalid rate",
+			opts:    RateChangeOptions{Rate: 0},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.ChangeRate(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Beatmap.ChangeRate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Version != tt.wantVersion || got.AudioFilename != tt.wantAudio {
+				t.Errorf("Beatmap.ChangeRate() names = %v, %v, want %v, %v", got.Version, got.AudioFilename, tt.wantVersion, tt.wantAudio)
+			}
+			if got.PreviewTime != tt.wantPreview {
+				t.Errorf("Beatmap.ChangeRate() PreviewTime = %v, want %v", got.PreviewTime, tt.wantPreview)
+			}
+			if got.Breaks[0].EndTime != tt.wantBreakEnd {
+				t.Errorf("Beatmap.ChangeRate() break end = %v, want %v", got.Breaks[0].EndTime, tt.wantBreakEnd)
+			}
+			if math.Abs(got.OverallDifficulty-tt.wantOD) > 1e-6 {
+				t.Errorf("Beatmap.ChangeRate() OverallDifficulty = %v, want %v", got.OverallDifficulty, tt.wantOD)
+			}
+			if got.TimingPoints[0].MillisecondsPerBeat != tt.wantBeatLength || got.TimingPoints[1].MillisecondsPerBeat != tt.wantGreenLength {
+				t.Errorf("Beatmap.ChangeRate() beat lengths = %v, %v", got.TimingPoints[0].MillisecondsPerBeat, got.TimingPoints[1].MillisecondsPerBeat)
+			}
+		})
+	}
+}
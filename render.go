@@ -0,0 +1,142 @@
+package pcircle
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Default sample rate of rendered hit sound tracks.
+const DEFAULT_SAMPLE_RATE = 44100
+
+// HitSoundRenderOptions specifies how hit sound track is rendered.
+type HitSoundRenderOptions struct {
+	SampleRate    int    // Sample rate of the result, DEFAULT_SAMPLE_RATE if zero
+	SkinDirectory string // Directory with default skin samples, skin samples are not played if empty
+	Length        int    // Minimal length of the result in milliseconds, such as length of the beatmap audio
+}
+
+// HitSoundRender is a rendered hit sound track.
+type HitSoundRender struct {
+	Audio   *Audio
+	Missing []string // Sample files which were not found or could not be decoded
+}
+
+// RenderHitSounds mixes all hit sound samples of the beatmap into a single audio track
+// which starts at time 0 and lasts at least until the end of the last hit object, or
+// HitSoundRenderOptions.Length if it is longer. Custom samples are loaded from the mapset
+// directory, skin samples from the skin directory. Only WAV samples are supported.
+func (m *Mapset) RenderHitSounds(b *Beatmap, opts HitSoundRenderOptions) (*HitSoundRender, error) {
+	rate := opts.SampleRate
+	if rate <= 0 {
+		rate = DEFAULT_SAMPLE_RATE
+	}
+
+	samples := m.HitSamples(b)
+	cache := make(map[string]*Audio)
+	render := &HitSoundRender{Audio: &Audio{SampleRate: rate}}
+	missing := make(map[string]bool)
+
+	load := func(s *HitSample) (*Audio, error) {
+		path := filepath.Join(m.DirectoryPath, s.Filename)
+		if s.Skin {
+			if opts.SkinDirectory == "" {
+				return nil, nil
+			}
+			filename, ok := lookupSampleIn(opts.SkinDirectory, strings.TrimSuffix(s.Filename, filepath.Ext(s.Filename)))
+			if !ok {
+				return nil, nil
+			}
+			path = filepath.Join(opts.SkinDirectory, filename)
+		}
+
+		if a, ok := cache[path]; ok {
+			return a, nil
+		}
+
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			cache[path] = nil
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		a, err := DecodeWAV(f)
+		if err != nil {
+			// unsupported or broken samples are reported as missing
+			cache[path] = nil
+			return nil, nil
+		}
+		a = a.Resample(rate)
+		cache[path] = a
+		return a, nil
+	}
+
+	for _, s := range samples {
+		a, err := load(s)
+		if err != nil {
+			return nil, err
+		}
+		if a == nil {
+			if !missing[s.Filename] && (!s.Skin || opts.SkinDirectory != "") {
+				missing[s.Filename] = true
+				render.Missing = append(render.Missing, s.Filename)
+			}
+			continue
+		}
+		render.Audio.mix(a, s.Time, s.Duration, float32(s.Volume)/100)
+	}
+
+	length := opts.Length
+	for _, hitObject := range b.HitObjects {
+		length = max(length, b.EndTime(hitObject))
+	}
+	render.Audio.pad(length)
+
+	return render, nil
+}
+
+// WriteHitSounds renders hit sounds of the beatmap and writes them as WAV file.
+// See Mapset.RenderHitSounds.
+func (m *Mapset) WriteHitSounds(b *Beatmap, w io.Writer, opts HitSoundRenderOptions) error {
+	render, err := m.RenderHitSounds(b, opts)
+	if err != nil {
+		return err
+	}
+	return render.Audio.EncodeWAV(w)
+}
+
+// pad appends silence to the track, so that it lasts at least specified number of milliseconds.
+func (a *Audio) pad(length int) {
+	if need := length * a.SampleRate / 1000 * 2; need > len(a.Samples) {
+		a.Samples = append(a.Samples, make([]float32, need-len(a.Samples))...)
+	}
+}
+
+// mix adds sample to the track at specified time. If duration is positive,
+// sample is looped for that many milliseconds.
+func (a *Audio) mix(sample *Audio, time, duration int, volume float32) {
+	if time < 0 || sample.Frames() == 0 {
+		return
+	}
+
+	start := time * a.SampleRate / 1000
+	frames := sample.Frames()
+	if duration > 0 {
+		frames = duration * a.SampleRate / 1000
+	}
+
+	if need := (start + frames) * 2; need > len(a.Samples) {
+		a.Samples = append(a.Samples, make([]float32, need-len(a.Samples))...)
+	}
+
+	for i := 0; i < frames; i++ {
+		j := i % sample.Frames()
+		a.Samples[2*(start+i)] += sample.Samples[2*j] * volume
+		a.Samples[2*(start+i)+1] += sample.Samples[2*j+1] * volume
+	}
+}
@@ -0,0 +1,296 @@
+package pcircle
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScanError is an error of a single file or directory found by Scanner.
+type ScanError struct {
+	Path string
+	Err  error
+}
+
+// Error returns ScanError in readable format.
+func (e *ScanError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// ScanResult is a mapset directory scanned by Scanner.
+type ScanResult struct {
+	Mapset *Mapset      // Beatmaps and storyboard of the directory, files which could not be parsed are left out
+	Errors []*ScanError // Errors of files which could not be read or parsed
+	Cached int          // Number of beatmaps taken from the cache
+}
+
+// Scanner scans osu! Songs directory and parses its mapsets concurrently.
+type Scanner struct {
+	Workers  int                      // Number of mapsets parsed at once, the number of CPUs if not positive
+	Cache    *ScanCache               // Cache of parsed beatmaps, nil to parse all files
+	Progress func(scanned, total int) // Called after each scanned mapset, if not nil
+}
+
+// NewScanner returns a new Scanner with a worker per CPU and no cache.
+func NewScanner() *Scanner {
+	return &Scanner{Workers: runtime.NumCPU()}
+}
+
+// Scan parses mapsets in subdirectories of songs directory and calls fn with the result
+// of each of them in order of completion. Calls of fn and Progress are not concurrent.
+// Errors of single files are reported in results, Scan only fails if songs directory
+// could not be read or ctx was cancelled, in which case remaining mapsets are skipped
+// and fn is not called with mapsets whose scan was interrupted.
+func (s *Scanner) Scan(ctx context.Context, songs string, fn func(*ScanResult)) error {
+	entries, err := os.ReadDir(songs)
+	if err != nil {
+		return err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(songs, entry.Name()))
+		}
+	}
+
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if s.Cache != nil {
+		s.Cache.startScan()
+	}
+
+	jobs := make(chan string)
+	go func() {
+		defer close(jobs)
+		for _, dir := range dirs {
+			select {
+			case jobs <- dir:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan *ScanResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dir := range jobs {
+				r := s.scanMapset(ctx, dir)
+				if ctx.Err() != nil {
+					// mapset may have been scanned only partially
+					return
+				}
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	scanned := 0
+	for r := range results {
+		scanned++
+		fn(r)
+		if s.Progress != nil {
+			s.Progress(scanned, len(dirs))
+		}
+	}
+	if s.Cache != nil {
+		s.Cache.finishScan(ctx.Err() == nil)
+	}
+	return ctx.Err()
+}
+
+// scanMapset parses beatmaps and storyboard of a mapset directory.
+func (s *Scanner) scanMapset(ctx context.Context, dir string) *ScanResult {
+	r := &ScanResult{Mapset: NewMapset()}
+	r.Mapset.DirectoryPath = dir
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		r.Errors = append(r.Errors, &ScanError{dir, err})
+		return r
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		path := filepath.Join(dir, entry.Name())
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".osu":
+			b, cached, err := s.loadBeatmap(path, entry)
+			if err != nil {
+				r.Errors = append(r.Errors, &ScanError{path, err})
+				continue
+			}
+			if cached {
+				r.Cached++
+			}
+			r.Mapset.Beatmaps = append(r.Mapset.Beatmaps, b)
+		case ".osb":
+			if r.Mapset.Storyboard != nil {
+				continue
+			}
+			sb := new(Storyboard)
+			if err := sb.FromFile(path); err != nil {
+				r.Errors = append(r.Errors, &ScanError{path, err})
+				continue
+			}
+			r.Mapset.Storyboard = sb
+		}
+	}
+
+	if len(r.Mapset.Beatmaps) > 0 {
+		r.Mapset.BeatmapSetID = r.Mapset.Beatmaps[0].BeatmapSetID
+	}
+	return r
+}
+
+// loadBeatmap parses .osu file or takes it from the cache, if it did not change.
+func (s *Scanner) loadBeatmap(path string, entry os.DirEntry) (b *Beatmap, cached bool, err error) {
+	fi, err := entry.Info()
+	if err != nil {
+		return nil, false, err
+	}
+	if s.Cache != nil {
+		if b := s.Cache.get(path, fi); b != nil {
+			return b, true, nil
+		}
+	}
+
+	b = NewBeatmap()
+	if err := b.FromFile(path); err != nil {
+		return nil, false, err
+	}
+	if s.Cache != nil {
+		s.Cache.put(path, fi, b)
+	}
+	return b, false, nil
+}
+
+// ScanCache keeps parsed beatmaps by path, so that Scanner skips files whose modification
+// time and size did not change. Entries of files which were not found by the last scan
+// are removed. It is safe for concurrent use, but must not be used by several scans at
+// once, and can be kept between scans with ToFile and FromFile. The zero value is an
+// empty cache.
+type ScanCache struct {
+	mu      sync.Mutex
+	entries map[string]*scanCacheEntry
+	seen    map[string]bool // Paths found during the current scan, nil between scans
+}
+
+// scanCacheEntry is a beatmap in ScanCache.
+type scanCacheEntry struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	Beatmap *Beatmap  `json:"beatmap"`
+}
+
+// NewScanCache returns a new empty ScanCache.
+func NewScanCache() *ScanCache {
+	return &ScanCache{entries: make(map[string]*scanCacheEntry)}
+}
+
+// Len returns the number of cached beatmaps.
+func (c *ScanCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// startScan starts recording paths found by a scan.
+func (c *ScanCache) startScan() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen = make(map[string]bool)
+}
+
+// finishScan stops recording paths found by a scan and, if it was complete, removes
+// entries of paths which it did not find.
+func (c *ScanCache) finishScan(complete bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for path := range c.entries {
+		if complete && !c.seen[path] {
+			delete(c.entries, path)
+		}
+	}
+	c.seen = nil
+}
+
+// get returns copy of cached beatmap of file, or nil if it is not cached or changed.
+func (c *ScanCache) get(path string, fi os.FileInfo) *Beatmap {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seen != nil {
+		c.seen[path] = true
+	}
+	e, ok := c.entries[path]
+	if !ok || !e.ModTime.Equal(fi.ModTime()) || e.Size != fi.Size() {
+		return nil
+	}
+	return e.Beatmap.Clone()
+}
+
+// put stores copy of beatmap parsed from file.
+func (c *ScanCache) put(path string, fi os.FileInfo, b *Beatmap) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*scanCacheEntry)
+	}
+	c.entries[path] = &scanCacheEntry{ModTime: fi.ModTime(), Size: fi.Size(), Beatmap: b.Clone()}
+}
+
+// ToFile writes ScanCache to specified file as JSON.
+func (c *ScanCache) ToFile(path string) error {
+	c.mu.Lock()
+	data, err := json.Marshal(c.entries)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// FromFile loads ScanCache written by ToFile, replacing its entries.
+func (c *ScanCache) FromFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var entries map[string]*scanCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	if entries == nil {
+		entries = make(map[string]*scanCacheEntry)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = entries
+	return nil
+}
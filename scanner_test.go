@@ -0,0 +1,186 @@
+package pcircle
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// writeTestSongs writes Songs directory with three mapsets, one of which has a broken
+// beatmap, and returns its path.
+func writeTestSongs(t *testing.T) string {
+	songs := t.TempDir()
+	for _, name := range []string{"1 Artist - First", "2 Artist - Second", "3 Artist - Broken"} {
+		dir := filepath.Join(songs, name)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, version := range []string{"Easy", "Hard"} {
+			b := newTestBeatmap()
+			b.Version = version
+			if err := b.ToFile(filepath.Join(dir, version+".osu")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	broken := filepath.Join(songs, "3 Artist - Broken", "Hard.osu")
+	if err := os.WriteFile(broken, []byte("osu file format v14\n\n[Difficulty]\nCircleSize:four\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// files in Songs directory itself are not mapsets
+	if err := os.WriteFile(filepath.Join(songs, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return songs
+}
+
+// scanTest scans songs directory and returns results sorted by directory.
+func scanTest(t *testing.T, s *Scanner, songs string) []*ScanResult {
+	var results []*ScanResult
+	if err := s.Scan(context.Background(), songs, func(r *ScanResult) {
+		results = append(results, r)
+	}); err != nil {
+		t.Fatalf("Scanner.Scan() error = %v", err)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Mapset.DirectoryPath < results[j].Mapset.DirectoryPath
+	})
+	return results
+}
+
+func TestScanner_Scan(t *testing.T) {
+	songs := writeTestSongs(t)
+
+	s := NewScanner()
+	s.Workers = 2
+	s.Cache = NewScanCache()
+	var mu sync.Mutex
+	var progress []int
+	s.Progress = func(scanned, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if total != 3 {
+			t.Errorf("Scanner.Progress total = %v, want 3", total)
+		}
+		progress = append(progress, scanned)
+	}
+
+	results := scanTest(t, s, songs)
+	if len(results) != 3 {
+		t.Fatalf("Scanner.Scan() results = %v, want 3", len(results))
+	}
+	if len(progress) != 3 || progress[2] != 3 {
+		t.Errorf("Scanner.Progress calls = %v, want 1, 2, 3", progress)
+	}
+	for i, want := range []int{2, 2, 1} {
+		r := results[i]
+		if len(r.Mapset.Beatmaps) != want || r.Cached != 0 {
+			t.Errorf("Scanner.Scan() %v beatmaps = %v, cached %v, want %v", r.Mapset.DirectoryPath, len(r.Mapset.Beatmaps), r.Cached, want)
+		}
+	}
+	if errs := results[2].Errors; len(errs) != 1 || filepath.Base(errs[0].Path) != "Hard.osu" {
+		t.Errorf("Scanner.Scan() errors = %v, want error of Hard.osu", errs)
+	}
+	if s.Cache.Len() != 5 {
+		t.Errorf("ScanCache.Len() = %v, want 5", s.Cache.Len())
+	}
+
+	// unchanged files are taken from the cache, also after it is stored
+	changed := filepath.Join(songs, "1 Artist - First", "Easy.osu")
+	b := newTestBeatmap()
+	b.Version, b.Title = "Easy", "Changed"
+	if err := b.ToFile(changed); err != nil {
+		t.Fatal(err)
+	}
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	if err := s.Cache.ToFile(cachePath); err != nil {
+		t.Fatalf("ScanCache.ToFile() error = %v", err)
+	}
+	s.Cache = NewScanCache()
+	if err := s.Cache.FromFile(cachePath); err != nil {
+		t.Fatalf("ScanCache.FromFile() error = %v", err)
+	}
+	s.Progress = nil
+
+	results = scanTest(t, s, songs)
+	for i, want := range []int{1, 2, 1} {
+		if got := results[i].Cached; got != want {
+			t.Errorf("Scanner.Scan() %v cached = %v, want %v", results[i].Mapset.DirectoryPath, got, want)
+		}
+	}
+	titles := map[string]string{}
+	for _, b := range results[0].Mapset.Beatmaps {
+		titles[b.Version] = b.Title
+	}
+	if titles["Easy"] != "Changed" || len(results[1].Mapset.Beatmaps[0].HitObjects) != 3 {
+		t.Errorf("Scanner.Scan() with cache = %v, %v hit objects", titles, len(results[1].Mapset.Beatmaps[0].HitObjects))
+	}
+}
+
+func TestScanner_Scan_cancel(t *testing.T) {
+	songs := writeTestSongs(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewScanner().Scan(ctx, songs, func(*ScanResult) {})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Scanner.Scan() error = %v, want %v", err, context.Canceled)
+	}
+
+	// mapsets interrupted by cancellation are not delivered
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := NewScanner()
+		s.Workers = 3
+		err := s.Scan(ctx, songs, func(r *ScanResult) {
+			cancel()
+			if got := len(r.Mapset.Beatmaps) + len(r.Errors); got != 2 {
+				t.Errorf("Scanner.Scan() %v delivered %v of 2 beatmaps after cancellation", r.Mapset.DirectoryPath, got)
+			}
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Scanner.Scan() error = %v, want %v", err, context.Canceled)
+		}
+	}
+
+	if err := NewScanner().Scan(context.Background(), filepath.Join(songs, "missing"), func(*ScanResult) {}); err == nil {
+		t.Errorf("Scanner.Scan() of missing directory error = nil, want error")
+	}
+}
+
+func TestScanCache(t *testing.T) {
+	songs := writeTestSongs(t)
+
+	// zero value and cache loaded from "null" are empty caches
+	null := filepath.Join(t.TempDir(), "null.json")
+	if err := os.WriteFile(null, []byte("null"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewScanCache()
+	if err := loaded.FromFile(null); err != nil {
+		t.Fatalf("ScanCache.FromFile(null) error = %v", err)
+	}
+	for _, c := range []*ScanCache{{}, loaded} {
+		s := NewScanner()
+		s.Cache = c
+		scanTest(t, s, songs)
+		if c.Len() != 5 {
+			t.Errorf("ScanCache.Len() = %v, want 5", c.Len())
+		}
+	}
+
+	// entries of deleted files are removed
+	if err := os.RemoveAll(filepath.Join(songs, "2 Artist - Second")); err != nil {
+		t.Fatal(err)
+	}
+	s := NewScanner()
+	s.Cache = loaded
+	scanTest(t, s, songs)
+	if loaded.Len() != 3 {
+		t.Errorf("ScanCache.Len() after deletion = %v, want 3", loaded.Len())
+	}
+}
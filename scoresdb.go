@@ -0,0 +1,231 @@
+package pcircle
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// NewScoresDB returns a new empty ScoresDB.
+func NewScoresDB() *ScoresDB {
+	return &ScoresDB{Scores: make(map[string][]*Score)}
+}
+
+// ScoresDB stores contents of scores.db, the local score database of osu! client.
+type ScoresDB struct {
+	Version int                 // Version of the client which wrote the file, like 20250108
+	Scores  map[string][]*Score // Scores by MD5 hash of the beatmap .osu file
+}
+
+// Score is a single play of a beatmap, with the same fields as the header of .osr replay file.
+type Score struct {
+	GameMode    int
+	Version     int    // Version of the client which set the score
+	BeatmapMD5  string // MD5 hash of the beatmap .osu file
+	PlayerName  string
+	ReplayMD5   string
+	Count300    int
+	Count100    int
+	Count50     int
+	CountGeki   int // Max 300s in osu!mania
+	CountKatu   int // 200s in osu!mania, missed droplets in osu!catch
+	CountMiss   int
+	TotalScore  int
+	MaxCombo    int
+	Perfect     bool // Whether there were no misses and slider breaks
+	Mods        Mods
+	Timestamp   time.Time
+	OnlineID    int64   // Online score ID, zero if the score was not submitted
+	TargetsInfo float64 // Additional mod information, only used by Target Practice
+}
+
+// FromFile parses specified scores.db file and fills ScoresDB with data.
+func (db *ScoresDB) FromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return db.FromReader(f)
+}
+
+// FromReader parses scores.db from r and fills ScoresDB with data.
+func (db *ScoresDB) FromReader(r io.Reader) error {
+	dr := newDBReader(r)
+
+	db.Version = dr.int()
+	count := dr.int()
+	if dr.err != nil {
+		return dr.err
+	}
+	if count < 0 {
+		return errors.New("invalid number of beatmaps")
+	}
+
+	db.Scores = make(map[string][]*Score, count)
+	for i := 0; i < count; i++ {
+		hash := dr.string()
+		n := dr.int()
+		if dr.err == nil && n < 0 {
+			return errors.New("invalid number of scores of beatmap " + hash)
+		}
+		for j := 0; j < n && dr.err == nil; j++ {
+			s := new(Score)
+			s.read(dr)
+			db.Scores[hash] = append(db.Scores[hash], s)
+		}
+		if dr.err != nil {
+			return dr.err
+		}
+	}
+	return nil
+}
+
+// read reads single score.
+func (s *Score) read(r *dbReader) {
+	s.GameMode = int(r.byte())
+	s.Version = r.int()
+	s.BeatmapMD5 = r.string()
+	s.PlayerName = r.string()
+	s.ReplayMD5 = r.string()
+	s.Count300 = r.short()
+	s.Count100 = r.short()
+	s.Count50 = r.short()
+	s.CountGeki = r.short()
+	s.CountKatu = r.short()
+	s.CountMiss = r.short()
+	s.TotalScore = r.int()
+	s.MaxCombo = r.short()
+	s.Perfect = r.bool()
+	s.Mods = Mods(r.int())
+	r.string() // life bar graph, always empty in scores.db
+	s.Timestamp = r.dateTime()
+	r.int() // length of compressed replay data, always -1 in scores.db
+	s.OnlineID = r.long()
+	if s.Mods.Has(TARGET_MOD) {
+		s.TargetsInfo = r.double()
+	}
+}
+
+// ScoresOf returns scores of specified beatmap, linked by MD5 hash of its .osu file.
+func (db *ScoresDB) ScoresOf(b *Beatmap) ([]*Score, error) {
+	hash, err := b.MD5()
+	if err != nil {
+		return nil, err
+	}
+	return db.Scores[hash], nil
+}
+
+// PersonalBests returns the highest score of specified player on every beatmap.
+// Scores of all players are considered if player is empty.
+func (db *ScoresDB) PersonalBests(player string) map[string]*Score {
+	bests := make(map[string]*Score)
+	for hash, scores := range db.Scores {
+		for _, s := range scores {
+			if player != "" && s.PlayerName != player {
+				continue
+			}
+			if best, ok := bests[hash]; !ok || s.TotalScore > best.TotalScore {
+				bests[hash] = s
+			}
+		}
+	}
+	return bests
+}
+
+// SortedScores returns scores of beatmap with specified hash from the highest to the lowest.
+func (db *ScoresDB) SortedScores(hash string) []*Score {
+	scores := append([]*Score(nil), db.Scores[hash]...)
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].TotalScore > scores[j].TotalScore
+	})
+	return scores
+}
+
+// Accuracy returns accuracy of the score in range from 0 to 1, calculated the way
+// game mode of the score does it.
+func (s *Score) Accuracy() float64 {
+	var hit, total float64
+	switch s.GameMode {
+	case TAIKO_GAMEMODE:
+		hit = float64(s.Count300) + float64(s.Count100)/2
+		total = float64(s.Count300 + s.Count100 + s.CountMiss)
+	case CTB_GAMEMODE:
+		hit = float64(s.Count300 + s.Count100 + s.Count50)
+		total = float64(s.Count300 + s.Count100 + s.Count50 + s.CountKatu + s.CountMiss)
+	case MANIA_GAMEMODE:
+		hit = float64(300*(s.Count300+s.CountGeki)+200*s.CountKatu+100*s.Count100+50*s.Count50) / 300
+		total = float64(s.Count300 + s.CountGeki + s.CountKatu + s.Count100 + s.Count50 + s.CountMiss)
+	default:
+		hit = float64(300*s.Count300+100*s.Count100+50*s.Count50) / 300
+		total = float64(s.Count300 + s.Count100 + s.Count50 + s.CountMiss)
+	}
+	if total == 0 {
+		return 1
+	}
+	return hit / total
+}
+
+// Grade returns grade of the score as osu! stable calculates it.
+func (s *Score) Grade() Grade {
+	if s.Count300+s.Count100+s.Count50+s.CountGeki+s.CountKatu+s.CountMiss == 0 {
+		return NO_GRADE
+	}
+	silver := s.Mods.Has(HIDDEN_MOD) || s.Mods.Has(FLASHLIGHT_MOD) || s.Mods.Has(FADE_IN_MOD)
+	accuracy := s.Accuracy()
+
+	var grade Grade
+	switch s.GameMode {
+	case CTB_GAMEMODE, MANIA_GAMEMODE:
+		thresholds := []float64{0.98, 0.94, 0.9, 0.85}
+		if s.GameMode == MANIA_GAMEMODE {
+			thresholds = []float64{0.95, 0.9, 0.8, 0.7}
+		}
+		grade = D_GRADE
+		switch {
+		case accuracy == 1:
+			grade = X_GRADE
+		case accuracy > thresholds[0]:
+			grade = S_GRADE
+		case accuracy > thresholds[1]:
+			grade = A_GRADE
+		case accuracy > thresholds[2]:
+			grade = B_GRADE
+		case accuracy > thresholds[3]:
+			grade = C_GRADE
+		}
+	default:
+		total := s.Count300 + s.Count100 + s.Count50 + s.CountMiss
+		if total == 0 {
+			// geki and katu are counted among 300 and 100 in these modes
+			return NO_GRADE
+		}
+		ratio300 := float64(s.Count300) / float64(total)
+		ratio50 := float64(s.Count50) / float64(total)
+		grade = D_GRADE
+		switch {
+		case ratio300 == 1:
+			grade = X_GRADE
+		case ratio300 > 0.9 && ratio50 <= 0.01 && s.CountMiss == 0:
+			grade = S_GRADE
+		case ratio300 > 0.8 && s.CountMiss == 0 || ratio300 > 0.9:
+			grade = A_GRADE
+		case ratio300 > 0.7 && s.CountMiss == 0 || ratio300 > 0.8:
+			grade = B_GRADE
+		case ratio300 > 0.6:
+			grade = C_GRADE
+		}
+	}
+
+	if silver {
+		switch grade {
+		case X_GRADE:
+			return XH_GRADE
+		case S_GRADE:
+			return SH_GRADE
+		}
+	}
+	return grade
+}
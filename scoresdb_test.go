@@ -0,0 +1,69 @@
+package pcircle
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestScoresDB_FromReader(t *testing.T) {
+	hash := "0123456789abcdef0123456789abcdef"
+	score := func(player string, total int32, mods Mods) []interface{} {
+		values := []interface{}{byte(OSU_GAMEMODE), int32(20250108), hash, player, "replay",
+			int16(95), int16(5), int16(0), int16(10), int16(3), int16(0), total, int16(150), false, int32(mods),
+			"", int64(638000000000000000), int32(-1), int64(12345)}
+		if mods.Has(TARGET_MOD) {
+			values = append(values, float64(3))
+		}
+		return values
+	}
+
+	db := new(dbBuffer)
+	db.put(int32(20250108), int32(1), hash, int32(3))
+	db.put(score("Player", 1000000, HIDDEN_MOD)...)
+	db.put(score("Player", 2000000, TARGET_MOD)...)
+	db.put(score("Other", 3000000, NO_MOD)...)
+
+	got := NewScoresDB()
+	if err := got.FromReader(bytes.NewReader(db.Bytes())); err != nil {
+		t.Fatalf("ScoresDB.FromReader() error = %v", err)
+	}
+	scores := got.Scores[hash]
+	if len(scores) != 3 {
+		t.Fatalf("ScoresDB.FromReader() scores = %v", scores)
+	}
+	if scores[1].TargetsInfo != 3 || scores[2].OnlineID != 12345 || scores[0].Timestamp.Year() != 2022 {
+		t.Errorf("ScoresDB.FromReader() scores = %+v, %+v, %+v", scores[0], scores[1], scores[2])
+	}
+	if best := got.PersonalBests("Player")[hash]; best != scores[1] {
+		t.Errorf("ScoresDB.PersonalBests() = %+v, want %+v", best, scores[1])
+	}
+}
+
+func TestScore_Grade(t *testing.T) {
+	tests := []struct {
+		name         string
+		score        Score
+		wantAccuracy float64
+		wantGrade    Grade
+	}{
+		{"osu SS", Score{Count300: 100}, 1, X_GRADE},
+		{"osu hidden S", Score{Count300: 95, Count100: 5, Mods: HIDDEN_MOD}, 0.9667, SH_GRADE},
+		{"osu miss A", Score{Count300: 95, Count100: 4, CountMiss: 1}, 0.9633, A_GRADE},
+		{"taiko", Score{GameMode: TAIKO_GAMEMODE, Count300: 90, Count100: 10}, 0.95, A_GRADE},
+		{"mania", Score{GameMode: MANIA_GAMEMODE, CountGeki: 50, Count300: 40, CountKatu: 10}, 0.9667, S_GRADE},
+		{"empty", Score{}, 1, NO_GRADE},
+		{"osu only geki and katu", Score{CountGeki: 3, CountKatu: 2}, 1, NO_GRADE},
+		{"taiko only geki", Score{GameMode: TAIKO_GAMEMODE, CountGeki: 3, Mods: HIDDEN_MOD}, 1, NO_GRADE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.score.Accuracy(); math.Abs(got-tt.wantAccuracy) > 1e-4 {
+				t.Errorf("Score.Accuracy() = %v, want %v", got, tt.wantAccuracy)
+			}
+			if got := tt.score.Grade(); got != tt.wantGrade {
+				t.Errorf("Score.Grade() = %v, want %v", got, tt.wantGrade)
+			}
+		})
+	}
+}
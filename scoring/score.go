@@ -0,0 +1,268 @@
+package scoring
+
+import (
+	"errors"
+	"math"
+
+	pcircle "github.com/Polkisss/osu-parser"
+)
+
+// Maximal score of normalised scoring systems: ScoreV2 and lazer standardised score.
+const MAX_NORMALISED_SCORE = 1000000
+
+// ErrUnsupportedGameMode is returned when the scoring system is not implemented for a game mode.
+var ErrUnsupportedGameMode = errors.New("scoring system is not supported for the game mode")
+
+// Score multipliers of mods in osu! stable ScoreV1 and lazer.
+var modMultipliers = []struct {
+	mods       pcircle.Mods
+	multiplier float64
+	mania      float64 // osu!mania multiplier
+}{
+	{pcircle.NO_FAIL_MOD, 0.5, 0.5},
+	{pcircle.EASY_MOD, 0.5, 0.5},
+	{pcircle.HALF_TIME_MOD, 0.3, 0.5},
+	{pcircle.HIDDEN_MOD, 1.06, 1},
+	{pcircle.HARD_ROCK_MOD, 1.06, 1},
+	{pcircle.DOUBLE_TIME_MOD, 1.12, 1},
+	{pcircle.FLASHLIGHT_MOD, 1.12, 1},
+	{pcircle.SPUN_OUT_MOD, 0.9, 1},
+	{pcircle.RELAX_MOD, 0, 0},
+	{pcircle.AUTOPILOT_MOD, 0, 0},
+}
+
+// Score multipliers of mods in osu! stable ScoreV2, where they differ from ScoreV1.
+var scoreV2ModMultipliers = map[pcircle.Mods]float64{
+	pcircle.NO_FAIL_MOD:     1,
+	pcircle.HARD_ROCK_MOD:   1.1,
+	pcircle.DOUBLE_TIME_MOD: 1.2,
+}
+
+// ModMultiplier returns ScoreV1 score multiplier of mods in specified game mode.
+func ModMultiplier(mode int, mods pcircle.Mods) float64 {
+	multiplier := 1.0
+	for _, m := range modMultipliers {
+		if !mods.Has(m.mods) {
+			continue
+		}
+		if mode == pcircle.MANIA_GAMEMODE {
+			multiplier *= m.mania
+		} else {
+			multiplier *= m.multiplier
+		}
+	}
+	return multiplier
+}
+
+// scoreV2ModMultiplier returns ScoreV2 score multiplier of mods in specified game mode.
+func scoreV2ModMultiplier(mode int, mods pcircle.Mods) float64 {
+	multiplier := 1.0
+	for _, m := range modMultipliers {
+		if !mods.Has(m.mods) {
+			continue
+		}
+		if v, ok := scoreV2ModMultipliers[m.mods]; ok && mode != pcircle.MANIA_GAMEMODE {
+			multiplier *= v
+		} else if mode == pcircle.MANIA_GAMEMODE {
+			multiplier *= m.mania
+		} else {
+			multiplier *= m.multiplier
+		}
+	}
+	return multiplier
+}
+
+// DifficultyMultiplier returns ScoreV1 difficulty multiplier of the beatmap, which
+// depends on HP, CS, OD, and the number of hit objects per second of drain time.
+func DifficultyMultiplier(b *pcircle.Beatmap) int {
+	drain := math.Max(float64(b.DrainTime())/1000, 1)
+	density := math.Min(math.Max(float64(len(b.HitObjects))/drain*8, 0), 16)
+	points := (b.HPDrainRate + b.CircleSize + b.OverallDifficulty + density) / 38 * 5
+	return int(math.Round(points))
+}
+
+// MaxScoreV1 returns the highest osu! stable ScoreV1 possible on the beatmap with
+// specified mods. Bonus of spinners, drum rolls, swells and bananas is not included,
+// neither are tiny droplets of osu!catch.
+//
+// In osu! and osu!catch hit values are increased by combo bonus, which grows with combo,
+// difficulty multiplier and mod multiplier. In osu!taiko hits gain bonus for every 10
+// combo up to 100, are increased by 20% in kiai time and doubled for finishers.
+// In osu!mania ScoreV1 is always normalised to 1,000,000 multiplied by mod multiplier.
+func MaxScoreV1(b *pcircle.Beatmap, mods pcircle.Mods) (int, error) {
+	switch b.GameMode {
+	case pcircle.OSU_GAMEMODE:
+		return maxScoreV1Osu(b, mods), nil
+	case pcircle.TAIKO_GAMEMODE:
+		return maxScoreV1Taiko(b, mods), nil
+	case pcircle.CTB_GAMEMODE:
+		return maxScoreV1Catch(b, mods), nil
+	case pcircle.MANIA_GAMEMODE:
+		return int(math.Round(MAX_NORMALISED_SCORE * ModMultiplier(b.GameMode, mods))), nil
+	}
+	return 0, ErrUnsupportedGameMode
+}
+
+// comboBonus returns ScoreV1 combo bonus of osu! and osu!catch of hit value at specified
+// combo before the hit.
+func comboBonus(value, combo int, bonusMultiplier float64) int {
+	return int(float64(value) * float64(max(combo-1, 0)) * bonusMultiplier)
+}
+
+// maxScoreV1Osu returns the highest ScoreV1 of osu! beatmap.
+func maxScoreV1Osu(b *pcircle.Beatmap, mods pcircle.Mods) int {
+	bonusMultiplier := float64(DifficultyMultiplier(b)) * ModMultiplier(b.GameMode, mods) / 25
+
+	score, combo := 0, 0
+	for _, hitObject := range b.HitObjects {
+		switch o := hitObject.(type) {
+		case *pcircle.Slider:
+			// head, ticks, repeats and tail give flat score and combo,
+			// then the whole slider is judged as a 300
+			edges := sliderSpans(o) + 1
+			ticks := len(b.SliderTickTimes(o))
+			score += edges*30 + ticks*10
+			combo += edges + ticks
+			score += 300 + comboBonus(300, combo, bonusMultiplier)
+		default:
+			score += 300 + comboBonus(300, combo, bonusMultiplier)
+			combo++
+		}
+	}
+	return score
+}
+
+// maxScoreV1Taiko returns the highest ScoreV1 of osu!taiko beatmap. Only hits give score.
+func maxScoreV1Taiko(b *pcircle.Beatmap, mods pcircle.Mods) int {
+	bonus := int(float64(300/35*2) * float64(DifficultyMultiplier(b)+1) * ModMultiplier(b.GameMode, mods))
+
+	score, combo := 0, 0
+	for _, hitObject := range b.HitObjects {
+		o, ok := hitObject.(*pcircle.Circle)
+		if !ok {
+			continue
+		}
+		value := 300 + bonus*(min(combo, 100)/10)
+		if tp := b.TimingPointAt(o.Time); tp != nil && tp.Kiai {
+			value = int(float64(value) * 1.2)
+		}
+		if o.HitSound&pcircle.FINISH_HITSOUND != 0 {
+			value *= 2
+		}
+		score += value
+		combo++
+	}
+	return score
+}
+
+// maxScoreV1Catch returns the highest ScoreV1 of osu!catch beatmap. Fruits, at circles
+// and at heads, repeats and tails of sliders, give 300 with combo bonus, droplets at
+// slider ticks give flat 100.
+func maxScoreV1Catch(b *pcircle.Beatmap, mods pcircle.Mods) int {
+	bonusMultiplier := float64(DifficultyMultiplier(b)) * ModMultiplier(b.GameMode, mods) / 25
+
+	score, combo := 0, 0
+	fruit := func() {
+		score += 300 + comboBonus(300, combo, bonusMultiplier)
+		combo++
+	}
+	for _, hitObject := range b.HitObjects {
+		switch o := hitObject.(type) {
+		case *pcircle.Circle:
+			fruit()
+		case *pcircle.Slider:
+			ticks := b.SliderTickTimes(o)
+			spans := sliderSpans(o)
+			span := b.SliderDuration(o) / float64(spans)
+			fruit()
+			for i := 1; i <= spans; i++ {
+				end := float64(o.Time) + span*float64(i)
+				for len(ticks) > 0 && float64(ticks[0]) < end {
+					score += 100
+					combo++
+					ticks = ticks[1:]
+				}
+				fruit()
+			}
+		}
+	}
+	return score
+}
+
+// comboPortion estimates part of the combo portion of normalised scores achieved with
+// specified longest combo. Each judgement adds combo raised to exponent, and the play is
+// assumed to consist of combos as long as the longest one, which gives the upper bound.
+func comboPortion(combo, maxCombo int, exponent float64) float64 {
+	if maxCombo <= 0 {
+		return 1
+	}
+	if combo <= 0 {
+		return 0
+	}
+	if combo > maxCombo {
+		combo = maxCombo
+	}
+	sum := func(n int) float64 {
+		s := 0.0
+		for i := 1; i <= n; i++ {
+			s += math.Pow(float64(i), exponent)
+		}
+		return s
+	}
+	return (float64(maxCombo/combo)*sum(combo) + sum(maxCombo%combo)) / sum(maxCombo)
+}
+
+// judged returns ratio of judged hit objects to all hit objects of the beatmap, at most 1.
+func judged(b *pcircle.Beatmap, c Counts) float64 {
+	mode, objects := b.GameMode, len(b.HitObjects)
+	n := c.Count300 + c.Count100 + c.Count50 + c.CountMiss
+	if mode == pcircle.MANIA_GAMEMODE {
+		n += c.CountGeki + c.CountKatu
+	}
+	if objects <= 0 || n >= objects {
+		return 1
+	}
+	return float64(n) / float64(objects)
+}
+
+// ScoreV2 estimates osu! stable ScoreV2 of a play: 700,000 for combo and 300,000 for
+// accuracy to the power of 10, multiplied by mod multiplier. The combo portion is
+// estimated from the longest combo. Spinner bonus is not included.
+//
+// Only osu! is supported.
+func ScoreV2(b *pcircle.Beatmap, c Counts, combo int, mods pcircle.Mods) (int, error) {
+	if b.GameMode != pcircle.OSU_GAMEMODE {
+		return 0, ErrUnsupportedGameMode
+	}
+	maxCombo := MaxCombo(b)
+	accuracy := Accuracy(b.GameMode, c)
+	score := 700000*comboPortion(combo, maxCombo, 1) + 300000*math.Pow(accuracy, 10)*judged(b, c)
+	return int(math.Round(score * scoreV2ModMultiplier(b.GameMode, mods))), nil
+}
+
+// Weights of combo and accuracy portions of lazer standardised score, and exponents of
+// accuracy, for each game mode.
+var standardisedWeights = map[int]struct {
+	combo, accuracy, exponent float64
+}{
+	pcircle.OSU_GAMEMODE:   {700000, 300000, 10},
+	pcircle.TAIKO_GAMEMODE: {250000, 750000, 3.6},
+	pcircle.CTB_GAMEMODE:   {600000, 400000, 3.5},
+}
+
+// Standardised estimates lazer standardised score of a play, multiplied by mod multiplier.
+// The combo portion is estimated from the longest combo. Bonus is not included.
+func Standardised(b *pcircle.Beatmap, c Counts, combo int, mods pcircle.Mods) int {
+	maxCombo := MaxCombo(b)
+	accuracy := Accuracy(b.GameMode, c)
+	progress := judged(b, c)
+
+	var score float64
+	if b.GameMode == pcircle.MANIA_GAMEMODE {
+		score = 150000*comboPortion(combo, maxCombo, 0.5) + 850000*math.Pow(accuracy, 2+2*accuracy)*progress
+	} else {
+		w := standardisedWeights[b.GameMode]
+		score = w.combo*comboPortion(combo, maxCombo, 0.5) + w.accuracy*math.Pow(accuracy, w.exponent)*progress
+	}
+	return int(math.Round(score * ModMultiplier(b.GameMode, mods)))
+}
@@ -0,0 +1,79 @@
+// Package scoring computes max combo, accuracy, grades and scores of beatmaps
+// in the scoring systems of osu! stable and lazer.
+package scoring
+
+import (
+	pcircle "github.com/Polkisss/osu-parser"
+)
+
+// Counts are numbers of judgements of a play.
+// Meaning of geki and katu counts depends on game mode, see pcircle.Score.
+type Counts struct {
+	Count300  int
+	Count100  int
+	Count50   int
+	CountGeki int
+	CountKatu int
+	CountMiss int
+}
+
+// score returns pcircle.Score with specified counts.
+func (c Counts) score(mode int, mods pcircle.Mods) *pcircle.Score {
+	return &pcircle.Score{
+		GameMode:  mode,
+		Count300:  c.Count300,
+		Count100:  c.Count100,
+		Count50:   c.Count50,
+		CountGeki: c.CountGeki,
+		CountKatu: c.CountKatu,
+		CountMiss: c.CountMiss,
+		Mods:      mods,
+	}
+}
+
+// Accuracy returns accuracy of judgements in specified game mode in range from 0 to 1.
+func Accuracy(mode int, c Counts) float64 {
+	return c.score(mode, pcircle.NO_MOD).Accuracy()
+}
+
+// Grade returns grade of judgements in specified game mode. Hidden, Flashlight and
+// Fade In turn SS and S into their silver variants.
+func Grade(mode int, c Counts, mods pcircle.Mods) pcircle.Grade {
+	return c.score(mode, mods).Grade()
+}
+
+// MaxCombo returns the highest combo possible on the beatmap in its game mode.
+//
+// In osu! sliders give combo for the head, every tick, repeat and the tail, and spinners
+// for completion. In osu!taiko only hits give combo. In osu!catch fruits and droplets give
+// combo, tiny droplets and bananas do not. In osu!mania hold notes give combo for the head
+// and the tail, like in lazer.
+func MaxCombo(b *pcircle.Beatmap) int {
+	combo := 0
+	for _, hitObject := range b.HitObjects {
+		switch o := hitObject.(type) {
+		case *pcircle.Circle:
+			combo++
+		case *pcircle.Slider:
+			switch b.GameMode {
+			case pcircle.OSU_GAMEMODE, pcircle.CTB_GAMEMODE:
+				combo += sliderSpans(o) + 1 + len(b.SliderTickTimes(o))
+			}
+		case *pcircle.Spinner:
+			if b.GameMode == pcircle.OSU_GAMEMODE {
+				combo++
+			}
+		case *pcircle.ManiaHoldNote:
+			combo += 2
+		}
+	}
+	return combo
+}
+
+// sliderSpans returns number of slider spans, which is at least 1.
+func sliderSpans(s *pcircle.Slider) int {
+	if s.Repeat < 1 {
+		return 1
+	}
+	return s.Repeat
+}
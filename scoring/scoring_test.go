@@ -0,0 +1,157 @@
+package scoring
+
+import (
+	"math"
+	"testing"
+
+	pcircle "github.com/Polkisss/osu-parser"
+)
+
+func newTestBeatmap() *pcircle.Beatmap {
+	return &pcircle.Beatmap{
+		HPDrainRate:       5,
+		CircleSize:        4,
+		OverallDifficulty: 8,
+		SliderMultiplier:  1,
+		SliderTickRate:    1,
+		TimingPoints: []*pcircle.TimingPoint{
+			{Offset: 0, MillisecondsPerBeat: 500, Meter: 4, Inherited: true},
+		},
+		HitObjects: []interface{}{
+			&pcircle.Circle{BaseHitObject: pcircle.BaseHitObject{Time: 1000, Type: pcircle.CIRCLE}},
+			&pcircle.Slider{
+				BaseHitObject: pcircle.BaseHitObject{Time: 2000, Type: pcircle.SLIDER},
+				SliderPath:    &pcircle.SliderPath{SliderType: "L", CurvePoints: []*pcircle.SliderCurvePoint{{X: 200, Y: 0}}},
+				Repeat:        2,
+				PixelLength:   200, // two beats per span, one tick in each
+			},
+			&pcircle.Spinner{BaseHitObject: pcircle.BaseHitObject{Time: 5000, Type: pcircle.SPINNER}, EndTime: 6000},
+		},
+	}
+}
+
+func TestMaxCombo(t *testing.T) {
+	tests := []struct {
+		mode int
+		want int
+	}{
+		{pcircle.OSU_GAMEMODE, 1 + 5 + 1},
+		{pcircle.TAIKO_GAMEMODE, 1},
+		{pcircle.CTB_GAMEMODE, 1 + 5},
+	}
+	for _, tt := range tests {
+		b := newTestBeatmap()
+		b.GameMode = tt.mode
+		if got := MaxCombo(b); got != tt.want {
+			t.Errorf("MaxCombo(mode %d) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMaxScoreV1(t *testing.T) {
+	b := newTestBeatmap()
+	if got := DifficultyMultiplier(b); got != 3 {
+		t.Fatalf("DifficultyMultiplier() = %v, want 3", got)
+	}
+
+	// circle: 300 at combo 0; slider: 3*30 + 2*10 for edges and ticks, then 300 + 300*5*3/25
+	// at combo 6; spinner: 300 + 300*5*3/25 at combo 6
+	want := 300 + 110 + 300 + 180 + 300 + 180
+	if got, err := MaxScoreV1(b, pcircle.NO_MOD); err != nil || got != want {
+		t.Errorf("MaxScoreV1() = %v, %v, want %v", got, err, want)
+	}
+
+	// only the circle is a hit, drum roll and swell are bonus
+	b.GameMode = pcircle.TAIKO_GAMEMODE
+	if got, err := MaxScoreV1(b, pcircle.NO_MOD); err != nil || got != 300 {
+		t.Errorf("MaxScoreV1(taiko) = %v, %v, want 300", got, err)
+	}
+
+	// circle: 300; slider fruits at combo 1, 3 and 5 with droplets between; banana is bonus
+	b.GameMode = pcircle.CTB_GAMEMODE
+	want = 300 + 300 + 100 + (300 + 300*2*3/25) + 100 + (300 + 300*4*3/25)
+	if got, err := MaxScoreV1(b, pcircle.NO_MOD); err != nil || got != want {
+		t.Errorf("MaxScoreV1(catch) = %v, %v, want %v", got, err, want)
+	}
+
+	b.GameMode = pcircle.MANIA_GAMEMODE
+	if got, _ := MaxScoreV1(b, pcircle.NO_FAIL_MOD); got != 500000 {
+		t.Errorf("MaxScoreV1(mania, NF) = %v, want 500000", got)
+	}
+	b.GameMode = 4
+	if _, err := MaxScoreV1(b, pcircle.NO_MOD); err != ErrUnsupportedGameMode {
+		t.Errorf("MaxScoreV1(mode 4) error = %v, want %v", err, ErrUnsupportedGameMode)
+	}
+}
+
+func TestMaxScoreV1_taiko(t *testing.T) {
+	b := newTestBeatmap()
+	b.GameMode = pcircle.TAIKO_GAMEMODE
+	b.HitObjects = nil
+	for i := 0; i < 12; i++ {
+		b.HitObjects = append(b.HitObjects, &pcircle.Circle{BaseHitObject: pcircle.BaseHitObject{Time: 1000 + i*1000, Type: pcircle.CIRCLE}})
+	}
+	b.HitObjects[11].(*pcircle.Circle).HitSound = pcircle.FINISH_HITSOUND
+	multiplier := DifficultyMultiplier(b)
+	if multiplier != 3 {
+		t.Fatalf("DifficultyMultiplier() = %v, want 3", multiplier)
+	}
+
+	// hits from combo 10 gain 300/35*2*(3+1), the finisher is doubled
+	want := 10*300 + (300 + 64) + 2*(300+64)
+	if got, _ := MaxScoreV1(b, pcircle.NO_MOD); got != want {
+		t.Errorf("MaxScoreV1(taiko) = %v, want %v", got, want)
+	}
+
+	b.TimingPoints[0].Kiai = true
+	want = 10*360 + (300+64)*6/5 + 2*((300+64)*6/5)
+	if got, _ := MaxScoreV1(b, pcircle.NO_MOD); got != want {
+		t.Errorf("MaxScoreV1(taiko, kiai) = %v, want %v", got, want)
+	}
+}
+
+func TestNormalisedScores(t *testing.T) {
+	b := newTestBeatmap()
+	perfect := Counts{Count300: 3}
+
+	if got, _ := ScoreV2(b, perfect, MaxCombo(b), pcircle.NO_MOD); got != MAX_NORMALISED_SCORE {
+		t.Errorf("ScoreV2() = %v, want %v", got, MAX_NORMALISED_SCORE)
+	}
+	if got, _ := ScoreV2(b, perfect, MaxCombo(b), pcircle.HIDDEN_MOD|pcircle.HARD_ROCK_MOD); got != 1166000 {
+		t.Errorf("ScoreV2(HDHR) = %v, want 1166000", got)
+	}
+	if got := Standardised(b, perfect, MaxCombo(b), pcircle.NO_MOD); got != MAX_NORMALISED_SCORE {
+		t.Errorf("Standardised() = %v, want %v", got, MAX_NORMALISED_SCORE)
+	}
+	if got := Standardised(b, Counts{Count300: 2, CountMiss: 1}, 3, pcircle.NO_MOD); got >= MAX_NORMALISED_SCORE {
+		t.Errorf("Standardised() with a miss = %v, want less than perfect", got)
+	}
+
+	if got := Accuracy(pcircle.OSU_GAMEMODE, Counts{Count300: 2, Count100: 1}); math.Abs(got-7.0/9) > 1e-9 {
+		t.Errorf("Accuracy() = %v, want %v", got, 7.0/9)
+	}
+	if got := Grade(pcircle.OSU_GAMEMODE, perfect, pcircle.HIDDEN_MOD); got != pcircle.XH_GRADE {
+		t.Errorf("Grade() = %v, want %v", got, pcircle.XH_GRADE)
+	}
+}
+
+func TestStandardised(t *testing.T) {
+	// full combo plays, 700,000*1 + 300,000*acc^10 in osu!, 250,000 + 750,000*acc^3.6 in
+	// osu!taiko and 600,000 + 400,000*acc^3.5 in osu!catch, as in lazer
+	tests := []struct {
+		mode   int
+		counts Counts
+		want   int
+	}{
+		{pcircle.OSU_GAMEMODE, Counts{Count300: 2, Count100: 1}, 724304},
+		{pcircle.TAIKO_GAMEMODE, Counts{Count300: 2, Count100: 1}, 639053},
+		{pcircle.CTB_GAMEMODE, Counts{Count300: 2, CountMiss: 1}, 696770},
+	}
+	for _, tt := range tests {
+		b := newTestBeatmap()
+		b.GameMode = tt.mode
+		if got := Standardised(b, tt.counts, MaxCombo(b), pcircle.NO_MOD); got != tt.want {
+			t.Errorf("Standardised(mode %d) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
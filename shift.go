@@ -0,0 +1,112 @@
+package pcircle
+
+import (
+	"math"
+)
+
+// Bounds of time ranges which cover the whole beatmap.
+const (
+	MIN_TIME = math.MinInt32
+	MAX_TIME = math.MaxInt32
+)
+
+// Shift moves all times of the beatmap by delta milliseconds.
+// See Beatmap.ShiftRange.
+func (b *Beatmap) Shift(delta int) {
+	b.ShiftRange(delta, MIN_TIME, MAX_TIME)
+}
+
+// ShiftAfter moves all times at or after specified time by delta milliseconds.
+// See Beatmap.ShiftRange.
+func (b *Beatmap) ShiftAfter(delta, time int) {
+	b.ShiftRange(delta, time, MAX_TIME)
+}
+
+// ShiftRange moves times in range [start, end) by delta milliseconds: hit objects,
+// timing points, breaks, bookmarks, preview time, video and storyboard commands.
+// Objects with duration are moved as a whole if they start in the range.
+func (b *Beatmap) ShiftRange(delta, start, end int) {
+	b.shiftRange(delta, start, end)
+	b.SortTimingPoints()
+	b.SortHitObjects()
+}
+
+// shiftRange is like ShiftRange, but keeps the order of timing points and hit objects.
+func (b *Beatmap) shiftRange(delta, start, end int) {
+	inRange := func(time int) bool {
+		return time >= start && time < end
+	}
+	shift := func(time int) int {
+		if inRange(time) {
+			return time + delta
+		}
+		return time
+	}
+
+	for _, hitObject := range b.HitObjects {
+		base := BaseOf(hitObject)
+		if !inRange(base.Time) {
+			continue
+		}
+		base.Time += delta
+		switch o := hitObject.(type) {
+		case *Spinner:
+			o.EndTime += delta
+		case *ManiaHoldNote:
+			o.EndTime += delta
+		}
+	}
+
+	for _, tp := range b.TimingPoints {
+		tp.Offset = shift(tp.Offset)
+	}
+
+	for _, br := range b.Breaks {
+		if inRange(br.StartTime) {
+			br.StartTime += delta
+			br.EndTime += delta
+		}
+	}
+
+	for i, bookmark := range b.Bookmarks {
+		b.Bookmarks[i] = shift(bookmark)
+	}
+
+	if b.PreviewTime != -1 {
+		b.PreviewTime = shift(b.PreviewTime)
+	}
+
+	if b.Video != nil {
+		b.Video.StartTime = shift(b.Video.StartTime)
+	}
+
+	shiftStoryboardEvents(b.Storyboard, shift)
+}
+
+// Shift moves all times of every beatmap and the storyboard by delta milliseconds.
+// See Beatmap.ShiftRange.
+func (m *Mapset) Shift(delta int) {
+	m.ShiftRange(delta, MIN_TIME, MAX_TIME)
+}
+
+// ShiftAfter moves all times at or after specified time in every beatmap and
+// the storyboard by delta milliseconds. See Beatmap.ShiftRange.
+func (m *Mapset) ShiftAfter(delta, time int) {
+	m.ShiftRange(delta, time, MAX_TIME)
+}
+
+// ShiftRange moves times in range [start, end) in every beatmap and the storyboard
+// by delta milliseconds. See Beatmap.ShiftRange.
+func (m *Mapset) ShiftRange(delta, start, end int) {
+	for _, b := range m.Beatmaps {
+		b.ShiftRange(delta, start, end)
+	}
+	if m.Storyboard != nil {
+		shiftStoryboardEvents(m.Storyboard.Events, func(time int) int {
+			if time >= start && time < end {
+				return time + delta
+			}
+			return time
+		})
+	}
+}
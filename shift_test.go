@@ -0,0 +1,59 @@
+package pcircle
+
+import (
+	"testing"
+)
+
+func TestShiftStoryboardEvent(t *testing.T) {
+	shift := func(time int) int {
+		if time >= 1000 {
+			return time + 50
+		}
+		return time
+	}
+	tests := []struct {
+		event string
+		want  string
+	}{
+		{`Sprite,Foreground,Centre,"star.png",320,240`, `Sprite,Foreground,Centre,"star.png",320,240`},
+		{" F,0,1000,2000,0,1", " F,0,1050,2050,0,1"},
+		{"_M,0,1500,,320,240", "_M,0,1550,,320,240"},
+		{" S,0,500,1500,1,2", " S,0,500,1500,1,2"},
+		{" L,1000,4", " L,1050,4"},
+		{"  F,0,1000,2000,0,1", "  F,0,1000,2000,0,1"},
+		{" T,HitSoundClap,1000,5000", " T,HitSoundClap,1050,5050"},
+		{`Sample,1200,0,"clap.wav",100`, `Sample,1250,0,"clap.wav",100`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			if got := shiftStoryboardEvent(tt.event, shift); got != tt.want {
+				t.Errorf("shiftStoryboardEvent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeatmap_ShiftAfter(t *testing.T) {
+	b := newTestBeatmap()
+	b.Video = &Video{StartTime: 0, FileName: "video.avi"}
+	b.ShiftAfter(-100, 3000)
+
+	if got := BaseOf(b.HitObjects[0]).Time; got != 1500 {
+		t.Errorf("Beatmap.ShiftAfter() circle time = %d, want 1500", got)
+	}
+	if got := BaseOf(b.HitObjects[1]).Time; got != 2900 {
+		t.Errorf("Beatmap.ShiftAfter() slider time = %d, want 2900", got)
+	}
+	if got := b.EndTime(b.HitObjects[2]); got != 11900 {
+		t.Errorf("Beatmap.ShiftAfter() spinner end = %d, want 11900", got)
+	}
+	if b.TimingPoints[0].Offset != 0 || b.TimingPoints[1].Offset != 2900 {
+		t.Errorf("Beatmap.ShiftAfter() timing points = %d, %d", b.TimingPoints[0].Offset, b.TimingPoints[1].Offset)
+	}
+	if b.Breaks[0].StartTime != 5900 || b.Breaks[0].EndTime != 8900 {
+		t.Errorf("Beatmap.ShiftAfter() break = %v", b.Breaks[0])
+	}
+	if b.PreviewTime != 2900 || b.Bookmarks[0] != 1500 || b.Video.StartTime != 0 {
+		t.Errorf("Beatmap.ShiftAfter() preview = %d, bookmark = %d, video = %d", b.PreviewTime, b.Bookmarks[0], b.Video.StartTime)
+	}
+}
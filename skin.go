@@ -0,0 +1,607 @@
+package pcircle
+
+import (
+	"archive/zip"
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Name of the skin configuration file.
+const SKIN_INI = "skin.ini"
+
+// Skin elements which are commonly validated: the basic gameplay elements of every game mode.
+var StandardSkinElements = []string{
+	"hitcircle", "hitcircleoverlay", "approachcircle", "sliderb", "sliderfollowcircle", "reversearrow",
+	"spinner-circle", "spinner-approachcircle", "cursor", "hit0", "hit50", "hit100", "hit300",
+	"default-0", "default-1", "default-2", "default-3", "default-4",
+	"default-5", "default-6", "default-7", "default-8", "default-9",
+	"taikohitcircle", "taikohitcircleoverlay", "fruit-apple", "fruit-catcher-idle",
+	"mania-key1", "mania-note1", "mania-stage-left", "mania-stage-right",
+}
+
+// NewSkin returns a new Skin with default settings.
+func NewSkin() *Skin {
+	return &Skin{
+		AnimationFramerate: -1,
+		CursorCentre:       true,
+		CursorExpand:       true,
+		CursorRotate:       true,
+		CursorTrailRotate:  true,
+		LayeredHitSounds:   true,
+		SliderBallFlip:     true,
+		HitCirclePrefix:    "default",
+		HitCircleOverlap:   -2,
+		ScorePrefix:        "score",
+		ScoreOverlap:       0,
+		ComboPrefix:        "score",
+		ComboOverlap:       0,
+	}
+}
+
+// Skin stores skin configuration parsed from skin.ini and the list of its files.
+// Parsing is lenient like in osu!: lines with invalid values are ignored.
+type Skin struct {
+	Path  string   // The location of skin directory or .osk file
+	Files []string // Paths of all skin files relative to the skin root, with forward slashes
+
+	// General
+	Name                        string
+	Author                      string
+	Version                     string // Skin version, like "2.7" or "latest"
+	AnimationFramerate          int    // Frames per second of animations, -1 if not set
+	AllowSliderBallTint         bool
+	ComboBurstRandom            bool
+	CursorCentre                bool
+	CursorExpand                bool
+	CursorRotate                bool
+	CursorTrailRotate           bool
+	CustomComboBurstSounds      []int
+	HitCircleOverlayAboveNumber bool
+	LayeredHitSounds            bool
+	SliderBallFlip              bool
+	SpinnerFadePlayfield        bool
+	SpinnerFrequencyModulate    bool
+	SpinnerNoBlink              bool
+
+	// Colours
+	ComboColours           []*RGB
+	InputOverlayText       *RGB
+	MenuGlow               *RGB
+	SliderBall             *RGB
+	SliderBorder           *RGB
+	SliderTrackOverride    *RGB
+	SongSelectActiveText   *RGB
+	SongSelectInactiveText *RGB
+	SpinnerBackground      *RGB
+	StarBreakAdditive      *RGB
+
+	// Fonts
+	HitCirclePrefix  string
+	HitCircleOverlap int
+	ScorePrefix      string
+	ScoreOverlap     int
+	ComboPrefix      string
+	ComboOverlap     int
+
+	// CatchTheBeat
+	HyperDash           *RGB
+	HyperDashFruit      *RGB
+	HyperDashAfterImage *RGB
+
+	Mania []*ManiaSkin // Configurations of osu!mania key counts
+}
+
+// ManiaSkin is the configuration of osu!mania stage with one key count.
+type ManiaSkin struct {
+	Keys            int
+	ColumnStart     float64
+	ColumnRight     float64
+	ColumnSpacing   []float64
+	ColumnWidth     []float64
+	ColumnLineWidth []float64
+	BarlineHeight   float64
+	HitPosition     int
+	LightPosition   int
+	ScorePosition   int
+	ComboPosition   int
+	JudgementLine   bool
+	SpecialStyle    int
+	UpsideDown      bool
+	KeysUnderNotes  bool
+
+	// Images by keys like "KeyImage0", "KeyImage0D", "NoteImage1H" or "StageLeft".
+	Images map[string]string
+
+	// Colours by keys like "Colour1" or "ColourLight1".
+	Colours map[string]*RGB
+}
+
+// newManiaSkin returns ManiaSkin with default settings for specified key count.
+func newManiaSkin(keys int) *ManiaSkin {
+	return &ManiaSkin{
+		Keys:          keys,
+		ColumnStart:   136,
+		ColumnRight:   19,
+		BarlineHeight: 1.2,
+		HitPosition:   402,
+		LightPosition: 413,
+		ScorePosition: 325,
+		ComboPosition: 111,
+		JudgementLine: true,
+		Images:        make(map[string]string),
+		Colours:       make(map[string]*RGB),
+	}
+}
+
+// ManiaSkin returns configuration of specified key count, or nil if skin has none.
+func (s *Skin) ManiaSkin(keys int) *ManiaSkin {
+	for _, m := range s.Mania {
+		if m.Keys == keys {
+			return m
+		}
+	}
+	return nil
+}
+
+// Colours returns combo colours of the skin, or DefaultComboColours if it has none.
+// Use it as fallback colours of Beatmap.Combos.
+func (s *Skin) Colours() []*RGB {
+	if len(s.ComboColours) > 0 {
+		return s.ComboColours
+	}
+	return DefaultComboColours
+}
+
+// FromDirectory loads skin from specified directory.
+// Skin without skin.ini has default settings.
+func (s *Skin) FromDirectory(dir string) error {
+	s.Path = dir
+	s.Files = nil
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		s.Files = append(s.Files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(s.Files)
+
+	ini, ok := s.findFile(SKIN_INI)
+	if !ok {
+		return nil
+	}
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(ini)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.FromReader(f)
+}
+
+// FromOSK loads skin from specified .osk archive.
+// Skin without skin.ini has default settings.
+func (s *Skin) FromOSK(p string) error {
+	r, err := zip.OpenReader(p)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	s.Path = p
+	s.Files = nil
+	var ini *zip.File
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(f.Name, `\`, "/")), "/")
+		s.Files = append(s.Files, name)
+		if strings.EqualFold(name, SKIN_INI) {
+			ini = f
+		}
+	}
+	sort.Strings(s.Files)
+
+	if ini == nil {
+		return nil
+	}
+	rc, err := ini.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return s.FromReader(rc)
+}
+
+// FromReader parses skin.ini from r and fills Skin with data.
+func (s *Skin) FromReader(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	var section string
+	var mania *ManiaSkin
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			section = strings.TrimRight(strings.TrimLeft(line, "["), "]")
+			if section == "Mania" {
+				mania = nil
+			}
+			continue
+		}
+		if !strings.Contains(line, ":") {
+			continue
+		}
+
+		head, data := tokenize(line)
+		switch section {
+		case "General":
+			s.parseGeneral(head, data)
+		case "Colours":
+			s.parseColour(head, data)
+		case "Fonts":
+			s.parseFont(head, data)
+		case "CatchTheBeat":
+			colour, ok := parseSkinColour(data)
+			if !ok {
+				continue
+			}
+			switch head {
+			case "HyperDash":
+				s.HyperDash = colour
+			case "HyperDashFruit":
+				s.HyperDashFruit = colour
+			case "HyperDashAfterImage":
+				s.HyperDashAfterImage = colour
+			}
+		case "Mania":
+			if head == "Keys" {
+				keys, err := strconv.Atoi(data)
+				if err != nil || keys <= 0 {
+					mania = nil
+					continue
+				}
+				mania = newManiaSkin(keys)
+				s.Mania = append(s.Mania, mania)
+				continue
+			}
+			if mania != nil {
+				mania.parse(head, data)
+			}
+		}
+	}
+
+	// skip combo colours which are not defined, like osu! does
+	colours := s.ComboColours[:0]
+	for _, colour := range s.ComboColours {
+		if colour != nil {
+			colours = append(colours, colour)
+		}
+	}
+	s.ComboColours = colours
+
+	return scanner.Err()
+}
+
+// parseGeneral parses a line of [General] section.
+func (s *Skin) parseGeneral(head, data string) {
+	switch head {
+	case "Name":
+		s.Name = data
+	case "Author":
+		s.Author = data
+	case "Version":
+		s.Version = data
+	case "AnimationFramerate":
+		if v, err := strconv.Atoi(data); err == nil {
+			s.AnimationFramerate = v
+		}
+	case "CustomComboBurstSounds":
+		s.CustomComboBurstSounds = nil
+		for _, item := range strings.Split(data, ",") {
+			if v, err := strconv.Atoi(strings.TrimSpace(item)); err == nil {
+				s.CustomComboBurstSounds = append(s.CustomComboBurstSounds, v)
+			}
+		}
+	}
+
+	flags := map[string]*bool{
+		"AllowSliderBallTint":         &s.AllowSliderBallTint,
+		"ComboBurstRandom":            &s.ComboBurstRandom,
+		"CursorCentre":                &s.CursorCentre,
+		"CursorExpand":                &s.CursorExpand,
+		"CursorRotate":                &s.CursorRotate,
+		"CursorTrailRotate":           &s.CursorTrailRotate,
+		"HitCircleOverlayAboveNumber": &s.HitCircleOverlayAboveNumber,
+		"HitCircleOverlayAboveNumer":  &s.HitCircleOverlayAboveNumber, // misspelled key supported by osu!
+		"LayeredHitSounds":            &s.LayeredHitSounds,
+		"SliderBallFlip":              &s.SliderBallFlip,
+		"SpinnerFadePlayfield":        &s.SpinnerFadePlayfield,
+		"SpinnerFrequencyModulate":    &s.SpinnerFrequencyModulate,
+		"SpinnerNoBlink":              &s.SpinnerNoBlink,
+	}
+	if flag, ok := flags[head]; ok {
+		if v, ok := parseSkinBool(data); ok {
+			*flag = v
+		}
+	}
+}
+
+// parseColour parses a line of [Colours] section.
+func (s *Skin) parseColour(head, data string) {
+	colour, ok := parseSkinColour(data)
+	if !ok {
+		return
+	}
+
+	if strings.HasPrefix(head, "Combo") {
+		n, err := strconv.Atoi(strings.TrimPrefix(head, "Combo"))
+		if err != nil || n < 1 || n > 8 {
+			return
+		}
+		for len(s.ComboColours) < n {
+			s.ComboColours = append(s.ComboColours, nil)
+		}
+		s.ComboColours[n-1] = colour
+		return
+	}
+
+	colours := map[string]**RGB{
+		"InputOverlayText":       &s.InputOverlayText,
+		"MenuGlow":               &s.MenuGlow,
+		"SliderBall":             &s.SliderBall,
+		"SliderBorder":           &s.SliderBorder,
+		"SliderTrackOverride":    &s.SliderTrackOverride,
+		"SongSelectActiveText":   &s.SongSelectActiveText,
+		"SongSelectInactiveText": &s.SongSelectInactiveText,
+		"SpinnerBackground":      &s.SpinnerBackground,
+		"StarBreakAdditive":      &s.StarBreakAdditive,
+	}
+	if c, ok := colours[head]; ok {
+		*c = colour
+	}
+}
+
+// parseFont parses a line of [Fonts] section.
+func (s *Skin) parseFont(head, data string) {
+	overlap := func(v *int) {
+		if n, err := strconv.Atoi(data); err == nil {
+			*v = n
+		}
+	}
+	prefix := strings.ReplaceAll(data, `\`, "/")
+
+	switch head {
+	case "HitCirclePrefix":
+		s.HitCirclePrefix = prefix
+	case "HitCircleOverlap":
+		overlap(&s.HitCircleOverlap)
+	case "ScorePrefix":
+		s.ScorePrefix = prefix
+	case "ScoreOverlap":
+		overlap(&s.ScoreOverlap)
+	case "ComboPrefix":
+		s.ComboPrefix = prefix
+	case "ComboOverlap":
+		overlap(&s.ComboOverlap)
+	}
+}
+
+// parse parses a line of [Mania] section.
+func (m *ManiaSkin) parse(head, data string) {
+	number := func(v *float64) {
+		if n, err := strconv.ParseFloat(data, 64); err == nil {
+			*v = n
+		}
+	}
+	integer := func(v *int) {
+		if n, err := strconv.Atoi(data); err == nil {
+			*v = n
+		}
+	}
+	list := func(v *[]float64) {
+		*v = nil
+		for _, item := range strings.Split(data, ",") {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(item), 64); err == nil {
+				*v = append(*v, n)
+			}
+		}
+	}
+	flag := func(v *bool) {
+		if b, ok := parseSkinBool(data); ok {
+			*v = b
+		}
+	}
+
+	switch head {
+	case "ColumnStart":
+		number(&m.ColumnStart)
+	case "ColumnRight":
+		number(&m.ColumnRight)
+	case "ColumnSpacing":
+		list(&m.ColumnSpacing)
+	case "ColumnWidth":
+		list(&m.ColumnWidth)
+	case "ColumnLineWidth":
+		list(&m.ColumnLineWidth)
+	case "BarlineHeight":
+		number(&m.BarlineHeight)
+	case "HitPosition":
+		integer(&m.HitPosition)
+	case "LightPosition":
+		integer(&m.LightPosition)
+	case "ScorePosition":
+		integer(&m.ScorePosition)
+	case "ComboPosition":
+		integer(&m.ComboPosition)
+	case "JudgementLine":
+		flag(&m.JudgementLine)
+	case "SpecialStyle":
+		integer(&m.SpecialStyle)
+	case "UpsideDown":
+		flag(&m.UpsideDown)
+	case "KeysUnderNotes":
+		flag(&m.KeysUnderNotes)
+	default:
+		switch {
+		case strings.HasPrefix(head, "Colour"):
+			if colour, ok := parseSkinColour(data); ok {
+				m.Colours[head] = colour
+			}
+		case strings.HasPrefix(head, "KeyImage"), strings.HasPrefix(head, "NoteImage"),
+			strings.HasPrefix(head, "Stage"), strings.HasPrefix(head, "Hit"),
+			strings.HasPrefix(head, "Lighting"), strings.HasPrefix(head, "WarningArrow"):
+			m.Images[head] = strings.ReplaceAll(data, `\`, "/")
+		}
+	}
+}
+
+// parseSkinBool parses boolean written as 0/1 or false/true.
+func parseSkinBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "1", "true":
+		return true, true
+	case "0", "false":
+		return false, true
+	}
+	return false, false
+}
+
+// parseSkinColour parses colour written as "r,g,b" or "r,g,b,a", ignoring alpha and spaces.
+func parseSkinColour(s string) (*RGB, bool) {
+	s = strings.ReplaceAll(s, " ", "")
+	if strings.Count(s, ",") < 2 {
+		return nil, false
+	}
+	colour := &RGB{}
+	if err := colour.FromString(s); err != nil {
+		return nil, false
+	}
+	return colour, true
+}
+
+// SkinElement describes which images of a skin element are present.
+type SkinElement struct {
+	Name string // Name of the element without extension and @2x suffix, like "hitcircle" or "mania/key1"
+	SD   bool   // Whether the standard definition image is present
+	HD   bool   // Whether the @2x high definition image is present
+}
+
+// Elements returns all image elements of the skin sorted by name.
+func (s *Skin) Elements() []*SkinElement {
+	byName := make(map[string]*SkinElement)
+	for _, file := range s.Files {
+		ext := strings.ToLower(path.Ext(file))
+		if ext != ".png" && ext != ".jpg" && ext != ".jpeg" {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSuffix(file, path.Ext(file)))
+		hd := strings.HasSuffix(name, "@2x")
+		name = strings.TrimSuffix(name, "@2x")
+
+		e, ok := byName[name]
+		if !ok {
+			e = &SkinElement{Name: name}
+			byName[name] = e
+		}
+		if hd {
+			e.HD = true
+		} else {
+			e.SD = true
+		}
+	}
+
+	elements := make([]*SkinElement, 0, len(byName))
+	for _, e := range byName {
+		elements = append(elements, e)
+	}
+	sort.Slice(elements, func(i, j int) bool {
+		return elements[i].Name < elements[j].Name
+	})
+	return elements
+}
+
+// Element returns image element with specified name, case insensitive. Animated
+// elements are found by their first frame, so "sliderb" matches "sliderb0.png".
+// Returns nil if element is not present.
+func (s *Skin) Element(name string) *SkinElement {
+	name = strings.ToLower(name)
+	var frame *SkinElement
+	for _, e := range s.Elements() {
+		switch e.Name {
+		case name:
+			return e
+		case name + "0", name + "-0":
+			frame = &SkinElement{Name: name, SD: e.SD, HD: e.HD}
+		}
+	}
+	return frame
+}
+
+// MissingElements returns names of specified elements which are not present in the skin.
+func (s *Skin) MissingElements(names []string) []string {
+	var missing []string
+	for _, name := range names {
+		if s.Element(name) == nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// findFile returns relative path of the file with specified name in the skin root,
+// ignoring case.
+func (s *Skin) findFile(name string) (string, bool) {
+	for _, file := range s.Files {
+		if strings.EqualFold(file, name) {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// LoadSkin loads skin from a directory or an .osk archive.
+func LoadSkin(p string) (*Skin, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewSkin()
+	switch {
+	case fi.IsDir():
+		err = s.FromDirectory(p)
+	case strings.EqualFold(filepath.Ext(p), ".osk"), strings.EqualFold(filepath.Ext(p), ".zip"):
+		err = s.FromOSK(p)
+	default:
+		err = errors.New("not a skin directory or .osk file: " + p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
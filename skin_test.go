@@ -0,0 +1,107 @@
+package pcircle
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSkinINI = `[General]
+Name: Test Skin // comment
+Author: Someone
+Version: 2.7
+CursorRotate: 0
+HitCircleOverlayAboveNumer: 1
+
+[Colours]
+Combo1: 255, 0, 0
+Combo3: 0,0,255
+SliderBorder: 255,255,255,255
+
+[Fonts]
+HitCirclePrefix: fonts\default
+
+[CatchTheBeat]
+HyperDash: 255,0,0
+
+[Mania]
+Keys: 4
+ColumnWidth: 30,40,40,30
+HitPosition: 420
+KeyImage0: mania\key
+UpsideDown: 1
+Colour1: 0,0,0,255
+
+[Mania]
+Keys: 7
+`
+
+func TestSkin_FromReader(t *testing.T) {
+	s := NewSkin()
+	if err := s.FromReader(strings.NewReader(testSkinINI)); err != nil {
+		t.Fatalf("Skin.FromReader() error = %v", err)
+	}
+
+	if s.Name != "Test Skin" || s.Version != "2.7" || s.CursorRotate || !s.CursorExpand || !s.HitCircleOverlayAboveNumber {
+		t.Errorf("Skin.FromReader() general = %+v", s)
+	}
+	if want := []*RGB{{255, 0, 0}, {0, 0, 255}}; !reflect.DeepEqual(s.ComboColours, want) {
+		t.Errorf("Skin.FromReader() combo colours = %v, want %v", s.ComboColours, want)
+	}
+	if s.SliderBorder == nil || *s.SliderBorder != (RGB{255, 255, 255}) || s.HyperDash == nil {
+		t.Errorf("Skin.FromReader() colours = %v, %v", s.SliderBorder, s.HyperDash)
+	}
+	if s.HitCirclePrefix != "fonts/default" {
+		t.Errorf("Skin.FromReader() HitCirclePrefix = %v", s.HitCirclePrefix)
+	}
+
+	m := s.ManiaSkin(4)
+	if m == nil || len(s.Mania) != 2 {
+		t.Fatalf("Skin.FromReader() mania = %v", s.Mania)
+	}
+	if !reflect.DeepEqual(m.ColumnWidth, []float64{30, 40, 40, 30}) || m.HitPosition != 420 || !m.UpsideDown ||
+		m.Images["KeyImage0"] != "mania/key" || m.Colours["Colour1"] == nil {
+		t.Errorf("Skin.FromReader() mania 4K = %+v", m)
+	}
+	if s.ManiaSkin(7).HitPosition != 402 {
+		t.Errorf("Skin.FromReader() mania 7K HitPosition = %v, want default", s.ManiaSkin(7).HitPosition)
+	}
+}
+
+func TestLoadSkin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skin.osk")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for _, name := range []string{"skin.ini", "hitcircle.png", "hitcircle@2x.png", "sliderb0@2x.png", "normal-hitclap.wav"} {
+		fw, _ := w.Create(name)
+		if name == "skin.ini" {
+			fw.Write([]byte(testSkinINI))
+		}
+	}
+	w.Close()
+	f.Close()
+
+	s, err := LoadSkin(path)
+	if err != nil {
+		t.Fatalf("LoadSkin() error = %v", err)
+	}
+	if s.Name != "Test Skin" || len(s.Files) != 5 {
+		t.Errorf("LoadSkin() = %+v", s)
+	}
+
+	if e := s.Element("hitcircle"); e == nil || !e.SD || !e.HD {
+		t.Errorf("Skin.Element(hitcircle) = %+v", e)
+	}
+	if e := s.Element("sliderb"); e == nil || e.SD || !e.HD {
+		t.Errorf("Skin.Element(sliderb) = %+v", e)
+	}
+	if missing := s.MissingElements([]string{"hitcircle", "sliderb", "cursor"}); !reflect.DeepEqual(missing, []string{"cursor"}) {
+		t.Errorf("Skin.MissingElements() = %v", missing)
+	}
+}
@@ -0,0 +1,201 @@
+package pcircle
+
+import (
+	"math"
+)
+
+// Beat divisors which are used for snapping, from the least to the most precise.
+var SnapDivisors = []int{1, 2, 4, 3, 6, 8, 12, 16}
+
+// Default maximal distance in milliseconds which Resnap moves objects by.
+const DEFAULT_RESNAP_TOLERANCE = 10
+
+// Kinds of snapped moments of hit objects.
+const (
+	HIT_OBJECT_SNAP    = "hit object"
+	SLIDER_REPEAT_SNAP = "slider repeat"
+	SLIDER_END_SNAP    = "slider end"
+	SPINNER_END_SNAP   = "spinner end"
+	HOLD_NOTE_END_SNAP = "hold note end"
+)
+
+// Snap describes how a moment of the beatmap is snapped to the beat grid.
+type Snap struct {
+	Time    float64 // Original time
+	Snapped float64 // The nearest snap on the beat grid
+	Divisor int     // Beat divisor of the nearest snap, zero if beatmap has no red lines
+	Error   float64 // Time - Snapped, in milliseconds
+}
+
+// IsSnapped reports whether the time is closer than 1ms to the beat grid.
+func (s Snap) IsSnapped() bool {
+	return s.Divisor == 0 || math.Abs(s.Error) < 1
+}
+
+// SnapPoint is a snapped moment of a hit object.
+type SnapPoint struct {
+	Snap
+	HitObject interface{}
+	Kind      string // One of *_SNAP constants
+}
+
+// NearestSnap returns the least precise of SnapDivisors which specified time is
+// the closest to, relative to the red line active at that time. The offset of
+// the next red line is a snap of 1/1 as well.
+func (b *Beatmap) NearestSnap(time float64) Snap {
+	return b.snapTo(time, SnapDivisors)
+}
+
+// snapTo returns the nearest snap of time on the grid of any of specified divisors.
+// Earlier divisors win when snaps are equally close.
+func (b *Beatmap) snapTo(time float64, divisors []int) Snap {
+	snap := Snap{Time: time, Snapped: time}
+	tp := b.RedLineAt(int(math.Floor(time)))
+	if tp == nil || tp.MillisecondsPerBeat <= 0 {
+		return snap
+	}
+
+	snap.Error = math.Inf(1)
+	for _, d := range divisors {
+		if d <= 0 {
+			continue
+		}
+		step := tp.MillisecondsPerBeat / float64(d)
+		snapped := float64(tp.Offset) + math.Round((time-float64(tp.Offset))/step)*step
+		if e := time - snapped; math.Abs(e) < math.Abs(snap.Error)-1e-9 {
+			snap.Snapped, snap.Divisor, snap.Error = snapped, d, e
+		}
+	}
+
+	// objects slightly before a red line are snapped to it
+	for _, next := range b.TimingPoints {
+		if next.Inherited && next.Offset > tp.Offset {
+			if e := time - float64(next.Offset); math.Abs(e) < math.Abs(snap.Error) {
+				snap.Snapped, snap.Divisor, snap.Error = float64(next.Offset), 1, e
+			}
+			break
+		}
+	}
+	return snap
+}
+
+// SnapAnalysis returns snaps of all hit objects, slider repeats and ends, spinner
+// and hold note ends of the beatmap.
+func (b *Beatmap) SnapAnalysis() []*SnapPoint {
+	var points []*SnapPoint
+	add := func(hitObject interface{}, time float64, kind string) {
+		points = append(points, &SnapPoint{Snap: b.NearestSnap(time), HitObject: hitObject, Kind: kind})
+	}
+
+	for _, hitObject := range b.HitObjects {
+		base := BaseOf(hitObject)
+		add(hitObject, float64(base.Time), HIT_OBJECT_SNAP)
+
+		switch o := hitObject.(type) {
+		case *Slider:
+			repeats := sliderRepeats(o)
+			span := b.SliderDuration(o) / float64(repeats)
+			for i := 1; i < repeats; i++ {
+				add(hitObject, float64(o.Time)+span*float64(i), SLIDER_REPEAT_SNAP)
+			}
+			add(hitObject, float64(o.Time)+span*float64(repeats), SLIDER_END_SNAP)
+		case *Spinner:
+			add(hitObject, float64(o.EndTime), SPINNER_END_SNAP)
+		case *ManiaHoldNote:
+			add(hitObject, float64(o.EndTime), HOLD_NOTE_END_SNAP)
+		}
+	}
+	return points
+}
+
+// sliderRepeats returns number of slider spans, which is at least 1.
+func sliderRepeats(s *Slider) int {
+	if s.Repeat < 1 {
+		return 1
+	}
+	return s.Repeat
+}
+
+// ResnapOptions specifies how Resnap moves unsnapped objects.
+type ResnapOptions struct {
+	// Preferred beat divisor, BeatDivisor of the beatmap if zero. Snaps of the preferred
+	// divisor are used when they are within tolerance, even if other snaps are closer.
+	// Times already on a tick of any of SnapDivisors are kept.
+	Divisor int
+
+	// Objects further than this from the beat grid are considered intentionally
+	// unsnapped and left as is. DEFAULT_RESNAP_TOLERANCE if zero.
+	Tolerance float64
+}
+
+// Resnap moves hit objects, slider ends, spinner and hold note ends, bookmarks and
+// green lines which are unsnapped by at least 1ms onto the beat grid. Slider ends are
+// snapped by changing slider length. Red lines are never moved.
+// Returns number of changed times.
+func (b *Beatmap) Resnap(opts ResnapOptions) int {
+	if opts.Divisor <= 0 {
+		opts.Divisor = b.BeatDivisor
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = DEFAULT_RESNAP_TOLERANCE
+	}
+
+	changed := 0
+	resnap := func(time int) int {
+		// objects on a tick of any divisor are not moved to the preferred one
+		if b.NearestSnap(float64(time)).IsSnapped() {
+			return time
+		}
+		snap := b.snapTo(float64(time), []int{opts.Divisor})
+		if math.Abs(snap.Error) > opts.Tolerance {
+			snap = b.NearestSnap(float64(time))
+		}
+		if snap.IsSnapped() || math.Abs(snap.Error) > opts.Tolerance {
+			return time
+		}
+		changed++
+		return int(math.Round(snap.Snapped))
+	}
+
+	for _, hitObject := range b.HitObjects {
+		base := BaseOf(hitObject)
+		base.Time = resnap(base.Time)
+
+		switch o := hitObject.(type) {
+		case *Slider:
+			b.resnapSliderEnd(o, resnap)
+		case *Spinner:
+			o.EndTime = resnap(o.EndTime)
+		case *ManiaHoldNote:
+			o.EndTime = resnap(o.EndTime)
+		}
+	}
+
+	for i, bookmark := range b.Bookmarks {
+		b.Bookmarks[i] = resnap(bookmark)
+	}
+
+	for _, tp := range b.TimingPoints {
+		if !tp.Inherited {
+			tp.Offset = resnap(tp.Offset)
+		}
+	}
+
+	b.SortTimingPoints()
+	b.SortHitObjects()
+	return changed
+}
+
+// resnapSliderEnd changes length of the slider so that its end is snapped.
+func (b *Beatmap) resnapSliderEnd(s *Slider, resnap func(int) int) {
+	duration := b.SliderDuration(s)
+	if duration <= 0 {
+		return
+	}
+	end := s.Time + int(math.Round(duration))
+	snapped := resnap(end)
+	if snapped == end {
+		return
+	}
+	s.PixelLength *= float64(snapped-s.Time) / duration
+}
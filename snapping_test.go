@@ -0,0 +1,77 @@
+package pcircle
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBeatmap_NearestSnap(t *testing.T) {
+	b := &Beatmap{TimingPoints: []*TimingPoint{
+		{Offset: 0, MillisecondsPerBeat: 500, Meter: 4, Inherited: true},
+		{Offset: 1000, MillisecondsPerBeat: 400, Meter: 4, Inherited: true},
+	}}
+	tests := []struct {
+		time        float64
+		wantDivisor int
+		wantError   float64
+	}{
+		{250, 2, 0},
+		{126, 4, 1},
+		{167, 3, 1.0 / 3},
+		{999, 1, -1},
+		{1050, 8, 0},
+	}
+	for _, tt := range tests {
+		got := b.NearestSnap(tt.time)
+		if got.Divisor != tt.wantDivisor || math.Abs(got.Error-tt.wantError) > 1e-6 {
+			t.Errorf("Beatmap.NearestSnap(%v) = 1/%d %vms, want 1/%d %vms", tt.time, got.Divisor, got.Error, tt.wantDivisor, tt.wantError)
+		}
+	}
+}
+
+func TestBeatmap_Resnap(t *testing.T) {
+	b := newTestBeatmap()
+	b.BeatDivisor = 4
+	b.Bookmarks = []int{1501}
+	b.TimingPoints[1].Offset = 3001
+	b.HitObjects = []interface{}{
+		&Circle{BaseHitObject{Time: 251, Type: CIRCLE}},
+		&Circle{BaseHitObject{Time: 1096, Type: CIRCLE}},
+		&Circle{BaseHitObject{Time: 1180, Type: CIRCLE}},
+		&Spinner{BaseHitObject: BaseHitObject{Time: 2000, Type: SPINNER}, EndTime: 2999},
+	}
+
+	if got := b.Resnap(ResnapOptions{Tolerance: 5}); got != 5 {
+		t.Errorf("Beatmap.Resnap() = %d, want 5", got)
+	}
+
+	var times []int
+	for _, hitObject := range b.HitObjects {
+		times = append(times, b.EndTime(hitObject))
+	}
+	want := []int{250, 1094, 1180, 3000}
+	for i := range want {
+		if times[i] != want[i] {
+			t.Errorf("Beatmap.Resnap() times = %v, want %v", times, want)
+			break
+		}
+	}
+	if b.Bookmarks[0] != 1500 || b.TimingPoints[1].Offset != 3000 {
+		t.Errorf("Beatmap.Resnap() bookmark = %d, green line = %d", b.Bookmarks[0], b.TimingPoints[1].Offset)
+	}
+}
+
+func TestBeatmap_Resnap_otherDivisor(t *testing.T) {
+	b := &Beatmap{
+		BeatDivisor:  8,
+		TimingPoints: []*TimingPoint{{Offset: 0, MillisecondsPerBeat: 60000.0 / 260, Meter: 4, Inherited: true}},
+		HitObjects:   []interface{}{&Circle{BaseHitObject{Time: 269, Type: CIRCLE}}},
+	}
+	// 269ms is on a 1/6 tick, the nearest 1/8 tick is 260ms
+	if got := b.Resnap(ResnapOptions{}); got != 0 {
+		t.Errorf("Beatmap.Resnap() = %d, want 0", got)
+	}
+	if time := BaseOf(b.HitObjects[0]).Time; time != 269 {
+		t.Errorf("Beatmap.Resnap() time = %d, want 269", time)
+	}
+}
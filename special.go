@@ -1,6 +1,7 @@
 package pcircle
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 )
@@ -9,8 +10,9 @@ import (
 // Example of an Background:
 //  0,0,"bg.jpg",0,0
 type Background struct {
-	FileName         string
-	XOffset, YOffset int
+	FileName string `json:"file_name"`
+	XOffset  int    `json:"x_offset"`
+	YOffset  int    `json:"y_offset"`
 }
 
 // String returns string of Background as it would be in .osu file
@@ -24,17 +26,70 @@ func (b Background) String() string {
 }
 
 // FromString fills Background fields with data parsed from string.
+// Event type may be given by name and offsets, which old versions omit, default to 0.
 func (b *Background) FromString(str string) (err error) {
-	attrs := strings.Split(strings.TrimLeft(str, "0,0,"), ",")
+	attrs := strings.Split(str, ",")
+	if len(attrs) < 3 {
+		return errors.New("invalid background: " + str)
+	}
 
-	b.FileName = strings.TrimRight(strings.TrimLeft(attrs[0], `"`), `"`)
+	b.FileName = strings.Trim(attrs[2], `"`)
+	b.XOffset, b.YOffset = 0, 0
+	if len(attrs) < 5 {
+		return nil
+	}
 
-	b.XOffset, err = strconv.Atoi(attrs[1])
+	b.XOffset, err = strconv.Atoi(attrs[3])
 	if err != nil {
 		return err
 	}
 
-	b.YOffset, err = strconv.Atoi(attrs[2])
+	b.YOffset, err = strconv.Atoi(attrs[4])
+	return err
+}
+
+// Video specifies parameters of beatmap background video.
+// Example of a Video:
+//  Video,-200,"video.avi",0,0
+type Video struct {
+	StartTime int    `json:"start_time"` // Number of milliseconds from the beginning of the song when the video starts
+	FileName  string `json:"file_name"`
+	XOffset   int    `json:"x_offset"`
+	YOffset   int    `json:"y_offset"`
+}
+
+// String returns string of Video as it would be in .osu file
+func (v Video) String() string {
+	return "Video," + strings.Join(
+		[]string{
+			strconv.Itoa(v.StartTime),
+			`"` + v.FileName + `"`,
+			strconv.Itoa(v.XOffset),
+			strconv.Itoa(v.YOffset),
+		}, ",")
+}
+
+// FromString fills Video fields with data parsed from string.
+func (v *Video) FromString(str string) (err error) {
+	attrs := strings.Split(str, ",")
+	if len(attrs) < 3 {
+		return errors.New("invalid video: " + str)
+	}
+
+	v.StartTime, err = strconv.Atoi(attrs[1])
+	if err != nil {
+		return err
+	}
+
+	v.FileName = strings.Trim(attrs[2], `"`)
+
+	if len(attrs) >= 5 {
+		v.XOffset, err = strconv.Atoi(attrs[3])
+		if err != nil {
+			return err
+		}
+		v.YOffset, err = strconv.Atoi(attrs[4])
+	}
 	return err
 }
 
@@ -44,8 +99,8 @@ func (b *Background) FromString(str string) (err error) {
 type Break struct {
 
 	// Both are an number of milliseconds from the beginning of the song defining the start and end point of the break period
-	StartTime int
-	EndTime   int
+	StartTime int `json:"start_time"`
+	EndTime   int `json:"end_time"`
 }
 
 // String returns string of Break as it would be in .osu file
@@ -58,17 +113,19 @@ func (b Break) String() string {
 }
 
 // FromString fills Break fields with data parsed from string.
+// Event type may be given by number or by name, as in "Break,4627,5743".
 func (b *Break) FromString(str string) (err error) {
-	str = strings.TrimPrefix(str, "2,")
-
-	sep := strings.Index(str, ",")
+	attrs := strings.Split(str, ",")
+	if len(attrs) < 3 {
+		return errors.New("invalid break: " + str)
+	}
 
-	b.StartTime, err = strconv.Atoi(str[:sep])
+	b.StartTime, err = strconv.Atoi(attrs[1])
 	if err != nil {
 		return err
 	}
 
-	b.EndTime, err = strconv.Atoi(str[sep+1:])
+	b.EndTime, err = strconv.Atoi(attrs[2])
 	return err
 }
 
@@ -76,7 +133,9 @@ func (b *Break) FromString(str string) (err error) {
 // Example:
 //  128,128,0
 type RGB struct {
-	R, G, B int
+	R int `json:"r"`
+	G int `json:"g"`
+	B int `json:"b"`
 }
 
 // String returns string of RGB as it would be in .osu file
@@ -110,11 +169,11 @@ func (c *RGB) FromString(str string) (err error) {
 // The most common example:
 //  0:0:0:0:
 type Extras struct {
-	SampleSet     SampleSet // The sample set of the normal hit sound. When sampleSet is 0, its value should be inherited from the timing point.
-	AdditionalSet SampleSet // The sample set for the other hit sounds
-	CustomIndex   int       // Custom sample set index
-	SampleVolume  int       // Volume of the sample, and ranges from 0 to 100 (percent)
-	Filename      string    // Names an audio file in the folder to play instead of sounds from sample sets
+	SampleSet     SampleSet `json:"sample_set"`     // The sample set of the normal hit sound. When sampleSet is 0, its value should be inherited from the timing point.
+	AdditionalSet SampleSet `json:"additional_set"` // The sample set for the other hit sounds
+	CustomIndex   int       `json:"custom_index"`   // Custom sample set index
+	SampleVolume  int       `json:"sample_volume"`  // Volume of the sample, and ranges from 0 to 100 (percent)
+	Filename      string    `json:"filename"`       // Names an audio file in the folder to play instead of sounds from sample sets
 }
 
 // String returns string of Extras as it would be in .osu file
@@ -144,12 +203,44 @@ func (e *Extras) FromString(str string) (err error) {
 	}
 	e.AdditionalSet = SampleSet(ss)
 
+	// old versions omit the rest
+	if len(attrs) < 3 {
+		return nil
+	}
+
 	e.CustomIndex, err = strconv.Atoi(attrs[2])
-	if err != nil {
+	if err != nil || len(attrs) < 4 {
 		return err
 	}
 
 	e.SampleVolume, err = strconv.Atoi(attrs[3])
+	if err != nil || len(attrs) < 5 {
+		return err
+	}
+
 	e.Filename = attrs[4]
-	return err
+	return nil
+}
+
+// copy returns copy of Extras, nil-safe.
+func (e *Extras) copy() *Extras {
+	if e == nil {
+		return nil
+	}
+	extras := *e
+	return &extras
+}
+
+// copy returns copy of RGB, nil-safe.
+func (c *RGB) copy() *RGB {
+	if c == nil {
+		return nil
+	}
+	colour := *c
+	return &colour
+}
+
+// Luminosity returns perceived brightness of the colour, ranging from 0 to 255.
+func (c RGB) Luminosity() float64 {
+	return 0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)
 }
@@ -0,0 +1,158 @@
+package pcircle
+
+// Hit objects closer than this distance (in osu!pixels) are stacked.
+const STACK_DISTANCE = 3
+
+// CircleRadius returns radius of hit circles in osu!pixels.
+func (b *Beatmap) CircleRadius() float64 {
+	return 54.4 - 4.48*b.CircleSize
+}
+
+// StackOffset returns offset of hit object position caused by its stack height.
+// Stacked objects are moved up and to the left by a tenth of circle radius per level.
+func (b *Beatmap) StackOffset(hitObject interface{}) Point {
+	base := BaseOf(hitObject)
+	if base == nil {
+		return Point{}
+	}
+	offset := -float64(base.StackHeight) * b.CircleRadius() / 10
+	return Point{offset, offset}
+}
+
+// StackedPosition returns position where hit object is actually displayed.
+func (b *Beatmap) StackedPosition(hitObject interface{}) Point {
+	base := BaseOf(hitObject)
+	if base == nil {
+		return Point{}
+	}
+	return base.Position().Add(b.StackOffset(hitObject))
+}
+
+// ApplyStacking computes stack height of every hit object in osu!standard beatmap
+// using StackLeniency. Beatmaps older than file format v6 use the old algorithm.
+// Hit objects should be sorted by time. Beatmaps of other game modes get zero heights.
+func (b *Beatmap) ApplyStacking() {
+	for _, hitObject := range b.HitObjects {
+		BaseOf(hitObject).StackHeight = 0
+	}
+
+	if b.GameMode != OSU_GAMEMODE {
+		return
+	}
+
+	if b.FileFormatVersion >= 6 {
+		b.applyStacking()
+	} else {
+		b.applyStackingOld()
+	}
+}
+
+// stackEndPosition returns position of hit object end which is used to stack next objects.
+func stackEndPosition(hitObject interface{}) Point {
+	if s, ok := hitObject.(*Slider); ok {
+		return s.EndPosition()
+	}
+	return BaseOf(hitObject).Position()
+}
+
+// applyStacking is the stacking algorithm used since file format v6.
+func (b *Beatmap) applyStacking() {
+	stackThreshold := approachRateToPreempt(b.ApproachRate) * b.StackLeniency
+
+	endPositions := make([]Point, len(b.HitObjects))
+	for i, hitObject := range b.HitObjects {
+		endPositions[i] = stackEndPosition(hitObject)
+	}
+
+	for i := len(b.HitObjects) - 1; i > 0; i-- {
+		n := i
+		objectI := BaseOf(b.HitObjects[i])
+		if objectI.StackHeight != 0 {
+			continue
+		}
+
+		switch b.HitObjects[i].(type) {
+		case *Circle:
+			for n--; n >= 0; n-- {
+				if _, ok := b.HitObjects[n].(*Spinner); ok {
+					continue
+				}
+				objectN := BaseOf(b.HitObjects[n])
+
+				if float64(objectI.Time-b.EndTime(b.HitObjects[n])) > stackThreshold {
+					break
+				}
+
+				if _, ok := b.HitObjects[n].(*Slider); ok && endPositions[n].Distance(objectI.Position()) < STACK_DISTANCE {
+					offset := objectI.StackHeight - objectN.StackHeight + 1
+					for j := n + 1; j <= i; j++ {
+						objectJ := BaseOf(b.HitObjects[j])
+						if endPositions[n].Distance(objectJ.Position()) < STACK_DISTANCE {
+							objectJ.StackHeight -= offset
+						}
+					}
+					break
+				}
+
+				if objectN.Position().Distance(objectI.Position()) < STACK_DISTANCE {
+					objectN.StackHeight = objectI.StackHeight + 1
+					objectI = objectN
+				}
+			}
+
+		case *Slider:
+			for n--; n >= 0; n-- {
+				if _, ok := b.HitObjects[n].(*Spinner); ok {
+					continue
+				}
+				objectN := BaseOf(b.HitObjects[n])
+
+				if float64(objectI.Time-objectN.Time) > stackThreshold {
+					break
+				}
+
+				if endPositions[n].Distance(objectI.Position()) < STACK_DISTANCE {
+					objectN.StackHeight = objectI.StackHeight + 1
+					objectI = objectN
+				}
+			}
+		}
+	}
+}
+
+// applyStackingOld is the stacking algorithm used before file format v6.
+func (b *Beatmap) applyStackingOld() {
+	stackThreshold := approachRateToPreempt(b.ApproachRate) * b.StackLeniency
+
+	for i, hitObject := range b.HitObjects {
+		current := BaseOf(hitObject)
+		slider, isSlider := hitObject.(*Slider)
+		if current.StackHeight != 0 && !isSlider {
+			continue
+		}
+
+		startTime := b.EndTime(hitObject)
+		tailPosition := current.Position()
+		if isSlider {
+			tailPosition = slider.PositionAt(1)
+		}
+
+		sliderStack := 0
+		for j := i + 1; j < len(b.HitObjects); j++ {
+			next := BaseOf(b.HitObjects[j])
+			if float64(next.Time)-stackThreshold > float64(startTime) {
+				break
+			}
+
+			if next.Position().Distance(current.Position()) < STACK_DISTANCE {
+				current.StackHeight++
+				startTime = b.EndTime(b.HitObjects[j])
+			} else if next.Position().Distance(tailPosition) < STACK_DISTANCE {
+				// bump notes stacked on slider tails down and right rather than up and left
+				sliderStack++
+				next.StackHeight -= sliderStack
+				startTime = b.EndTime(b.HitObjects[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package pcircle
+
+import (
+	"testing"
+)
+
+func TestBeatmap_ApplyStacking(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		objects []interface{}
+		want    []int
+	}{
+		{
+			name:    "stacked circles",
+			version: 14,
+			objects: []interface{}{
+				&Circle{BaseHitObject{X: 100, Y: 100, Time: 1000}},
+				&Circle{BaseHitObject{X: 100, Y: 100, Time: 1100}},
+				&Circle{BaseHitObject{X: 101, Y: 100, Time: 1200}},
+				&Circle{BaseHitObject{X: 300, Y: 100, Time: 1300}},
+			},
+			want: []int{2, 1, 0, 0},
+		},
+		{
+			name:    "circles too far in time",
+			version: 14,
+			objects: []interface{}{
+				&Circle{BaseHitObject{X: 100, Y: 100, Time: 1000}},
+				&Circle{BaseHitObject{X: 100, Y: 100, Time: 5000}},
+			},
+			want: []int{0, 0},
+		},
+		{
+			name:    "circle on slider end",
+			version: 14,
+			objects: []interface{}{
+				&Slider{
+					BaseHitObject: BaseHitObject{X: 100, Y: 100, Time: 1000},
+					SliderPath:    &SliderPath{SliderType: LINEAR_CURVE, CurvePoints: []*SliderCurvePoint{{200, 100}}},
+					Repeat:        1,
+					PixelLength:   100,
+				},
+				&Circle{BaseHitObject{X: 200, Y: 100, Time: 1500}},
+			},
+			want: []int{0, -1},
+		},
+		{
+			name:    "old stacking",
+			version: 5,
+			objects: []interface{}{
+				&Circle{BaseHitObject{X: 100, Y: 100, Time: 1000}},
+				&Circle{BaseHitObject{X: 100, Y: 100, Time: 1100}},
+			},
+			want: []int{1, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Beatmap{
+				FileFormatVersion: tt.version,
+				ApproachRate:      9,
+				CircleSize:        4,
+				StackLeniency:     0.7,
+				SliderMultiplier:  1,
+				TimingPoints:      []*TimingPoint{{Offset: 0, MillisecondsPerBeat: 500, Inherited: true}},
+				HitObjects:        tt.objects,
+			}
+			b.ApplyStacking()
+			for i, hitObject := range b.HitObjects {
+				if got := BaseOf(hitObject).StackHeight; got != tt.want[i] {
+					t.Errorf("Beatmap.ApplyStacking() height of object %v = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+
+	b := &Beatmap{CircleSize: 4}
+	c := &Circle{BaseHitObject{X: 100, Y: 100, StackHeight: 2}}
+	if got, want := b.StackedPosition(c), (Point{100 - 7.296, 100 - 7.296}); got.Distance(want) > 1e-9 {
+		t.Errorf("Beatmap.StackedPosition() = %v, want %v", got, want)
+	}
+}
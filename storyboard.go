@@ -0,0 +1,230 @@
+package pcircle
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Storyboard layers in the order they are written to files.
+var storyboardLayerNames = []string{"Background", "Fail", "Pass", "Foreground", "Overlay"}
+
+// Storyboard is a storyboard shared by all difficulties of a mapset, stored in .osb file.
+// Events are kept as they are, only times in them are understood.
+type Storyboard struct {
+	FilePath  string   `json:"file_path,omitempty"` // The location of .osb file
+	Variables []string `json:"variables,omitempty"` // Lines of [Variables] section
+	Events    []string `json:"events,omitempty"`    // Lines of [Events] section, commands keep their indentation
+}
+
+// FromFile parses specified .osb file and fills Storyboard with data.
+func (s *Storyboard) FromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s.FilePath = path
+
+	scanner := bufio.NewScanner(f)
+	var section string
+
+	for scanner.Scan() {
+		raw := strings.TrimRight(scanner.Text(), " \t")
+		line := strings.TrimSpace(raw)
+
+		if len(line) <= 2 || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			section = strings.TrimRight(strings.TrimLeft(line, "["), "]")
+			continue
+		}
+
+		switch section {
+		case "Variables":
+			s.Variables = append(s.Variables, line)
+		case "Events":
+			s.Events = append(s.Events, raw)
+		}
+	}
+	return scanner.Err()
+}
+
+// String returns Storyboard as it would be in .osb file.
+func (s *Storyboard) String() string {
+	var lines []string
+	if len(s.Variables) > 0 {
+		lines = append(lines, "[Variables]")
+		lines = append(lines, s.Variables...)
+		lines = append(lines, "")
+	}
+	lines = append(lines, "[Events]", "//Background and Video events")
+	lines = append(lines, storyboardEventLines(s.Events)...)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// ToFile writes Storyboard to specified .osb file.
+func (s *Storyboard) ToFile(path string) error {
+	return os.WriteFile(path, []byte(s.String()), 0644)
+}
+
+// storyboardEventLines returns storyboard events grouped by layers under the usual comments.
+// Every object is kept together with its commands.
+func storyboardEventLines(events []string) []string {
+	groups := make([][]string, len(storyboardLayerNames)+1) // the last one is for sound samples
+	current := 0
+	for _, event := range events {
+		if event != "" && event[0] != ' ' && event[0] != '_' {
+			current = storyboardEventLayer(event)
+		}
+		groups[current] = append(groups[current], event)
+	}
+
+	var lines []string
+	for i, name := range storyboardLayerNames {
+		lines = append(lines, "//Storyboard Layer "+strconv.Itoa(i)+" ("+name+")")
+		lines = append(lines, groups[i]...)
+	}
+	lines = append(lines, "//Storyboard Sound Samples")
+	return append(lines, groups[len(storyboardLayerNames)]...)
+}
+
+// storyboardEventLayer returns index of the layer of specified object line,
+// or the number of layers for sound samples.
+func storyboardEventLayer(event string) int {
+	attrs := strings.Split(event, ",")
+	layer := ""
+	switch attrs[0] {
+	case "Sample", "5":
+		return len(storyboardLayerNames)
+	case "Sprite", "4", "Animation", "6":
+		if len(attrs) > 1 {
+			layer = attrs[1]
+		}
+	}
+
+	for i, name := range storyboardLayerNames {
+		if layer == name || layer == strconv.Itoa(i) {
+			return i
+		}
+	}
+	return 0
+}
+
+// storyboardFiles returns files used by sprites, animations, sound samples, videos and
+// backgrounds of storyboard events, with forward slashes as separators. Animations use
+// a file per frame, with frame number before the extension. Variables, lines of
+// [Variables] section like "$name=value", are substituted first.
+func storyboardFiles(events, variables []string) []string {
+	// longer names first, so that names which are prefixes of others are not substituted
+	vars := append([]string(nil), variables...)
+	sort.SliceStable(vars, func(i, j int) bool {
+		return strings.IndexByte(vars[i], '=') > strings.IndexByte(vars[j], '=')
+	})
+
+	var files []string
+	for _, event := range events {
+		if event == "" || event[0] == ' ' || event[0] == '_' {
+			continue
+		}
+		for _, v := range vars {
+			if name, value, ok := strings.Cut(v, "="); ok && strings.HasPrefix(name, "$") {
+				event = strings.ReplaceAll(event, name, value)
+			}
+		}
+
+		attrs := strings.Split(event, ",")
+		field := 3
+		switch attrs[0] {
+		case "Sprite", "4", "Sample", "5", "Animation", "6":
+		case "Video", "1", "Background", "0":
+			field = 2
+		default:
+			continue
+		}
+		if field >= len(attrs) {
+			continue
+		}
+		file := strings.ReplaceAll(strings.Trim(attrs[field], `"`), `\`, "/")
+		if file == "" {
+			continue
+		}
+
+		if attrs[0] != "Animation" && attrs[0] != "6" {
+			files = append(files, file)
+			continue
+		}
+		frames := 0
+		if len(attrs) > 6 {
+			frames, _ = strconv.Atoi(attrs[6])
+		}
+		ext := path.Ext(file)
+		for i := 0; i < frames; i++ {
+			files = append(files, strings.TrimSuffix(file, ext)+strconv.Itoa(i)+ext)
+		}
+	}
+	return files
+}
+
+// shiftStoryboardEvents moves times of storyboard events by specified function.
+// Commands nested into loops and triggers are relative to them and are not changed.
+func shiftStoryboardEvents(events []string, shift func(int) int) {
+	for i, event := range events {
+		events[i] = shiftStoryboardEvent(event, shift)
+	}
+}
+
+// shiftStoryboardEvent moves times of a single storyboard event.
+func shiftStoryboardEvent(event string, shift func(int) int) string {
+	depth := 0
+	for depth < len(event) && (event[depth] == ' ' || event[depth] == '_') {
+		depth++
+	}
+	if depth > 1 {
+		return event
+	}
+
+	attrs := strings.Split(event[depth:], ",")
+	var fields []int // indices of time fields
+	switch {
+	case depth == 0 && (attrs[0] == "Sample" || attrs[0] == "5" || attrs[0] == "Video" || attrs[0] == "1"):
+		fields = []int{1}
+	case depth == 1 && attrs[0] == "L":
+		fields = []int{1}
+	case depth == 1:
+		// F, M, MX, MY, S, V, R, C, P commands and T triggers have start and end times at the same place
+		fields = []int{2, 3}
+	}
+
+	changed := false
+	var start int
+	for n, i := range fields {
+		if i >= len(attrs) || attrs[i] == "" {
+			continue
+		}
+		t, err := strconv.Atoi(attrs[i])
+		if err != nil {
+			continue
+		}
+		// the whole command is moved if its start is moved
+		if n == 0 {
+			start = t
+		}
+		delta := shift(start) - start
+		if delta != 0 {
+			attrs[i] = strconv.Itoa(t + delta)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return event
+	}
+	return event[:depth] + strings.Join(attrs, ",")
+}
@@ -1,7 +1,9 @@
 package pcircle
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -14,51 +16,70 @@ import (
 // Example of an inherited TimingPoint:
 //  10171,-100,4,2,0,60,0,1
 type TimingPoint struct {
-	Offset int // Define when the timing point starts
+	Offset int `json:"offset"` // Define when the timing point starts
 
 	// Defines the duration of one beat. It affect the scrolling speed in osu!taiko or
 	// osu!mania, and the slider speed in osu!standard.
-	MillisecondsPerBeat float64
+	MillisecondsPerBeat float64 `json:"milliseconds_per_beat"`
 
-	Meter       int       // The number of beats in a measure
-	SampleSet   SampleSet // The default sample set for hit objects
-	SampleIndex int       // The default custom index
-	Volume      int       // The default hitsound volume, ranges from 0 to 100 (percent)
+	Meter       int       `json:"meter"`        // The number of beats in a measure
+	SampleSet   SampleSet `json:"sample_set"`   // The default sample set for hit objects
+	SampleIndex int       `json:"sample_index"` // The default custom index
+	Volume      int       `json:"volume"`       // The default hitsound volume, ranges from 0 to 100 (percent)
 
 	// Tells if the timing point can be inherited from.
 	// A positive milliseconds per beat implies inherited is true (1), and a negative
 	// one implies it is false (0). Note that false (0) means green line, true (1) means
 	// red line.
-	Inherited bool
+	Inherited bool `json:"inherited"`
 
-	Kiai bool // Defines whether or not Kiai Time effects are active
+	Kiai bool `json:"kiai"` // Defines whether or not Kiai Time effects are active
+
+	// Defines whether the first bar line of the red line is hidden in osu!taiko and osu!mania
+	OmitFirstBarLine bool `json:"omit_first_bar_line,omitempty"`
 }
 
+// Bits of the effects field of timing points.
+const (
+	KIAI_EFFECT                = 1
+	OMIT_FIRST_BAR_LINE_EFFECT = 8
+)
+
 // String returns string of TimingPoint as it would be in .osu file
 func (tp TimingPoint) String() string {
-	var mpb string
-
-	if tp.Inherited {
-		mpb = fmt.Sprintf("%g", tp.MillisecondsPerBeat)
-	} else {
-		mpb = strconv.Itoa(int(tp.MillisecondsPerBeat))
-	}
-
 	return strings.Join([]string{
 		strconv.Itoa(tp.Offset),
-		mpb,
+		strconv.FormatFloat(tp.MillisecondsPerBeat, 'f', -1, 64),
 		strconv.Itoa(tp.Meter),
 		strconv.Itoa(int(tp.SampleSet)),
 		strconv.Itoa(tp.SampleIndex),
 		strconv.Itoa(tp.Volume),
 		bool2int2string(tp.MillisecondsPerBeat > 0),
-		bool2int2string(tp.Kiai),
+		strconv.Itoa(tp.effects()),
 	}, ",")
 }
 
-// FromString fills TimingPoint fields with data parsed from string.
+// effects returns the effects field of TimingPoint.
+func (tp TimingPoint) effects() int {
+	effects := 0
+	if tp.Kiai {
+		effects |= KIAI_EFFECT
+	}
+	if tp.OmitFirstBarLine {
+		effects |= OMIT_FIRST_BAR_LINE_EFFECT
+	}
+	return effects
+}
+
+// FromString fills TimingPoint fields with data parsed from string. Old versions omit
+// trailing fields, which default to 4 beats in a measure, the sample set of the beatmap,
+// full volume and no kiai. Without the uninherited field, timing points with positive
+// beat length are red lines.
 func (tp *TimingPoint) FromString(str string) (err error) {
 	attrs := strings.Split(str, ",")
+	if len(attrs) < 2 {
+		return errors.New("invalid timing point: " + str)
+	}
 
 	tp.Offset, err = strconv.Atoi(attrs[0])
 	if err != nil {
@@ -69,30 +90,117 @@ func (tp *TimingPoint) FromString(str string) (err error) {
 	if err != nil {
 		return err
 	}
+	tp.Inherited = tp.MillisecondsPerBeat > 0
 
-	tp.Meter, err = strconv.Atoi(attrs[2])
-	if err != nil {
-		return err
+	tp.Meter, tp.SampleSet, tp.SampleIndex, tp.Volume, tp.Kiai, tp.OmitFirstBarLine = 4, AUTO_SAMPLESET, 0, 100, false, false
+
+	if len(attrs) > 2 {
+		tp.Meter, err = strconv.Atoi(attrs[2])
+		if err != nil {
+			return err
+		}
 	}
 
-	ss, err := strconv.Atoi(attrs[3])
-	if err != nil {
-		return err
+	if len(attrs) > 3 {
+		ss, err := strconv.Atoi(attrs[3])
+		if err != nil {
+			return err
+		}
+		tp.SampleSet = SampleSet(ss)
 	}
-	tp.SampleSet = SampleSet(ss)
 
-	tp.SampleIndex, err = strconv.Atoi(attrs[4])
-	if err != nil {
-		return err
+	if len(attrs) > 4 {
+		tp.SampleIndex, err = strconv.Atoi(attrs[4])
+		if err != nil {
+			return err
+		}
 	}
 
-	tp.Volume, err = strconv.Atoi(attrs[5])
-	if err != nil {
-		return err
+	if len(attrs) > 5 {
+		tp.Volume, err = strconv.Atoi(attrs[5])
+		if err != nil {
+			return err
+		}
 	}
 
-	tp.Inherited = tp.MillisecondsPerBeat > 0
+	if len(attrs) > 6 {
+		uninherited, err := strconv.Atoi(attrs[6])
+		if err != nil {
+			return err
+		}
+		tp.Inherited = uninherited != 0
+	}
+
+	if len(attrs) > 7 {
+		effects, err := strconv.Atoi(attrs[7])
+		if err != nil {
+			return err
+		}
+		tp.Kiai = effects&KIAI_EFFECT != 0
+		tp.OmitFirstBarLine = effects&OMIT_FIRST_BAR_LINE_EFFECT != 0
+	}
+	return nil
+}
 
-	tp.Kiai, err = strconv.ParseBool(attrs[7])
-	return err
+// RedLineAt returns uninherited (red line) TimingPoint which governs specified time.
+// If time is before the first red line, the first one is returned.
+// Returns nil if beatmap has no red lines.
+func (b *Beatmap) RedLineAt(time int) *TimingPoint {
+	var current *TimingPoint
+	for _, tp := range b.TimingPoints {
+		if !tp.Inherited {
+			continue
+		}
+		if tp.Offset > time && current != nil {
+			break
+		}
+		current = tp
+		if tp.Offset > time {
+			break
+		}
+	}
+	return current
+}
+
+// TimingPointAt returns TimingPoint (red or green line) which is active at specified time.
+// If time is before the first timing point, the first one is returned.
+// Returns nil if beatmap has no timing points.
+func (b *Beatmap) TimingPointAt(time int) *TimingPoint {
+	var current *TimingPoint
+	for _, tp := range b.TimingPoints {
+		if tp.Offset > time && current != nil {
+			break
+		}
+		current = tp
+		if tp.Offset > time {
+			break
+		}
+	}
+	return current
+}
+
+// SliderVelocityAt returns slider velocity multiplier defined by the green line active at specified time.
+func (b *Beatmap) SliderVelocityAt(time int) float64 {
+	tp := b.TimingPointAt(time)
+	if tp == nil || tp.Inherited || tp.MillisecondsPerBeat >= 0 {
+		return 1
+	}
+	return math.Min(math.Max(-100/tp.MillisecondsPerBeat, 0.1), 10)
+}
+
+// BeatLengthAt returns duration of one beat in milliseconds at specified time.
+func (b *Beatmap) BeatLengthAt(time int) float64 {
+	tp := b.RedLineAt(time)
+	if tp == nil {
+		return 0
+	}
+	return tp.MillisecondsPerBeat
+}
+
+// EditorTimestamp returns time in format of osu! editor timestamps, e.g. "01:23:456".
+func EditorTimestamp(time int) string {
+	if time < 0 {
+		return "-" + EditorTimestamp(-time)
+	}
+	return fmt.Sprintf("%02d:%02d:%03d", time/60000, time/1000%60, time%1000)
 }
@@ -66,6 +66,33 @@ func TestTimingPoint_String(t *testing.T) {
 	}
 }
 
+func TestTimingPoint_FromString_fields(t *testing.T) {
+	tests := []struct {
+		str       string
+		inherited bool
+		kiai      bool
+		omit      bool
+	}{
+		{"1000,500", true, false, false},
+		{"1000,-100,4,2,0,60", false, false, false},
+		{"1000,500,4,2,0,60,1,8", true, false, true},
+		{"1000,500,4,2,0,60,1,9", true, true, true},
+		{"1000,-100,4,2,0,60,0,1", false, true, false},
+		{"1000,500,4,2,0,60,0,0", false, false, false},
+	}
+	for _, tt := range tests {
+		tp := new(TimingPoint)
+		if err := tp.FromString(tt.str); err != nil {
+			t.Errorf("TimingPoint.FromString(%q) error = %v", tt.str, err)
+			continue
+		}
+		if tp.Inherited != tt.inherited || tp.Kiai != tt.kiai || tp.OmitFirstBarLine != tt.omit {
+			t.Errorf("TimingPoint.FromString(%q) = inherited %v, kiai %v, omit first bar line %v, want %v, %v, %v",
+				tt.str, tp.Inherited, tp.Kiai, tp.OmitFirstBarLine, tt.inherited, tt.kiai, tt.omit)
+		}
+	}
+}
+
 func TestTimingPoint_FromString(t *testing.T) {
 	type fields struct {
 		Offset              int
@@ -37,8 +37,26 @@ func tokenize(str string) (head, data string) {
 }
 
 func generateLineFor(head string, data interface{}) string {
-	if ndata, ok := data.(fmt.Stringer); ok {
-		return head + ": " + ndata.String()
+	return head + ": " + formatValue(data)
+}
+
+func generateCompactLineFor(head string, data interface{}) string {
+	return head + ":" + formatValue(data)
+}
+
+func formatValue(data interface{}) string {
+	switch ndata := data.(type) {
+	case fmt.Stringer:
+		return ndata.String()
+	case bool:
+		return bool2int2string(ndata)
+	}
+	return fmt.Sprintf("%v", data)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
 	}
-	return fmt.Sprintf("%v: %v", head, data)
+	return x
 }
@@ -0,0 +1,166 @@
+package pcircle
+
+import (
+	"errors"
+	"strings"
+)
+
+// File format versions with distinct behaviour.
+const (
+	OLDEST_FORMAT_VERSION            = 3   // The oldest version osu! reads
+	LEGACY_OFFSET_FORMAT_VERSION     = 5   // Times of older versions are stored LEGACY_OFFSET milliseconds early
+	TIMING_SAMPLES_FORMAT_VERSION    = 5   // Timing points have meter, sample set, sample index and volume since
+	COLOURS_FORMAT_VERSION           = 5   // [Colours] section exists since
+	EDITOR_SECTION_FORMAT_VERSION    = 5   // [Editor] section exists since, before it editor settings are in [General]
+	STORYBOARD_LAYERS_FORMAT_VERSION = 5   // Storyboard events are grouped under comments of their layers since
+	TIMING_EFFECTS_FORMAT_VERSION    = 6   // Timing points have inherited and kiai fields since
+	APPROACH_RATE_FORMAT_VERSION     = 8   // Approach rate is separate from overall difficulty since
+	SLIDER_EDGES_FORMAT_VERSION      = 8   // Sliders have edge hit sounds and additions since
+	UNICODE_METADATA_FORMAT_VERSION  = 10  // Metadata has TitleUnicode and ArtistUnicode since
+	BEATMAP_ID_FORMAT_VERSION        = 10  // Metadata has BeatmapID and BeatmapSetID since
+	HIT_OBJECT_EXTRAS_FORMAT_VERSION = 12  // Hit objects have extras since
+	LATEST_FORMAT_VERSION            = 14  // The latest version of osu!stable
+	LAZER_FORMAT_VERSION             = 128 // Version of osu!lazer, with its extensions
+)
+
+// ErrUnsupportedFormatVersion is returned when Beatmap is written in a file format version
+// osu! does not read.
+var ErrUnsupportedFormatVersion = errors.New("unsupported file format version")
+
+// LEGACY_OFFSET is the number of milliseconds osu! adds to times of beatmaps older than
+// LEGACY_OFFSET_FORMAT_VERSION, to make up for latency of the old audio engine.
+const LEGACY_OFFSET = 24
+
+// hasLegacyOffset reports whether times of specified file format version are stored
+// LEGACY_OFFSET milliseconds early. Unknown version (0) has no offset.
+func hasLegacyOffset(version int) bool {
+	return olderThan(version, LEGACY_OFFSET_FORMAT_VERSION)
+}
+
+// olderThan reports whether specified file format version precedes version since.
+// Unknown version (0) is treated as the latest one.
+func olderThan(version, since int) bool {
+	return version > 0 && version < since
+}
+
+// SupportedFormatVersion reports whether Beatmap can be written in specified file format
+// version: one of OLDEST_FORMAT_VERSION to LATEST_FORMAT_VERSION or LAZER_FORMAT_VERSION.
+func SupportedFormatVersion(version int) bool {
+	return version >= OLDEST_FORMAT_VERSION && version <= LATEST_FORMAT_VERSION || version == LAZER_FORMAT_VERSION
+}
+
+// forVersion returns Beatmap as it is stored in specified file format version: times are
+// moved by the legacy offset and fields the version does not have are dropped. Beatmap
+// is copied only if anything has to be changed.
+func (b *Beatmap) forVersion(version int) *Beatmap {
+	if !olderThan(version, HIT_OBJECT_EXTRAS_FORMAT_VERSION) {
+		return b
+	}
+	b = b.Clone()
+	if hasLegacyOffset(version) {
+		b.shiftRange(-LEGACY_OFFSET, MIN_TIME, MAX_TIME)
+	}
+	if olderThan(version, COLOURS_FORMAT_VERSION) {
+		b.ComboColours = nil
+		b.SliderBody = nil
+		b.SliderTrackOverride = nil
+		b.SliderBorder = nil
+	}
+	for _, hitObject := range b.HitObjects {
+		switch o := hitObject.(type) {
+		case *Circle:
+			o.Extras = nil
+		case *Slider:
+			o.Extras = nil
+			if olderThan(version, SLIDER_EDGES_FORMAT_VERSION) {
+				o.EdgeHitSounds = nil
+				o.EdgeAdditions = nil
+			}
+		case *Spinner:
+			o.Extras = nil
+		}
+	}
+	return b
+}
+
+// timingPointLine returns line of specified timing point in specified file format version,
+// without the fields the version does not have.
+func timingPointLine(tp *TimingPoint, version int) string {
+	line := tp.String()
+	fields := 0
+	switch {
+	case olderThan(version, TIMING_SAMPLES_FORMAT_VERSION):
+		fields = 2
+	case olderThan(version, TIMING_EFFECTS_FORMAT_VERSION):
+		fields = 6
+	default:
+		return line
+	}
+	return strings.Join(strings.Split(line, ",")[:fields], ",")
+}
+
+// outputVersion returns file format version Beatmap is written in by default: the latest
+// version of osu!stable, or LAZER_FORMAT_VERSION for beatmaps of osu!lazer.
+func (b *Beatmap) outputVersion() int {
+	if b.FileFormatVersion >= LAZER_FORMAT_VERSION {
+		return LAZER_FORMAT_VERSION
+	}
+	return LATEST_FORMAT_VERSION
+}
+
+// applyDefaults sets fields missing in the parsed file to defaults osu! uses for them.
+// Keys of seen fields are given as "Section.Key". Old versions omit many of the fields.
+func (b *Beatmap) applyDefaults(seen map[string]bool) {
+	missing := func(key string) bool {
+		return !seen[key]
+	}
+
+	if missing("General.PreviewTime") {
+		b.PreviewTime = -1
+	}
+	if missing("General.Countdown") {
+		b.Countdown = NORMAL_COUNTDOWN
+	}
+	if missing("General.SampleSet") {
+		b.SampleSet = NORMAL_SAMPLESET
+	}
+	if missing("General.StackLeniency") {
+		b.StackLeniency = 0.7
+	}
+	if missing("General.StoryFireInFront") {
+		b.StoryFireInFront = true
+	}
+
+	if missing("Editor.DistanceSpacing") && missing("General.EditorDistanceSpacing") {
+		b.DistanceSpacing = 1
+	}
+	if missing("Editor.BeatDivisor") {
+		b.BeatDivisor = 4
+	}
+	if missing("Editor.GridSize") {
+		b.GridSize = 4
+	}
+	if missing("Editor.TimelineZoom") {
+		b.TimelineZoom = 1
+	}
+
+	if missing("Difficulty.HPDrainRate") {
+		b.HPDrainRate = 5
+	}
+	if missing("Difficulty.CircleSize") {
+		b.CircleSize = 5
+	}
+	if missing("Difficulty.OverallDifficulty") {
+		b.OverallDifficulty = 5
+	}
+	if missing("Difficulty.ApproachRate") {
+		// approach rate was added in version 8, before it was equal to overall difficulty
+		b.ApproachRate = b.OverallDifficulty
+	}
+	if missing("Difficulty.SliderMultiplier") {
+		b.SliderMultiplier = 1.4
+	}
+	if missing("Difficulty.SliderTickRate") {
+		b.SliderTickRate = 1
+	}
+}
@@ -0,0 +1,265 @@
+package pcircle
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const oldTestBeatmap = `osu file format v4
+
+[General]
+AudioFilename: audio.mp3
+AudioLeadIn: 0
+EditorBookmarks: 1000,2000
+SampleSet: None
+AlwaysShowPlayfield: 1
+
+[Metadata]
+Title:Old
+
+[Difficulty]
+HPDrainRate:6
+CircleSize:4
+OverallDifficulty:7
+SliderMultiplier:1.4
+
+[Events]
+0,0,"bg.jpg"
+Break,5000,8000
+
+[TimingPoints]
+1000,500
+
+[HitObjects]
+64,64,1000,1,0
+128,64,1500,2,0,B|200:64,1,70
+256,192,9000,12,0,10000
+`
+
+// v3TestBeatmap is in the canonical format of version 3.
+const v3TestBeatmap = `osu file format v3
+
+[General]
+AudioFilename: audio.mp3
+AudioLeadIn: 0
+PreviewTime: -1
+Countdown: 1
+SampleSet: Normal
+StackLeniency: 0.7
+Mode: 0
+LetterboxInBreaks: 0
+EditorBookmarks: 1000,2000
+EditorDistanceSpacing: 1.2
+
+[Metadata]
+Title:Old
+Artist:Artist
+Creator:Mapper
+Version:Normal
+Source:
+Tags:old map
+
+[Difficulty]
+HPDrainRate:6
+CircleSize:4
+OverallDifficulty:7
+SliderMultiplier:1.4
+SliderTickRate:1
+
+[Events]
+//Background and Video events
+0,0,"bg.jpg",0,0
+//Break Periods
+2,5000,8000
+Sprite,Background,Centre,"sb.png",320,240
+ F,0,1000,2000,0,1
+
+[TimingPoints]
+1000,500
+3000,-50
+
+
+[HitObjects]
+64,64,1000,1,0
+128,64,1500,2,0,B|200:64,1,70
+256,192,9000,12,0,10000
+`
+
+// writeTestFile writes text into a file in temporary directory and returns its path.
+func writeTestFile(t *testing.T, name, text string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBeatmap_FromFile_oldVersion(t *testing.T) {
+	path := writeTestFile(t, "old.osu", oldTestBeatmap)
+	b := NewBeatmap()
+	if err := b.FromFile(path); err != nil {
+		t.Fatalf("Beatmap.FromFile() error = %v", err)
+	}
+
+	if b.FileFormatVersion != 4 || b.SampleSet != AUTO_SAMPLESET || !b.AlwaysShowPlayfield {
+		t.Errorf("Beatmap.FromFile() general = v%v %v %v", b.FileFormatVersion, b.SampleSet, b.AlwaysShowPlayfield)
+	}
+	// defaults of missing fields
+	if b.ApproachRate != 7 || b.StackLeniency != 0.7 || !b.StoryFireInFront || b.PreviewTime != -1 || b.Countdown != NORMAL_COUNTDOWN {
+		t.Errorf("Beatmap.FromFile() defaults = AR %v, stack leniency %v, story fire %v, preview %v, countdown %v",
+			b.ApproachRate, b.StackLeniency, b.StoryFireInFront, b.PreviewTime, b.Countdown)
+	}
+	if b.Background == nil || b.Background.FileName != "bg.jpg" {
+		t.Errorf("Beatmap.FromFile() background = %v", b.Background)
+	}
+
+	// times are moved by the legacy offset
+	if want := []int{1024, 2024}; !reflect.DeepEqual(b.Bookmarks, want) {
+		t.Errorf("Beatmap.FromFile() bookmarks = %v, want %v", b.Bookmarks, want)
+	}
+	if len(b.Breaks) != 1 || *b.Breaks[0] != (Break{5024, 8024}) {
+		t.Errorf("Beatmap.FromFile() breaks = %v", b.Breaks)
+	}
+	if tp := b.TimingPoints[0]; tp.Offset != 1024 || tp.Meter != 4 || tp.Volume != 100 || !tp.Inherited {
+		t.Errorf("Beatmap.FromFile() timing point = %+v", tp)
+	}
+	if len(b.HitObjects) != 3 {
+		t.Fatalf("Beatmap.FromFile() hit objects = %v, want 3", len(b.HitObjects))
+	}
+	for i, time := range []int{1024, 1524, 9024} {
+		if got := BaseOf(b.HitObjects[i]).Time; got != time {
+			t.Errorf("Beatmap.FromFile() hit object %d time = %v, want %v", i, got, time)
+		}
+	}
+	if s := b.HitObjects[2].(*Spinner); s.EndTime != 10024 {
+		t.Errorf("Beatmap.FromFile() spinner end time = %v, want 10024", s.EndTime)
+	}
+
+	latest := b.String()
+	for _, want := range []string{"osu file format v14", "1024,500,4,0,0,100,1,0", "64,64,1024,1,0\n", "AlwaysShowPlayfield: 1"} {
+		if !strings.Contains(latest, want) {
+			t.Errorf("Beatmap.String() = %v, want to contain %v", latest, want)
+		}
+	}
+	old, err := b.StringVersion(4)
+	if err != nil {
+		t.Fatalf("Beatmap.StringVersion(4) error = %v", err)
+	}
+	for _, want := range []string{"osu file format v4", "\n1000,500\n", "64,64,1000,1,0\n", "2,5000,8000", "Bookmarks: 1000,2000"} {
+		if !strings.Contains(old, want) {
+			t.Errorf("Beatmap.StringVersion(4) = %v, want to contain %v", old, want)
+		}
+	}
+
+	// written old version is parsed back with the same times
+	nb := NewBeatmap()
+	if err := nb.FromFile(writeTestFile(t, "written.osu", old)); err != nil {
+		t.Fatalf("Beatmap.FromFile() of written beatmap error = %v", err)
+	}
+	if got, _ := nb.StringVersion(4); got != old {
+		t.Errorf("Beatmap.StringVersion(4) after round trip = %v, want %v", got, old)
+	}
+
+	lossless := NewBeatmap()
+	if err := lossless.FromFileLossless(path); err != nil || lossless.String() != oldTestBeatmap {
+		t.Errorf("Beatmap.FromFileLossless() = %v, %v, want %v", err, lossless.String(), oldTestBeatmap)
+	}
+}
+
+func TestBeatmap_FromFile_lazer(t *testing.T) {
+	b := newTestBeatmap()
+	b.FileFormatVersion = LAZER_FORMAT_VERSION
+	b.SamplesMatchPlaybackRate = true
+	b.HitObjects[0].(*Circle).Extras.SampleSet = 5
+
+	text := b.String()
+	for _, want := range []string{"osu file format v128", "SamplesMatchPlaybackRate: 1", "100,100,1500,5,0,5:0:0:0:"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Beatmap.String() = %v, want to contain %v", text, want)
+		}
+	}
+
+	nb := NewBeatmap()
+	if err := nb.FromFile(writeTestFile(t, "lazer.osu", text)); err != nil {
+		t.Fatalf("Beatmap.FromFile() error = %v", err)
+	}
+	if nb.FileFormatVersion != LAZER_FORMAT_VERSION || !nb.SamplesMatchPlaybackRate || nb.HitObjects[0].(*Circle).Extras.SampleSet != 5 {
+		t.Errorf("Beatmap.FromFile() = v%v %v %v", nb.FileFormatVersion, nb.SamplesMatchPlaybackRate, nb.HitObjects[0].(*Circle).Extras)
+	}
+	if latest, _ := nb.StringVersion(LATEST_FORMAT_VERSION); !strings.HasPrefix(latest, "osu file format v14\n") {
+		t.Errorf("Beatmap.StringVersion(14) header = %v", strings.SplitN(latest, "\n", 2)[0])
+	}
+}
+
+func TestBeatmap_StringVersion(t *testing.T) {
+	b := newTestBeatmap()
+	b.ComboColours = []*RGB{{255, 0, 0}}
+
+	tests := []struct {
+		version int
+		want    []string
+		notWant []string
+	}{
+		{3, []string{"osu file format v3\n", "\n-24,500\n", "\n2976,-50\n", "100,100,1476,5,0\n", "256,192,9976,12,0,11976\n"},
+			[]string{"[Colours]", ",1,0\n", ":0:"}},
+		{5, []string{"osu file format v5\n", "\n0,500,4,2,0,60\n", "[Colours]", "100,100,1500,5,0\n"},
+			[]string{",1,0\n", ":0:"}},
+		{7, []string{"\n0,500,4,2,0,60,1,0\n", "100,100,1500,5,0\n", ",140\n"}, []string{":0:"}},
+		{10, []string{"100,100,1500,5,0\n", ",140,"}, []string{"0:0:0:0:\n"}},
+		{LATEST_FORMAT_VERSION, []string{"100,100,1500,5,0,0:0:0:0:\n"}, nil},
+	}
+	for _, tt := range tests {
+		got, err := b.StringVersion(tt.version)
+		if err != nil {
+			t.Errorf("Beatmap.StringVersion(%v) error = %v", tt.version, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("Beatmap.StringVersion(%v) = %v, want to contain %q", tt.version, got, want)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(got, notWant) {
+				t.Errorf("Beatmap.StringVersion(%v) = %v, want not to contain %q", tt.version, got, notWant)
+			}
+		}
+
+		// every version is read back with the same times
+		nb := NewBeatmap()
+		if err := nb.FromFile(writeTestFile(t, "written.osu", got)); err != nil {
+			t.Errorf("Beatmap.FromFile() of version %v error = %v", tt.version, err)
+		} else if BaseOf(nb.HitObjects[0]).Time != 1500 || nb.TimingPoints[0].Offset != 0 {
+			t.Errorf("Beatmap.FromFile() of version %v times = %v, %v", tt.version, BaseOf(nb.HitObjects[0]).Time, nb.TimingPoints[0].Offset)
+		}
+	}
+
+	for _, version := range []int{-1, 0, OLDEST_FORMAT_VERSION - 1, LATEST_FORMAT_VERSION + 1, LAZER_FORMAT_VERSION + 1} {
+		if _, err := b.StringVersion(version); err != ErrUnsupportedFormatVersion {
+			t.Errorf("Beatmap.StringVersion(%v) error = %v, want %v", version, err, ErrUnsupportedFormatVersion)
+		}
+		if err := b.ToFileVersion(filepath.Join(t.TempDir(), "b.osu"), version); err != ErrUnsupportedFormatVersion {
+			t.Errorf("Beatmap.ToFileVersion(%v) error = %v, want %v", version, err, ErrUnsupportedFormatVersion)
+		}
+	}
+}
+
+func TestBeatmap_StringVersion_v3(t *testing.T) {
+	b := NewBeatmap()
+	if err := b.FromFile(writeTestFile(t, "v3.osu", v3TestBeatmap)); err != nil {
+		t.Fatalf("Beatmap.FromFile() error = %v", err)
+	}
+	if got, err := b.StringVersion(3); err != nil || got != v3TestBeatmap {
+		t.Errorf("Beatmap.StringVersion(3) = %v, %v, want %v", got, err, v3TestBeatmap)
+	}
+
+	latest := b.String()
+	for _, want := range []string{"[Editor]\nBookmarks: 1024,2024\nDistanceSpacing: 1.2", "ApproachRate:7", "TitleUnicode:", "BeatmapID:", "//Storyboard Layer 0"} {
+		if !strings.Contains(latest, want) {
+			t.Errorf("Beatmap.String() = %v, want to contain %v", latest, want)
+		}
+	}
+}
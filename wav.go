@@ -0,0 +1,193 @@
+package pcircle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+)
+
+// WAV audio formats.
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// Audio is an uncompressed stereo audio track.
+type Audio struct {
+	SampleRate int
+
+	// Interleaved left and right channel samples, ranging from -1 to 1.
+	Samples []float32
+}
+
+// Frames returns number of stereo frames in the track.
+func (a *Audio) Frames() int {
+	return len(a.Samples) / 2
+}
+
+// Duration returns duration of the track in milliseconds.
+func (a *Audio) Duration() float64 {
+	if a.SampleRate == 0 {
+		return 0
+	}
+	return float64(a.Frames()) * 1000 / float64(a.SampleRate)
+}
+
+// DecodeWAV reads PCM (8, 16, 24 or 32 bit) or IEEE float WAV file.
+// Mono files are converted to stereo, additional channels are dropped.
+func DecodeWAV(r io.Reader) (*Audio, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errors.New("invalid wav file: no RIFF header")
+	}
+
+	var format, channels, bits int
+	a := new(Audio)
+	var pcm []byte
+
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		pos += 8
+		if size < 0 || pos+size > len(data) {
+			size = len(data) - pos
+		}
+		chunk := data[pos : pos+size]
+
+		switch id {
+		case "fmt ":
+			if len(chunk) < 16 {
+				return nil, errors.New("invalid wav file: short fmt chunk")
+			}
+			format = int(binary.LittleEndian.Uint16(chunk[0:2]))
+			channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			a.SampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			bits = int(binary.LittleEndian.Uint16(chunk[14:16]))
+			if format == wavFormatExtensible && len(chunk) >= 26 {
+				format = int(binary.LittleEndian.Uint16(chunk[24:26]))
+			}
+		case "data":
+			pcm = chunk
+		}
+
+		// chunks are padded to even size
+		pos += size + size%2
+	}
+
+	if channels == 0 || a.SampleRate == 0 {
+		return nil, errors.New("invalid wav file: no fmt chunk")
+	}
+	if format != wavFormatPCM && format != wavFormatFloat {
+		return nil, errors.New("unsupported wav format: " + strconv.Itoa(format))
+	}
+	if (format == wavFormatPCM && bits != 8 && bits != 16 && bits != 24 && bits != 32) ||
+		(format == wavFormatFloat && bits != 32 && bits != 64) {
+		return nil, errors.New("unsupported wav bit depth: " + strconv.Itoa(bits))
+	}
+
+	sampleSize := bits / 8
+	frameSize := sampleSize * channels
+	frames := len(pcm) / frameSize
+	a.Samples = make([]float32, frames*2)
+
+	for i := 0; i < frames; i++ {
+		frame := pcm[i*frameSize:]
+		left := decodeWAVSample(frame, format, bits)
+		right := left
+		if channels > 1 {
+			right = decodeWAVSample(frame[sampleSize:], format, bits)
+		}
+		a.Samples[2*i] = left
+		a.Samples[2*i+1] = right
+	}
+
+	return a, nil
+}
+
+// decodeWAVSample decodes a single sample to -1..1 range.
+func decodeWAVSample(b []byte, format, bits int) float32 {
+	if format == wavFormatFloat {
+		if bits == 64 {
+			return float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	}
+
+	switch bits {
+	case 8:
+		return float32(int(b[0])-128) / 128
+	case 16:
+		return float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+	case 24:
+		v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+		return float32(v) / 8388608
+	}
+	return float32(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+}
+
+// EncodeWAV writes audio as 16 bit stereo PCM WAV file. Samples are clipped to -1..1.
+func (a *Audio) EncodeWAV(w io.Writer) error {
+	dataSize := len(a.Samples) * 2
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(16))
+	binary.Write(buf, binary.LittleEndian, uint16(wavFormatPCM))
+	binary.Write(buf, binary.LittleEndian, uint16(2))
+	binary.Write(buf, binary.LittleEndian, uint32(a.SampleRate))
+	binary.Write(buf, binary.LittleEndian, uint32(a.SampleRate*4))
+	binary.Write(buf, binary.LittleEndian, uint16(4))
+	binary.Write(buf, binary.LittleEndian, uint16(16))
+
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, uint32(dataSize))
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	pcm := make([]byte, dataSize)
+	for i, s := range a.Samples {
+		v := math.Max(-1, math.Min(1, float64(s)))
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(int16(math.Round(v*32767))))
+	}
+	_, err := w.Write(pcm)
+	return err
+}
+
+// Resample returns audio converted to specified sample rate using linear interpolation.
+func (a *Audio) Resample(rate int) *Audio {
+	if rate == a.SampleRate || a.SampleRate == 0 || rate <= 0 {
+		return a
+	}
+
+	frames := int(float64(a.Frames()) * float64(rate) / float64(a.SampleRate))
+	na := &Audio{SampleRate: rate, Samples: make([]float32, frames*2)}
+	step := float64(a.SampleRate) / float64(rate)
+
+	for i := 0; i < frames; i++ {
+		pos := float64(i) * step
+		j := int(pos)
+		frac := float32(pos - float64(j))
+		for c := 0; c < 2; c++ {
+			cur := a.Samples[2*j+c]
+			next := cur
+			if j+1 < a.Frames() {
+				next = a.Samples[2*(j+1)+c]
+			}
+			na.Samples[2*i+c] = cur + (next-cur)*frac
+		}
+	}
+	return na
+}
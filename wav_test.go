@@ -0,0 +1,109 @@
+package pcircle
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAudio_EncodeWAV(t *testing.T) {
+	a := &Audio{SampleRate: 1000, Samples: []float32{0, 0.5, -0.5, 1, 2, -2}}
+
+	buf := new(bytes.Buffer)
+	if err := a.EncodeWAV(buf); err != nil {
+		t.Fatalf("Audio.EncodeWAV() error = %v", err)
+	}
+
+	got, err := DecodeWAV(buf)
+	if err != nil {
+		t.Fatalf("DecodeWAV() error = %v", err)
+	}
+	if got.SampleRate != 1000 || got.Frames() != 3 {
+		t.Fatalf("DecodeWAV() = %v Hz, %v frames, want 1000 Hz, 3 frames", got.SampleRate, got.Frames())
+	}
+	want := []float32{0, 0.5, -0.5, 1, 1, -1}
+	for i := range want {
+		if math.Abs(float64(got.Samples[i]-want[i])) > 1e-3 {
+			t.Errorf("DecodeWAV() samples = %v, want %v", got.Samples, want)
+			break
+		}
+	}
+}
+
+func TestMapset_RenderHitSounds(t *testing.T) {
+	dir := t.TempDir()
+	skin := t.TempDir()
+
+	writeWAV := func(path string, value float32, frames int) {
+		a := &Audio{SampleRate: 1000, Samples: make([]float32, frames*2)}
+		for i := range a.Samples {
+			a.Samples[i] = value
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := a.EncodeWAV(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeWAV(filepath.Join(skin, "normal-hitnormal.wav"), 0.25, 10)
+	writeWAV(filepath.Join(dir, "normal-hitclap2.wav"), 0.5, 5)
+
+	b := &Beatmap{
+		TimingPoints: []*TimingPoint{{Offset: 0, MillisecondsPerBeat: 500, SampleSet: NORMAL_SAMPLESET, SampleIndex: 2, Volume: 100, Inherited: true}},
+		HitObjects: []interface{}{
+			&Circle{BaseHitObject{Time: 100, Extras: &Extras{}}},
+			&Circle{BaseHitObject{Time: 105, HitSound: CLAP_HITSOUND | FINISH_HITSOUND, Extras: &Extras{SampleVolume: 50}}},
+		},
+	}
+	m := &Mapset{DirectoryPath: dir}
+
+	render, err := m.RenderHitSounds(b, HitSoundRenderOptions{SampleRate: 1000, SkinDirectory: skin})
+	if err != nil {
+		t.Fatalf("Mapset.RenderHitSounds() error = %v", err)
+	}
+	if len(render.Missing) != 1 || render.Missing[0] != "normal-hitfinish.wav" {
+		t.Errorf("Mapset.RenderHitSounds() missing = %v, want [normal-hitfinish.wav]", render.Missing)
+	}
+
+	a := render.Audio
+	if a.Frames() != 115 {
+		t.Fatalf("Mapset.RenderHitSounds() frames = %v, want 115", a.Frames())
+	}
+	for _, tt := range []struct {
+		frame int
+		want  float64
+	}{
+		{99, 0},
+		{100, 0.25},
+		{105, 0.25 + 0.125 + 0.25},
+		{112, 0.125},
+	} {
+		if got := float64(a.Samples[2*tt.frame]); math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("Mapset.RenderHitSounds() frame %v = %v, want %v", tt.frame, got, tt.want)
+		}
+	}
+
+	// the track is padded to the end of the beatmap or to the requested length
+	b.HitObjects = append(b.HitObjects, &Circle{BaseHitObject{Time: 200, Extras: &Extras{}}})
+	for _, tt := range []struct {
+		opts HitSoundRenderOptions
+		want int
+	}{
+		{HitSoundRenderOptions{SampleRate: 1000}, 200},
+		{HitSoundRenderOptions{SampleRate: 1000, SkinDirectory: skin, Length: 50}, 210},
+		{HitSoundRenderOptions{SampleRate: 1000, SkinDirectory: skin, Length: 300}, 300},
+	} {
+		render, err := m.RenderHitSounds(b, tt.opts)
+		if err != nil {
+			t.Fatalf("Mapset.RenderHitSounds() error = %v", err)
+		}
+		if got := render.Audio.Frames(); got != tt.want {
+			t.Errorf("Mapset.RenderHitSounds(%+v) frames = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}